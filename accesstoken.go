@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// accessTokenPrefix tells the personal access tokens apart from the tokens of the other authentications.
+const accessTokenPrefix = "pat_"
+
+var (
+	errAccessTokenNotFound = errors.New("access token not found")
+	errAccessTokenExpired  = errors.New("access token expired")
+	errNotAccessToken      = errors.New("not a personal access token")
+)
+
+// accessToken lets scripts authenticate as the user who created it. Only the hash of its secret is stored, the
+// secret itself is returned once when the token is created.
+type accessToken struct {
+	ID   string `json:"id"`
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	// Scopes limits the permissions of the token to the ones listed, it has all the permissions of the role of the
+	// user when empty
+	Scopes    []permission `json:"scopes"`
+	CreatedAt time.Time    `json:"createdAt"`
+	// ExpiresAt is nil for the tokens that never expire
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Hash      string     `json:"-"`
+}
+
+type createAccessTokenParams struct {
+	Name   string       `json:"name" validate:"required,max=100"`
+	Scopes []permission `json:"scopes" validate:"omitempty,dive,oneof=view use edit manage"`
+	// ExpiresInDays is how long the token can be used, it never expires when 0
+	ExpiresInDays int `json:"expiresInDays" validate:"omitempty,min=1,max=365"`
+}
+
+func withScopes(ctx context.Context, scopes []permission) context.Context {
+	return context.WithValue(ctx, scopesContextKey, scopes)
+}
+
+func withAccessTokenID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, accessTokenIDContextKey, id)
+}
+
+// getAccessTokenID returns the id of the access token authenticating the request, empty if it was authenticated
+// otherwise.
+func getAccessTokenID(ctx context.Context) string {
+	id, _ := ctx.Value(accessTokenIDContextKey).(string)
+
+	return id
+}
+
+// getScopes returns the scopes of the access token authenticating the request, none if it was authenticated
+// otherwise.
+func getScopes(ctx context.Context) []permission {
+	scopes, _ := ctx.Value(scopesContextKey).([]permission)
+
+	return scopes
+}
+
+// checkScope returns errForbidden if the request was authenticated by an access token without the permission in
+// its scopes.
+func checkScope(ctx context.Context, p permission) error {
+	if scopes := getScopes(ctx); len(scopes) > 0 && !slices.Contains(scopes, p) {
+		return fmt.Errorf("%w: the access token does not have the %s scope", errForbidden, p)
+	}
+
+	return nil
+}
+
+func hashAccessToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// createAccessToken creates an access token for the user of the context, returning it along with its secret.
+func createAccessToken(
+	ctx context.Context, repo repository, validate *validator.Validate, params createAccessTokenParams,
+) (accessToken, string, error) {
+	if err := validate.Struct(params); err != nil {
+		return accessToken{}, "", fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	uid := getUID(ctx)
+	if uid == "" {
+		return accessToken{}, "", fmt.Errorf("%w: creating an access token requires authentication", errValidation)
+	}
+
+	scopes := append([]permission{}, params.Scopes...)
+	slices.Sort(scopes)
+
+	secret := accessTokenPrefix + rand.Text()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	token := accessToken{
+		ID:        uuid.NewString(),
+		UID:       uid,
+		Name:      params.Name,
+		Scopes:    slices.Compact(scopes),
+		CreatedAt: now,
+		Hash:      hashAccessToken(secret),
+	}
+
+	if params.ExpiresInDays > 0 {
+		token.ExpiresAt = getPtr(now.AddDate(0, 0, params.ExpiresInDays))
+	}
+
+	if err := repo.CreateAccessToken(ctx, token); err != nil {
+		return accessToken{}, "", fmt.Errorf("create access token: %w", err)
+	}
+
+	return token, secret, nil
+}
+
+func listAccessTokens(ctx context.Context, repo repository) ([]accessToken, error) {
+	tokens, err := repo.GetAccessTokens(ctx, getUID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// revokeAccessToken deletes the access token of the user of the context, which stops authenticating right away.
+func revokeAccessToken(ctx context.Context, repo repository, id string) error {
+	if err := repo.DeleteAccessToken(ctx, getUID(ctx), id); err != nil {
+		return fmt.Errorf("delete access token: %w", err)
+	}
+
+	return nil
+}
+
+// accessTokenAuthentication authenticates the requests bearing a personal access token.
+type accessTokenAuthentication struct {
+	repo repository
+}
+
+func (auth accessTokenAuthentication) Check(ctx context.Context, r *http.Request) (authenticatedUser, error) {
+	secret := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !strings.HasPrefix(secret, accessTokenPrefix) {
+		return authenticatedUser{}, errNotAccessToken
+	}
+
+	token, err := auth.repo.GetAccessTokenByHash(ctx, hashAccessToken(secret))
+	if err != nil {
+		return authenticatedUser{}, fmt.Errorf("get access token: %w", err)
+	}
+
+	if token.ExpiresAt != nil && !time.Now().Before(*token.ExpiresAt) {
+		return authenticatedUser{}, errAccessTokenExpired
+	}
+
+	return authenticatedUser{UID: token.UID, Scopes: token.Scopes, AccessTokenID: token.ID}, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type mockAuthentication struct {
+	user authenticatedUser
+	err  error
+}
+
+func (auth mockAuthentication) Check(_ context.Context, _ *http.Request) (authenticatedUser, error) {
+	return auth.user, auth.err
+}
+
+func getBearerRequest(t *testing.T, token string) *http.Request {
+	t.Helper()
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/items", nil)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return r
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	ctx := withUID(context.Background(), "alice")
+	mockRepo := &mockRepository{}
+
+	params := createAccessTokenParams{
+		Name: "Home Assistant", Scopes: []permission{permissionUse, permissionView, permissionUse}, ExpiresInDays: 30,
+	}
+
+	token, secret, err := createAccessToken(ctx, mockRepo, validate, params)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if mockRepo.CreateAccessTokenCalls != 1 || !reflect.DeepEqual(mockRepo.CreateAccessTokenToken, token) {
+		t.Errorf("Stored %+v instead of %+v", mockRepo.CreateAccessTokenToken, token)
+	}
+
+	if !strings.HasPrefix(secret, accessTokenPrefix) || token.Hash != hashAccessToken(secret) ||
+		strings.Contains(token.Hash, secret) {
+		t.Errorf("Expected the hash of the secret %q to be stored, got %q", secret, token.Hash)
+	}
+
+	if token.UID != "alice" || !reflect.DeepEqual(token.Scopes, []permission{permissionUse, permissionView}) {
+		t.Errorf("Got invalid token %+v", token)
+	}
+
+	if token.ExpiresAt == nil || !token.ExpiresAt.Equal(token.CreatedAt.AddDate(0, 0, 30)) {
+		t.Errorf("Expected the token to expire in 30 days, got %v", token.ExpiresAt)
+	}
+
+	token, _, err = createAccessToken(ctx, mockRepo, validate, createAccessTokenParams{Name: "Scripts"})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if token.ExpiresAt != nil || len(token.Scopes) != 0 {
+		t.Errorf("Expected a token without scopes which never expires, got %+v", token)
+	}
+
+	invalid := []createAccessTokenParams{{}, {Name: "Scripts", Scopes: []permission{"admin"}}}
+	for _, params := range invalid {
+		if _, _, err := createAccessToken(ctx, mockRepo, validate, params); !errors.Is(err, errValidation) {
+			t.Errorf("Expected errValidation for %+v, got %v", params, err)
+		}
+	}
+
+	_, _, err = createAccessToken(context.Background(), mockRepo, validate, createAccessTokenParams{Name: "Scripts"})
+	if !errors.Is(err, errValidation) {
+		t.Errorf("Expected errValidation without a user, got %v", err)
+	}
+}
+
+func TestCheckNotAccessToken(t *testing.T) {
+	t.Parallel()
+
+	ctx := withUID(context.Background(), "alice")
+
+	if err := checkNotAccessToken(ctx); err != nil {
+		t.Errorf("Got error: %s", err)
+	}
+
+	if err := checkNotAccessToken(withAccessTokenID(ctx, "1")); !errors.Is(err, errForbidden) {
+		t.Errorf("Expected errForbidden with an access token, got %v", err)
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	t.Parallel()
+
+	if err := checkScope(context.Background(), permissionManage); err != nil {
+		t.Errorf("Expected every scope without an access token, got %v", err)
+	}
+
+	ctx := withScopes(context.Background(), []permission{permissionView, permissionUse})
+
+	if err := checkScope(ctx, permissionUse); err != nil {
+		t.Errorf("Got error: %s", err)
+	}
+
+	if err := checkScope(ctx, permissionEdit); !errors.Is(err, errForbidden) {
+		t.Errorf("Expected errForbidden, got %v", err)
+	}
+}
+
+func TestAccessTokenAuthentication(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	auth := accessTokenAuthentication{repo: repo}
+
+	params := createAccessTokenParams{Name: "Scripts", Scopes: []permission{permissionView}}
+
+	token, secret, err := createAccessToken(withUID(ctx, "alice"), repo, validate, params)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	user, err := auth.Check(ctx, getBearerRequest(t, secret))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	expected := authenticatedUser{UID: "alice", Scopes: []permission{permissionView}, AccessTokenID: token.ID}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Got invalid user %+v", user)
+	}
+
+	if _, err := auth.Check(ctx, getBearerRequest(t, "firebase-id-token")); !errors.Is(err, errNotAccessToken) {
+		t.Errorf("Expected errNotAccessToken, got %v", err)
+	}
+
+	_, err = auth.Check(ctx, getBearerRequest(t, accessTokenPrefix+"unknown"))
+	if !errors.Is(err, errAccessTokenNotFound) {
+		t.Errorf("Expected errAccessTokenNotFound, got %v", err)
+	}
+
+	expired := accessToken{
+		ID: "expired", UID: "alice", Name: "Expired", Scopes: []permission{}, CreatedAt: time.Now().AddDate(0, 0, -2),
+		ExpiresAt: getPtr(time.Now().AddDate(0, 0, -1)), Hash: hashAccessToken(accessTokenPrefix + "expired"),
+	}
+
+	if err := repo.CreateAccessToken(ctx, expired); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	_, err = auth.Check(ctx, getBearerRequest(t, accessTokenPrefix+"expired"))
+	if !errors.Is(err, errAccessTokenExpired) {
+		t.Errorf("Expected errAccessTokenExpired, got %v", err)
+	}
+
+	if err := revokeAccessToken(withUID(ctx, "alice"), repo, token.ID); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if _, err := auth.Check(ctx, getBearerRequest(t, secret)); !errors.Is(err, errAccessTokenNotFound) {
+		t.Errorf("Expected errAccessTokenNotFound for the revoked token, got %v", err)
+	}
+}
+
+func TestChainedAuthentication(t *testing.T) {
+	t.Parallel()
+
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	alice := authenticatedUser{UID: "alice"}
+	r := getBearerRequest(t, "token")
+
+	user, err := chainedAuthentication{
+		mockAuthentication{err: errFirst}, mockAuthentication{user: alice},
+	}.Check(context.Background(), r)
+	if err != nil || !reflect.DeepEqual(user, alice) {
+		t.Errorf("Expected the second authentication to accept the request, got %+v with error %v", user, err)
+	}
+
+	_, err = chainedAuthentication{
+		mockAuthentication{err: errFirst}, mockAuthentication{err: errSecond},
+	}.Check(context.Background(), r)
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("Expected the errors of both authentications, got %v", err)
+	}
+}
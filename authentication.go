@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/nickelghost/nghttp"
@@ -11,24 +12,109 @@ import (
 
 var errNoEmailAddressesFound = errors.New("no email addresses found")
 
+// authenticatedUser is the user authenticated by a request.
+type authenticatedUser struct {
+	UID string
+	// Scopes limits the permissions of the role of the user to the ones listed, none when the authentication does
+	// not limit them
+	Scopes []permission
+	// AccessTokenID is the id of the access token authenticating the request, empty when authenticated otherwise
+	AccessTokenID string
+}
+
 type authentication interface {
-	Check(ctx context.Context, r *http.Request) error
+	// Check returns the user authenticated by the request.
+	Check(ctx context.Context, r *http.Request) (authenticatedUser, error)
+}
+
+// chainedAuthentication authenticates the request with the first of its authentications accepting it, so that the
+// clients can use any of them.
+type chainedAuthentication []authentication
+
+func (chain chainedAuthentication) Check(ctx context.Context, r *http.Request) (authenticatedUser, error) {
+	errs := make([]error, 0, len(chain))
+
+	for _, auth := range chain {
+		user, err := auth.Check(ctx, r)
+		if err == nil {
+			return user, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return authenticatedUser{}, errors.Join(errs...)
 }
 
 type authenticationRepository interface {
-	// GetAllEmails returns all email addresses that should be notified about expiring items.
-	// If no email addresses are found, it returns errNoEmailAddressesFound.
+	// GetEmails returns the email addresses of the users, which are notified about the expiring items of their
+	// household. If none of the users has an email address, it returns errNoEmailAddressesFound.
+	GetEmails(ctx context.Context, uids []string) ([]string, error)
+	// GetAllEmails returns the email addresses of all the users, which are notified about the default household
+	// while it has no members. If none of the users has an email address, it returns errNoEmailAddressesFound.
 	GetAllEmails(ctx context.Context) ([]string, error)
 }
 
 func authMiddleware(next http.Handler, auth authentication) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := auth.Check(r.Context(), r); err != nil {
+		user, err := auth.Check(r.Context(), r)
+		if err != nil {
 			nghttp.RespondGeneric(w, r, http.StatusUnauthorized, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
+		ctx := withScopes(withUID(r.Context(), user.UID), user.Scopes)
+
+		next.ServeHTTP(w, r.WithContext(withAccessTokenID(ctx, user.AccessTokenID)))
+	})
+}
+
+// checkNotAccessToken returns errForbidden if the request was authenticated by an access token.
+func checkNotAccessToken(ctx context.Context) error {
+	if getAccessTokenID(ctx) != "" {
+		return fmt.Errorf("%w: the request cannot be authenticated by an access token", errForbidden)
+	}
+
+	return nil
+}
+
+// noAccessTokenMiddleware responds with 403 to the requests authenticated by an access token, for the routes
+// managing the user's account that a token, whatever its scopes, must not reach.
+func noAccessTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkNotAccessToken(r.Context()); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusForbidden, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkRequestPermission returns errForbidden unless the role of the user in their household, which
+// householdMiddleware put in the context, has the permission, and so do the scopes of the access token
+// authenticating the request.
+func checkRequestPermission(ctx context.Context, p permission) error {
+	if err := getHouseholdRole(ctx).checkPermission(p); err != nil {
+		return err
+	}
+
+	return checkScope(ctx, p)
+}
+
+// permissionMiddleware responds with 403 unless the request has the permission.
+func permissionMiddleware(next http.Handler, p permission) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkRequestPermission(r.Context(), p); err != nil {
+			// unlike the generic message, the error tells the user which permission their role is missing
+			res := nghttp.GenericResponse{Message: err.Error()}
+			nghttp.Respond(w, r, http.StatusForbidden, err, res, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// expiryRule gives the default expiry of the items of a type or having a tag.
+type expiryRule struct {
+	ID   string  `json:"id"`
+	Type *string `json:"type"`
+	Tag  *string `json:"tag"`
+	// ShelfLife is the number of days an unopened item lasts after being bought
+	ShelfLife *int `json:"shelfLife"`
+	// OpenedLifespan is the number of days an item lasts after being opened
+	OpenedLifespan *int `json:"openedLifespan"`
+	// FrozenShelfLife is the number of days an item lasts in the freezer
+	FrozenShelfLife *int `json:"frozenShelfLife"`
+	// ThawedLifespan is the number of days an item lasts after being thawed
+	ThawedLifespan *int `json:"thawedLifespan"`
+}
+
+type writeExpiryRuleParams struct {
+	Type            *string `json:"type"            validate:"required_without=Tag,excluded_with=Tag,omitempty,min=1"`
+	Tag             *string `json:"tag"             validate:"required_without=Type,omitempty,min=1"`
+	ShelfLife       *int    `json:"shelfLife"       validate:"omitempty,gt=0"`
+	OpenedLifespan  *int    `json:"openedLifespan"  validate:"omitempty,gt=0"`
+	FrozenShelfLife *int    `json:"frozenShelfLife" validate:"omitempty,gt=0"`
+	ThawedLifespan  *int    `json:"thawedLifespan"  validate:"omitempty,gt=0"`
+}
+
+// expiryRuleFromParams returns the rule written with the params.
+func expiryRuleFromParams(id string, params writeExpiryRuleParams) expiryRule {
+	return expiryRule{
+		ID:              id,
+		Type:            params.Type,
+		Tag:             params.Tag,
+		ShelfLife:       params.ShelfLife,
+		OpenedLifespan:  params.OpenedLifespan,
+		FrozenShelfLife: params.FrozenShelfLife,
+		ThawedLifespan:  params.ThawedLifespan,
+	}
+}
+
+// validateExpiryRule validates the params, which need to set at least one of the durations.
+func validateExpiryRule(validate *validator.Validate, params writeExpiryRuleParams) error {
+	if err := validate.Struct(params); err != nil {
+		return fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	if params.ShelfLife == nil && params.OpenedLifespan == nil &&
+		params.FrozenShelfLife == nil && params.ThawedLifespan == nil {
+		return fmt.Errorf("%w: the rule has to set at least one duration", errValidation)
+	}
+
+	return nil
+}
+
+const (
+	// defaultFrozenShelfLife and defaultThawedLifespan are used for the items no rule sets them for
+	defaultFrozenShelfLife = 90
+	defaultThawedLifespan  = 2
+)
+
+var (
+	errExpiryRuleNotFound = errors.New("expiry rule not found")
+	errExpiryRuleExists   = errors.New("an expiry rule for the type or tag already exists")
+)
+
+// expiryRules looks up the rules applying to items.
+type expiryRules struct {
+	byType map[string]expiryRule
+	byTag  map[string]expiryRule
+}
+
+func newExpiryRules(rules []expiryRule) expiryRules {
+	res := expiryRules{byType: map[string]expiryRule{}, byTag: map[string]expiryRule{}}
+
+	for _, r := range rules {
+		if r.Type != nil {
+			res.byType[*r.Type] = r
+		}
+
+		if r.Tag != nil {
+			res.byTag[*r.Tag] = r
+		}
+	}
+
+	return res
+}
+
+// matching returns the rules applying to the item, the rule of its type comes first followed by the rules of its
+// tags in their order.
+func (rules expiryRules) matching(itemType *string, tags []string) []expiryRule {
+	matching := []expiryRule{}
+
+	if itemType != nil {
+		if r, ok := rules.byType[*itemType]; ok {
+			matching = append(matching, r)
+		}
+	}
+
+	for _, tag := range tags {
+		if r, ok := rules.byTag[tag]; ok {
+			matching = append(matching, r)
+		}
+	}
+
+	return matching
+}
+
+// defaults returns the rule applying to the item, each of its durations is taken from the first matching rule
+// setting it. The frozen shelf life and thawed lifespan fall back to the defaults, the others are nil when no rule
+// sets them.
+func (rules expiryRules) defaults(itemType *string, tags []string) expiryRule {
+	var res expiryRule
+
+	for _, r := range rules.matching(itemType, tags) {
+		res.ShelfLife = cmp.Or(res.ShelfLife, r.ShelfLife)
+		res.OpenedLifespan = cmp.Or(res.OpenedLifespan, r.OpenedLifespan)
+		res.FrozenShelfLife = cmp.Or(res.FrozenShelfLife, r.FrozenShelfLife)
+		res.ThawedLifespan = cmp.Or(res.ThawedLifespan, r.ThawedLifespan)
+	}
+
+	res.FrozenShelfLife = cmp.Or(res.FrozenShelfLife, getPtr(defaultFrozenShelfLife))
+	res.ThawedLifespan = cmp.Or(res.ThawedLifespan, getPtr(defaultThawedLifespan))
+
+	return res
+}
+
+func addDays(t time.Time, days int) time.Time {
+	return t.Add(time.Duration(days) * 24 * time.Hour) //nolint:mnd
+}
+
+// withDefaultExpiry returns the item with ExpiresAt and Lifespan filled from the rules when it does not have them.
+// The filled values are not stored, so that they follow the type and tags of the item and the rules as they change.
+func (i item) withDefaultExpiry(rules expiryRules) item {
+	defaults := rules.defaults(i.Type, i.Tags)
+
+	if i.ExpiresAt == nil && defaults.ShelfLife != nil {
+		i.ExpiresAt = getPtr(addDays(i.BoughtAt, *defaults.ShelfLife))
+	}
+
+	if i.Lifespan == nil && defaults.OpenedLifespan != nil {
+		i.Lifespan = getPtr(*defaults.OpenedLifespan)
+	}
+
+	return i
+}
+
+func getExpiryRules(ctx context.Context, repo repository) (expiryRules, error) {
+	rules, err := repo.GetExpiryRules(ctx)
+	if err != nil {
+		return expiryRules{}, fmt.Errorf("get expiry rules: %w", err)
+	}
+
+	return newExpiryRules(rules), nil
+}
+
+func listExpiryRules(ctx context.Context, repo repository) ([]expiryRule, error) {
+	rules, err := repo.GetExpiryRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get expiry rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// checkExpiryRuleUnique returns errExpiryRuleExists if a rule other than the one with the id has the same type or tag.
+func checkExpiryRuleUnique(ctx context.Context, repo repository, id string, params writeExpiryRuleParams) error {
+	rules, err := repo.GetExpiryRules(ctx)
+	if err != nil {
+		return fmt.Errorf("get expiry rules: %w", err)
+	}
+
+	conflicts := slices.ContainsFunc(rules, func(r expiryRule) bool {
+		if r.ID == id {
+			return false
+		}
+
+		return (params.Type != nil && r.Type != nil && *params.Type == *r.Type) ||
+			(params.Tag != nil && r.Tag != nil && *params.Tag == *r.Tag)
+	})
+	if conflicts {
+		return errExpiryRuleExists
+	}
+
+	return nil
+}
+
+// createExpiryRule creates the rule, returning it as stored.
+func createExpiryRule(
+	ctx context.Context, repo repository, validate *validator.Validate, params writeExpiryRuleParams,
+) (expiryRule, error) {
+	if err := validateExpiryRule(validate, params); err != nil {
+		return expiryRule{}, err
+	}
+
+	if err := checkExpiryRuleUnique(ctx, repo, "", params); err != nil {
+		return expiryRule{}, err
+	}
+
+	rule, err := repo.CreateExpiryRule(ctx, params)
+	if err != nil {
+		return expiryRule{}, fmt.Errorf("create expiry rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+func updateExpiryRule(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, params writeExpiryRuleParams,
+) error {
+	if err := validateExpiryRule(validate, params); err != nil {
+		return err
+	}
+
+	if err := checkExpiryRuleUnique(ctx, repo, id, params); err != nil {
+		return err
+	}
+
+	if err := repo.UpdateExpiryRule(ctx, id, params); err != nil {
+		return fmt.Errorf("update expiry rule: %w", err)
+	}
+
+	return nil
+}
+
+func deleteExpiryRule(ctx context.Context, repo repository, id string) error {
+	if err := repo.DeleteExpiryRule(ctx, id); err != nil {
+		return fmt.Errorf("delete expiry rule: %w", err)
+	}
+
+	return nil
+}
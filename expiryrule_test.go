@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestExpiryRulesDefaults(t *testing.T) {
+	t.Parallel()
+
+	rules := newExpiryRules([]expiryRule{
+		{ID: "milk", Type: getPtr("milk"), OpenedLifespan: getPtr(4)},
+		{ID: "dairy", Tag: getPtr("dairy"), ShelfLife: getPtr(10), OpenedLifespan: getPtr(5)},
+		{ID: "frozen", Tag: getPtr("frozen"), ShelfLife: getPtr(90)},
+	})
+	scenarios := []struct {
+		itemType       *string
+		tags           []string
+		shelfLife      *int
+		openedLifespan *int
+	}{
+		{itemType: getPtr("milk"), tags: []string{"dairy"}, shelfLife: getPtr(10), openedLifespan: getPtr(4)},
+		{tags: []string{"dairy"}, shelfLife: getPtr(10), openedLifespan: getPtr(5)},
+		{tags: []string{"frozen", "dairy"}, shelfLife: getPtr(90), openedLifespan: getPtr(5)},
+		{itemType: getPtr("milk"), openedLifespan: getPtr(4)},
+		{itemType: getPtr("bread"), tags: []string{"bakery"}},
+	}
+
+	for _, s := range scenarios {
+		defaults := rules.defaults(s.itemType, s.tags)
+		if !reflect.DeepEqual(defaults.ShelfLife, s.shelfLife) ||
+			!reflect.DeepEqual(defaults.OpenedLifespan, s.openedLifespan) {
+			t.Errorf("Defaults of %v %v were %v and %v instead of %v and %v",
+				s.itemType, s.tags, defaults.ShelfLife, defaults.OpenedLifespan, s.shelfLife, s.openedLifespan)
+		}
+	}
+}
+
+func TestCreateItemExpiryRules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := boughtAt.Add(48 * time.Hour)
+	repo := newMemoryRepository()
+
+	_, err := repo.CreateExpiryRule(ctx, writeExpiryRuleParams{
+		Type: getPtr("milk"), ShelfLife: getPtr(7), OpenedLifespan: getPtr(4),
+	})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	scenarios := []struct {
+		params    writeItemParams
+		expiresAt *time.Time
+		lifespan  *int
+	}{
+		{
+			params:    writeItemParams{Name: "Milk", Type: getPtr("milk"), Tags: []string{}, BoughtAt: boughtAt},
+			expiresAt: getPtr(boughtAt.AddDate(0, 0, 7)), lifespan: getPtr(4),
+		},
+		{
+			params: writeItemParams{
+				Name: "Oat milk", Type: getPtr("milk"), Tags: []string{}, BoughtAt: boughtAt,
+				ExpiresAt: &expiresAt, Lifespan: getPtr(2),
+			},
+			expiresAt: &expiresAt, lifespan: getPtr(2),
+		},
+		{params: writeItemParams{Name: "Bread", Tags: []string{}, BoughtAt: boughtAt}},
+	}
+
+	for _, s := range scenarios {
+		i, err := createItem(ctx, repo, validate, s.params)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !contractEqualTimes(i.ExpiresAt, s.expiresAt) || !reflect.DeepEqual(i.Lifespan, s.lifespan) {
+			t.Errorf("Created %s expiring at %v with lifespan %v instead of %v and %v",
+				i.Name, i.ExpiresAt, i.Lifespan, s.expiresAt, s.lifespan)
+		}
+
+		// only the values of the client are stored
+		stored, err := repo.GetItem(ctx, i.ID)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !contractEqualTimes(stored.ExpiresAt, s.params.ExpiresAt) ||
+			!reflect.DeepEqual(stored.Lifespan, s.params.Lifespan) {
+			t.Errorf("Stored %s expiring at %v with lifespan %v instead of %v and %v",
+				i.Name, stored.ExpiresAt, stored.Lifespan, s.params.ExpiresAt, s.params.Lifespan)
+		}
+	}
+}
+
+func TestPatchItemExpiryRules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := newMemoryRepository()
+
+	for _, params := range []writeExpiryRuleParams{
+		{Type: getPtr("milk"), ShelfLife: getPtr(7), OpenedLifespan: getPtr(4)},
+		{Type: getPtr("cream"), ShelfLife: getPtr(14)},
+	} {
+		if _, err := repo.CreateExpiryRule(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	milk, err := createItem(ctx, repo, validate, writeItemParams{
+		Name: "Milk", Type: getPtr("milk"), Tags: []string{}, BoughtAt: boughtAt,
+	})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	scenarios := []struct {
+		patch     string
+		expiresAt *time.Time
+		lifespan  *int
+	}{
+		// the expiry of the rule follows the type of the item
+		{patch: `{"type": "cream"}`, expiresAt: getPtr(boughtAt.AddDate(0, 0, 14))},
+		{patch: `{"expiresAt": "2024-05-03T12:00:00Z"}`, expiresAt: getPtr(boughtAt.AddDate(0, 0, 2))},
+		{patch: `{"type": "milk"}`, expiresAt: getPtr(boughtAt.AddDate(0, 0, 2)), lifespan: getPtr(4)},
+		// clearing the expiry of the item brings back the one of its rule
+		{patch: `{"expiresAt": null}`, expiresAt: getPtr(boughtAt.AddDate(0, 0, 7)), lifespan: getPtr(4)},
+	}
+
+	for _, s := range scenarios {
+		i, err := patchItem(ctx, repo, validate, milk.ID, []byte(s.patch), nil)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !contractEqualTimes(i.ExpiresAt, s.expiresAt) || !reflect.DeepEqual(i.Lifespan, s.lifespan) {
+			t.Errorf("Patching with %s made the item expire at %v with lifespan %v instead of %v and %v",
+				s.patch, i.ExpiresAt, i.Lifespan, s.expiresAt, s.lifespan)
+		}
+	}
+}
+
+func TestGetItemDaysLeftExpiryRules(t *testing.T) {
+	t.Parallel()
+
+	rules := newExpiryRules([]expiryRule{{ID: "dairy", Tag: getPtr("dairy"), ShelfLife: getPtr(10)}})
+	scenarios := []struct {
+		item     item
+		daysLeft *int
+	}{
+		{item: item{Tags: []string{"dairy"}, BoughtAt: time.Now().Add(-2 * 24 * time.Hour)}, daysLeft: getPtr(8)},
+		{
+			item:     item{Tags: []string{"dairy"}, BoughtAt: time.Now(), ExpiresAt: getPtr(time.Now().Add(time.Hour))},
+			daysLeft: getPtr(1),
+		},
+		{item: item{Tags: []string{"bakery"}, BoughtAt: time.Now()}},
+	}
+
+	for _, s := range scenarios {
+		if daysLeft := getItemDaysLeft(s.item, rules); !reflect.DeepEqual(daysLeft, s.daysLeft) {
+			t.Errorf("Item %+v has %v days left instead of %v", s.item, daysLeft, s.daysLeft)
+		}
+	}
+}
+
+func TestCreateExpiryRule(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	scenarios := []struct {
+		params writeExpiryRuleParams
+		err    error
+	}{
+		{params: writeExpiryRuleParams{Tag: getPtr("dairy"), ShelfLife: getPtr(10)}},
+		{params: writeExpiryRuleParams{Type: getPtr("milk"), OpenedLifespan: getPtr(4)}},
+		{params: writeExpiryRuleParams{Tag: getPtr("fish"), FrozenShelfLife: getPtr(180), ThawedLifespan: getPtr(1)}},
+		{params: writeExpiryRuleParams{Type: getPtr("bread"), ShelfLife: getPtr(3)}, err: errExpiryRuleExists},
+		{params: writeExpiryRuleParams{ShelfLife: getPtr(3)}, err: errValidation},
+		{
+			params: writeExpiryRuleParams{Type: getPtr("milk"), Tag: getPtr("dairy"), ShelfLife: getPtr(3)},
+			err:    errValidation,
+		},
+		{params: writeExpiryRuleParams{Tag: getPtr("dairy")}, err: errValidation},
+		{params: writeExpiryRuleParams{Tag: getPtr("dairy"), ShelfLife: getPtr(0)}, err: errValidation},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{GetExpiryRulesRes: []expiryRule{{ID: "bread", Type: getPtr("bread")}}}
+
+		_, err := createExpiryRule(context.Background(), mockRepo, validate, s.params)
+		if !errors.Is(err, s.err) || (s.err == nil && err != nil) {
+			t.Errorf("Creating %+v returned %v instead of %v", s.params, err, s.err)
+		}
+
+		if (mockRepo.CreateExpiryRuleCalls == 1) != (s.err == nil) {
+			t.Errorf("Creating %+v called CreateExpiryRule %d times", s.params, mockRepo.CreateExpiryRuleCalls)
+		}
+	}
+}
+
+func TestUpdateExpiryRuleKeepsItsOwnKey(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	mockRepo := &mockRepository{GetExpiryRulesRes: []expiryRule{{ID: "bread", Type: getPtr("bread")}}}
+	params := writeExpiryRuleParams{Type: getPtr("bread"), ShelfLife: getPtr(4)}
+
+	if err := updateExpiryRule(context.Background(), mockRepo, validate, "bread", params); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if mockRepo.UpdateExpiryRuleCalls != 1 || mockRepo.UpdateExpiryRuleID != "bread" {
+		t.Errorf("Expected UpdateExpiryRule to be called with bread, got %+v", mockRepo)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 
 	firebase "firebase.google.com/go/v4"
@@ -36,24 +37,55 @@ type firebaseAuthentication struct {
 	tracer trace.Tracer
 }
 
-func (auth firebaseAuthentication) Check(ctx context.Context, r *http.Request) error {
+func (auth firebaseAuthentication) Check(ctx context.Context, r *http.Request) (authenticatedUser, error) {
 	ctx, span := auth.tracer.Start(ctx, "firebaseAuthentication.Check")
 	defer span.End()
 
 	idToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
 
-	_, err := auth.client.VerifyIDToken(ctx, idToken)
+	token, err := auth.client.VerifyIDToken(ctx, idToken)
 	if err != nil {
-		return fmt.Errorf("failed to verify ID token: %w", err)
+		return authenticatedUser{}, fmt.Errorf("failed to verify ID token: %w", err)
 	}
 
-	return nil
+	return authenticatedUser{UID: token.UID}, nil
 }
 
 type firebaseAuthenticationRepository struct {
 	client *auth.Client
 }
 
+// firebaseMaxGetUsers is the maximum number of users a single GetUsers call accepts.
+const firebaseMaxGetUsers = 100
+
+func (repo firebaseAuthenticationRepository) GetEmails(ctx context.Context, uids []string) ([]string, error) {
+	emails := []string{}
+
+	for chunk := range slices.Chunk(uids, firebaseMaxGetUsers) {
+		identifiers := make([]auth.UserIdentifier, 0, len(chunk))
+		for _, uid := range chunk {
+			identifiers = append(identifiers, auth.UIDIdentifier{UID: uid})
+		}
+
+		res, err := repo.client.GetUsers(ctx, identifiers)
+		if err != nil {
+			return nil, fmt.Errorf("firebase get users: %w", err)
+		}
+
+		for _, user := range res.Users {
+			if user.Email != "" {
+				emails = append(emails, user.Email)
+			}
+		}
+	}
+
+	if len(emails) == 0 {
+		return nil, errNoEmailAddressesFound
+	}
+
+	return emails, nil
+}
+
 func (repo firebaseAuthenticationRepository) GetAllEmails(ctx context.Context) ([]string, error) {
 	emails := []string{}
 	iter := repo.client.Users(ctx, "")
@@ -66,7 +98,9 @@ func (repo firebaseAuthenticationRepository) GetAllEmails(ctx context.Context) (
 			return nil, fmt.Errorf("firebase get user: %w", err)
 		}
 
-		emails = append(emails, user.Email)
+		if user.Email != "" {
+			emails = append(emails, user.Email)
+		}
 	}
 
 	if len(emails) == 0 {
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
@@ -32,12 +35,25 @@ type firestoreRepository struct {
 	tracer trace.Tracer
 }
 
+// collection returns the collection of the household of the context. The default household uses the root
+// collections, which hold the data stored before there were households.
+func (repo firestoreRepository) collection(ctx context.Context, name string) *firestore.CollectionRef {
+	householdID := getHouseholdID(ctx)
+	if householdID == defaultHouseholdID {
+		return repo.client.Collection(name)
+	}
+
+	return repo.client.Collection("households").Doc(householdID).Collection(name)
+}
+
 func firestoreToLocation(doc *firestore.DocumentSnapshot) (location, error) {
 	l := location{ID: doc.Ref.ID}
 	if err := doc.DataTo(&l); err != nil {
 		return location{}, fmt.Errorf("firestore to location: %w", err)
 	}
 
+	l.Version = max(l.Version, 1)
+
 	return l, nil
 }
 
@@ -63,6 +79,58 @@ func firestoreToLocations(iter *firestore.DocumentIterator) ([]location, error)
 	return locations, nil
 }
 
+func firestoreToItem(doc *firestore.DocumentSnapshot) (item, error) {
+	i := item{ID: doc.Ref.ID, Tags: []string{}}
+	if err := doc.DataTo(&i); err != nil {
+		return item{}, fmt.Errorf("firestore to item: %w", err)
+	}
+
+	i.Version = max(i.Version, 1)
+
+	return i, nil
+}
+
+// firestoreItem is how items are stored, the fields of the params are stored next to the version.
+type firestoreItem struct {
+	writeItemParams
+
+	Version int
+}
+
+// firestoreGetVersion reads the version of the document in the transaction. It returns notFound if the document
+// does not exist and errVersionMismatch if the version is given and differs from the stored one.
+func firestoreGetVersion(
+	tx *firestore.Transaction, doc *firestore.DocumentRef, version *int, notFound error,
+) (int, error) {
+	snap, err := tx.Get(doc)
+	if status.Code(err) == codes.NotFound {
+		return 0, fmt.Errorf("%w: %w", notFound, err)
+	} else if err != nil {
+		return 0, fmt.Errorf("firestore get version: %w", err)
+	}
+
+	stored, err := firestoreToVersion(snap)
+	if err != nil {
+		return 0, err
+	}
+
+	if version != nil && *version != stored {
+		return 0, errVersionMismatch
+	}
+
+	return stored, nil
+}
+
+func firestoreToVersion(snap *firestore.DocumentSnapshot) (int, error) {
+	var stored struct{ Version int }
+	if err := snap.DataTo(&stored); err != nil {
+		return 0, fmt.Errorf("firestore to version: %w", err)
+	}
+
+	// documents written before the versions were stored are at the first version
+	return max(stored.Version, 1), nil
+}
+
 func firestoreToItems(iter *firestore.DocumentIterator) ([]item, error) {
 	items := []item{}
 
@@ -74,9 +142,9 @@ func firestoreToItems(iter *firestore.DocumentIterator) ([]item, error) {
 			return nil, fmt.Errorf("firestore to items next: %w", err)
 		}
 
-		i := item{ID: doc.Ref.ID, Tags: []string{}}
-		if err := doc.DataTo(&i); err != nil {
-			return nil, fmt.Errorf("firestore to item: %w", err)
+		i, err := firestoreToItem(doc)
+		if err != nil {
+			return nil, err
 		}
 
 		items = append(items, i)
@@ -85,19 +153,27 @@ func firestoreToItems(iter *firestore.DocumentIterator) ([]item, error) {
 	return items, nil
 }
 
-func (repo firestoreRepository) GetLocations(ctx context.Context, ids *[]string) ([]location, error) {
+func (repo firestoreRepository) GetLocations(ctx context.Context, query locationsQuery) ([]location, error) {
 	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetLocations")
 	defer span.End()
 
-	if ids == nil {
-		return firestoreToLocations(
-			repo.client.Collection("locations").Documents(ctx),
-		)
+	if query.IDs == nil {
+		q := repo.collection(ctx, "locations").OrderBy("Name", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc)
+
+		if query.After != nil {
+			q = q.StartAfter(query.After.Name, query.After.ID)
+		}
+
+		if query.Limit > 0 {
+			q = q.Limit(query.Limit)
+		}
+
+		return firestoreToLocations(q.Documents(ctx))
 	}
 
 	refs := []*firestore.DocumentRef{}
-	for _, id := range *ids {
-		refs = append(refs, repo.client.Collection("locations").Doc(id))
+	for _, id := range *query.IDs {
+		refs = append(refs, repo.collection(ctx, "locations").Doc(id))
 	}
 
 	docs, err := repo.client.GetAll(ctx, refs)
@@ -108,73 +184,108 @@ func (repo firestoreRepository) GetLocations(ctx context.Context, ids *[]string)
 	locations := []location{}
 
 	for _, doc := range docs {
+		// ids without a document are skipped, same as when querying the whole collection
+		if !doc.Exists() {
+			continue
+		}
+
 		l, err := firestoreToLocation(doc)
 		if err != nil {
 			return nil, err
 		}
 
+		// the documents are read by their references, so they are sorted and paginated here
+		if query.After != nil && cmp.Or(cmp.Compare(l.Name, query.After.Name), cmp.Compare(l.ID, query.After.ID)) <= 0 {
+			continue
+		}
+
 		locations = append(locations, l)
 	}
 
-	return locations, nil
+	slices.SortFunc(locations, func(a, b location) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return limitEntries(locations, query.Limit), nil
 }
 
-func (repo firestoreRepository) CreateLocation(ctx context.Context, name string) error {
-	id := uuid.NewString()
+func (repo firestoreRepository) CreateLocation(ctx context.Context, name string) (location, error) {
+	l := location{ID: uuid.NewString(), Name: name, Version: 1}
 
-	_, err := repo.client.
-		Collection("locations").
-		Doc(id).
+	_, err := repo.collection(ctx, "locations").
+		Doc(l.ID).
 		Set(ctx, map[string]any{
-			"Name": name,
+			"Name":    l.Name,
+			"Version": l.Version,
 		})
 	if err != nil {
-		return fmt.Errorf("firestore create location: %w", err)
+		return location{}, fmt.Errorf("firestore create location: %w", err)
 	}
 
-	return nil
+	return l, nil
 }
 
-func (repo firestoreRepository) UpdateLocation(ctx context.Context, id string, name string) error {
-	_, err := repo.client.
-		Collection("locations").
-		Doc(id).
-		Update(ctx, []firestore.Update{{
-			Path:  "Name",
-			Value: name,
-		}})
+func (repo firestoreRepository) UpdateLocation(ctx context.Context, id string, name string, version *int) error {
+	doc := repo.collection(ctx, "locations").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		stored, err := firestoreGetVersion(tx, doc, version, errLocationNotFound)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Update(doc, []firestore.Update{
+			{Path: "Name", Value: name},
+			{Path: "Version", Value: stored + 1},
+		})
+		if err != nil {
+			return fmt.Errorf("firestore update location: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("firestore update location: %w", err)
+		return fmt.Errorf("firestore transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (repo firestoreRepository) DeleteLocation(ctx context.Context, id string) error {
-	itemsIter := repo.client.
-		Collection("items").
-		Where("LocationID", "==", id).
-		Documents(ctx)
+func (repo firestoreRepository) DeleteLocation(ctx context.Context, id string, version *int) error {
+	locationDoc := repo.collection(ctx, "locations").Doc(id)
+	itemsQuery := repo.collection(ctx, "items").
+		Where("LocationID", "==", id)
 
 	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
-		for {
-			doc, err := itemsIter.Next()
-			if errors.Is(err, iterator.Done) {
-				break
-			} else if err != nil {
-				return fmt.Errorf("firestore get items: %w", err)
+		if version != nil {
+			// deleting a location that does not exist does not match the version either
+			if _, err := firestoreGetVersion(tx, locationDoc, version, errVersionMismatch); err != nil {
+				return err
+			}
+		}
+
+		// the items are read within the transaction and before any write, as a retry has to see the current ones
+		docs, err := tx.Documents(itemsQuery).GetAll()
+		if err != nil {
+			return fmt.Errorf("firestore get items: %w", err)
+		}
+
+		for _, doc := range docs {
+			i, err := firestoreToItem(doc)
+			if err != nil {
+				return err
 			}
 
-			err = tx.Update(doc.Ref, []firestore.Update{{
-				Path:  "LocationID",
-				Value: nil,
-			}})
+			err = tx.Update(doc.Ref, []firestore.Update{
+				{Path: "LocationID", Value: nil},
+				{Path: "Version", Value: i.Version + 1},
+			})
 			if err != nil {
 				return fmt.Errorf("firestore nullify item location: %w", err)
 			}
 		}
 
-		err := tx.Delete(repo.client.Collection("locations").Doc(id))
+		err = tx.Delete(locationDoc)
 		if err != nil {
 			return fmt.Errorf("firestore delete location: %w", err)
 		}
@@ -188,80 +299,1127 @@ func (repo firestoreRepository) DeleteLocation(ctx context.Context, id string) e
 	return nil
 }
 
-func (repo firestoreRepository) GetItems(ctx context.Context, tags *[]string, locationIDs *[]string) ([]item, error) {
+// firestoreItemsOrderBy maps the sorts to document fields, combined with filters they need composite indexes.
+var firestoreItemsOrderBy = map[itemsSort]string{
+	itemsSortName:     "Name",
+	itemsSortBoughtAt: "BoughtAt",
+	itemsSortPrice:    "Price",
+}
+
+// firestoreMaxInValues is the maximum number of values an "in" filter accepts.
+const firestoreMaxInValues = 30
+
+func (repo firestoreRepository) GetItems(ctx context.Context, query itemsQuery) ([]item, error) {
 	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetItems")
 	defer span.End()
 
-	q := repo.client.Collection("items").Query
+	// Firestore rejects filters on empty lists
+	if (query.Tags != nil && len(*query.Tags) == 0) || (query.LocationIDs != nil && len(*query.LocationIDs) == 0) {
+		return []item{}, nil
+	}
 
-	if tags != nil {
-		q = q.Where("Tags", "array-contains-any", *tags)
+	if query.LocationIDs == nil || len(*query.LocationIDs) <= firestoreMaxInValues {
+		return repo.queryItems(ctx, query)
 	}
 
-	if locationIDs != nil {
-		q = q.Where("LocationID", "in", locationIDs)
+	// each query can only filter by a chunk of the locations, their pages are merged
+	items := []item{}
+
+	for chunk := range slices.Chunk(*query.LocationIDs, firestoreMaxInValues) {
+		chunkQuery := query
+		chunkQuery.LocationIDs = &chunk
+
+		chunkItems, err := repo.queryItems(ctx, chunkQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, chunkItems...)
 	}
 
-	iter := q.Documents(ctx)
+	slices.SortFunc(items, func(a, b item) int { return compareItems(a, b, query.Sort.stored()) })
 
-	return firestoreToItems(iter)
+	return limitEntries(items, query.Limit), nil
 }
 
-func (repo firestoreRepository) CreateItem(ctx context.Context, params writeItemParams) error {
-	id := uuid.NewString()
+func (repo firestoreRepository) queryItems(ctx context.Context, query itemsQuery) ([]item, error) {
+	items := []item{}
 
-	_, err := repo.client.
-		Collection("items").
-		Doc(id).
-		Set(ctx, params)
-	if err != nil {
-		return fmt.Errorf("firestore create location: %w", err)
+	for {
+		q := repo.collection(ctx, "items").Query
+
+		if query.Tags != nil {
+			q = q.Where("Tags", "array-contains-any", *query.Tags)
+		}
+
+		if query.LocationIDs != nil {
+			q = q.Where("LocationID", "in", *query.LocationIDs)
+		}
+
+		if query.Unassigned {
+			q = q.Where("LocationID", "==", nil)
+		}
+
+		if query.Outcome != nil {
+			q = q.Where("Outcome", "==", *query.Outcome)
+		}
+
+		// range filters need the first ordering to be on the same field, otherwise they are applied below
+		if query.Sort == itemsSortBoughtAt && query.BoughtFrom != nil {
+			q = q.Where("BoughtAt", ">=", *query.BoughtFrom)
+		}
+
+		if query.Sort == itemsSortBoughtAt && query.BoughtBefore != nil {
+			q = q.Where("BoughtAt", "<", *query.BoughtBefore)
+		}
+
+		field, hasField := firestoreItemsOrderBy[query.Sort]
+		if hasField {
+			q = q.OrderBy(field, firestore.Asc)
+		}
+
+		q = q.OrderBy(firestore.DocumentID, firestore.Asc)
+
+		if query.After != nil {
+			q = q.StartAfter(firestoreItemsCursorValues(query.Sort, *query.After)...)
+		}
+
+		if query.Limit > 0 {
+			q = q.Limit(query.Limit)
+		}
+
+		page, err := firestoreToItems(q.Documents(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		pageLen := len(page)
+		if pageLen > 0 {
+			query.After = getPtr(newItemsCursor(page[pageLen-1], query.Sort))
+		}
+
+		// an inequality filter would have to be the first ordering and documents written before archiving was added
+		// lack the field, so these filters are applied here and more pages are read until the limit is reached
+		page = slices.DeleteFunc(page, func(i item) bool {
+			return (query.OpenedOnly && i.OpenedAt == nil) || query.Archived != (i.ArchivedAt != nil) ||
+				(query.BoughtFrom != nil && i.BoughtAt.Before(*query.BoughtFrom)) ||
+				(query.BoughtBefore != nil && !i.BoughtAt.Before(*query.BoughtBefore))
+		})
+
+		items = append(items, page...)
+
+		if query.Limit == 0 || pageLen < query.Limit || len(items) >= query.Limit {
+			return limitEntries(items, query.Limit), nil
+		}
 	}
+}
 
-	return nil
+// firestoreItemsCursorValues returns the values of the ordered fields for the cursor.
+func firestoreItemsCursorValues(sort itemsSort, after itemsCursor) []any {
+	switch sort {
+	case itemsSortName:
+		return []any{after.Name, after.ID}
+	case itemsSortBoughtAt:
+		return []any{after.BoughtAt, after.ID}
+	case itemsSortPrice:
+		if after.Price == nil {
+			return []any{nil, after.ID}
+		}
+
+		return []any{*after.Price, after.ID}
+	case itemsSortID, itemsSortDaysLeft:
+	}
+
+	return []any{after.ID}
 }
 
-func (repo firestoreRepository) UpdateItem(ctx context.Context, id string, params writeItemParams) error {
-	doc := repo.client.Collection("items").Doc(id)
+func (repo firestoreRepository) GetItem(ctx context.Context, id string) (item, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetItem")
+	defer span.End()
 
-	_, err := doc.Get(ctx)
+	doc, err := repo.collection(ctx, "items").Doc(id).Get(ctx)
 	if status.Code(err) == codes.NotFound {
-		return fmt.Errorf("item not found: %w", err)
+		return item{}, fmt.Errorf("%w: %w", errItemNotFound, err)
 	} else if err != nil {
-		return fmt.Errorf("firestore get item: %w", err)
+		return item{}, fmt.Errorf("firestore get item: %w", err)
 	}
 
-	_, err = doc.Set(ctx, params)
+	return firestoreToItem(doc)
+}
+
+func (repo firestoreRepository) CreateItem(ctx context.Context, params writeItemParams) (item, error) {
+	id := uuid.NewString()
+
+	_, err := repo.collection(ctx, "items").
+		Doc(id).
+		Set(ctx, firestoreItem{writeItemParams: params, Version: 1})
+	if err != nil {
+		return item{}, fmt.Errorf("firestore create item: %w", err)
+	}
+
+	// the item is read back to return it as stored, like with its times in UTC
+	return repo.GetItem(ctx, id)
+}
+
+func (repo firestoreRepository) UpdateItem(
+	ctx context.Context, id string, params writeItemParams, version *int,
+) error {
+	doc := repo.collection(ctx, "items").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		stored, err := firestoreGetVersion(tx, doc, version, errItemNotFound)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Set(doc, firestoreItem{writeItemParams: params, Version: stored + 1}); err != nil {
+			return fmt.Errorf("firestore update item: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("firestore update location: %w", err)
+		return fmt.Errorf("firestore transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (repo firestoreRepository) UpdateItemLocation(ctx context.Context, id string, locationID *string) error {
-	_, err := repo.client.
-		Collection("items").
-		Doc(id).
-		Update(ctx, []firestore.Update{{
-			Path:  "LocationID",
-			Value: locationID,
-		}})
+func (repo firestoreRepository) UpdateItemLocation(
+	ctx context.Context, id string, locationID *string, version *int,
+) error {
+	doc := repo.collection(ctx, "items").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		stored, err := firestoreGetVersion(tx, doc, version, errItemNotFound)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Update(doc, []firestore.Update{
+			{Path: "LocationID", Value: locationID},
+			{Path: "Version", Value: stored + 1},
+		})
+		if err != nil {
+			return fmt.Errorf("firestore update item location: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("firestore update item location: %w", err)
+		return fmt.Errorf("firestore transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (repo firestoreRepository) DeleteItem(ctx context.Context, id string) error {
-	_, err := repo.client.
-		Collection("items").
-		Doc(id).
-		Delete(ctx)
+func (repo firestoreRepository) DeleteItem(ctx context.Context, id string, version *int) error {
+	doc := repo.collection(ctx, "items").Doc(id)
+
+	if version == nil {
+		if _, err := doc.Delete(ctx); err != nil {
+			return fmt.Errorf("firestore delete item: %w", err)
+		}
+
+		return nil
+	}
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		// deleting an item that does not exist does not match the version either
+		if _, err := firestoreGetVersion(tx, doc, version, errVersionMismatch); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(doc); err != nil {
+			return fmt.Errorf("firestore delete item: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("firestore delete item: %w", err)
+		return fmt.Errorf("firestore transaction: %w", err)
 	}
 
 	return nil
 }
+
+// writeItemsTx queues the writes in the transaction, which has to read the items written by ID beforehand. The
+// versions hold the stored version of each of the existing items, they are updated as the writes are queued.
+func (repo firestoreRepository) writeItemsTx(
+	ctx context.Context, tx *firestore.Transaction, writes []itemWrite, versions map[string]int,
+) ([]string, error) {
+	ids := make([]string, len(writes))
+
+	for idx, w := range writes {
+		id := w.ID
+		if w.Op == itemBatchCreate {
+			id = uuid.NewString()
+		}
+
+		doc := repo.collection(ctx, "items").Doc(id)
+		stored, ok := versions[id]
+
+		var err error
+
+		switch {
+		case w.Op == itemBatchCreate:
+			stored, err = 0, tx.Create(doc, firestoreItem{writeItemParams: w.Params, Version: 1})
+		case w.Op == itemBatchDelete && w.Version != nil && (!ok || *w.Version != stored):
+			// deleting an item that does not exist does not match the version either
+			err = errVersionMismatch
+		case w.Op == itemBatchDelete:
+			err = tx.Delete(doc)
+		case !ok:
+			err = errItemNotFound
+		case w.Version != nil && *w.Version != stored:
+			err = errVersionMismatch
+		case w.Op == itemBatchUpdate:
+			err = tx.Set(doc, firestoreItem{writeItemParams: w.Params, Version: stored + 1})
+		case w.Op == itemBatchMove:
+			err = tx.Update(doc, []firestore.Update{
+				{Path: "LocationID", Value: w.LocationID},
+				{Path: "Version", Value: stored + 1},
+			})
+		}
+
+		if err != nil {
+			return nil, itemWriteError{Index: idx, Err: err}
+		}
+
+		if w.Op == itemBatchDelete {
+			delete(versions, id)
+		} else {
+			versions[id] = stored + 1
+		}
+
+		ids[idx] = id
+	}
+
+	return ids, nil
+}
+
+func (repo firestoreRepository) ApplyItemWrites(ctx context.Context, writes []itemWrite) ([]string, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.ApplyItemWrites")
+	defer span.End()
+
+	refs := []*firestore.DocumentRef{}
+
+	for _, w := range writes {
+		if w.Op != itemBatchCreate && !slices.ContainsFunc(refs, func(ref *firestore.DocumentRef) bool {
+			return ref.ID == w.ID
+		}) {
+			refs = append(refs, repo.collection(ctx, "items").Doc(w.ID))
+		}
+	}
+
+	var ids []string
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		versions := map[string]int{}
+
+		// all the reads of a transaction come before its writes
+		if len(refs) > 0 {
+			snaps, err := tx.GetAll(refs)
+			if err != nil {
+				return fmt.Errorf("firestore get items: %w", err)
+			}
+
+			for _, snap := range snaps {
+				if !snap.Exists() {
+					continue
+				}
+
+				if versions[snap.Ref.ID], err = firestoreToVersion(snap); err != nil {
+					return err
+				}
+			}
+		}
+
+		var err error
+
+		ids, err = repo.writeItemsTx(ctx, tx, writes, versions)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return ids, nil
+}
+
+func firestoreToShoppingListItem(doc *firestore.DocumentSnapshot) (shoppingListItem, error) {
+	e := shoppingListItem{ID: doc.Ref.ID, Tags: []string{}}
+	if err := doc.DataTo(&e); err != nil {
+		return shoppingListItem{}, fmt.Errorf("firestore to shopping list item: %w", err)
+	}
+
+	return e, nil
+}
+
+func (repo firestoreRepository) GetShoppingListItems(ctx context.Context) ([]shoppingListItem, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetShoppingListItems")
+	defer span.End()
+
+	iter := repo.collection(ctx, "shoppingList").
+		OrderBy("Name", firestore.Asc).
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Documents(ctx)
+
+	defer iter.Stop()
+
+	entries := []shoppingListItem{}
+
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("firestore get shopping list items next: %w", err)
+		}
+
+		e, err := firestoreToShoppingListItem(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func (repo firestoreRepository) GetShoppingListItem(ctx context.Context, id string) (shoppingListItem, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetShoppingListItem")
+	defer span.End()
+
+	doc, err := repo.collection(ctx, "shoppingList").Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return shoppingListItem{}, fmt.Errorf("%w: %w", errShoppingListItemNotFound, err)
+	} else if err != nil {
+		return shoppingListItem{}, fmt.Errorf("firestore get shopping list item: %w", err)
+	}
+
+	return firestoreToShoppingListItem(doc)
+}
+
+func (repo firestoreRepository) CreateShoppingListItem(
+	ctx context.Context, params writeShoppingListItemParams,
+) (shoppingListItem, error) {
+	id := uuid.NewString()
+
+	_, err := repo.collection(ctx, "shoppingList").
+		Doc(id).
+		Set(ctx, params)
+	if err != nil {
+		return shoppingListItem{}, fmt.Errorf("firestore create shopping list item: %w", err)
+	}
+
+	return repo.GetShoppingListItem(ctx, id)
+}
+
+func (repo firestoreRepository) UpdateShoppingListItem(
+	ctx context.Context, id string, params writeShoppingListItemParams,
+) error {
+	doc := repo.collection(ctx, "shoppingList").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		// the existence is checked in the transaction, so that a concurrent delete is not undone by the write
+		_, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %w", errShoppingListItemNotFound, err)
+		} else if err != nil {
+			return fmt.Errorf("firestore get shopping list item: %w", err)
+		}
+
+		if err := tx.Set(doc, params); err != nil {
+			return fmt.Errorf("firestore update shopping list item: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) DeleteShoppingListItem(ctx context.Context, id string) error {
+	doc := repo.collection(ctx, "shoppingList").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		// deleting a missing document succeeds, so its existence is checked first
+		_, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %w", errShoppingListItemNotFound, err)
+		} else if err != nil {
+			return fmt.Errorf("firestore get shopping list item: %w", err)
+		}
+
+		if err := tx.Delete(doc); err != nil {
+			return fmt.Errorf("firestore delete shopping list item: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) BuyShoppingListItem(
+	ctx context.Context, id string, params writeItemParams,
+) (item, error) {
+	entryDoc := repo.collection(ctx, "shoppingList").Doc(id)
+	itemDoc := repo.collection(ctx, "items").Doc(uuid.NewString())
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		_, err := tx.Get(entryDoc)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %w", errShoppingListItemNotFound, err)
+		} else if err != nil {
+			return fmt.Errorf("firestore get shopping list item: %w", err)
+		}
+
+		if err := tx.Create(itemDoc, firestoreItem{writeItemParams: params, Version: 1}); err != nil {
+			return fmt.Errorf("firestore create item: %w", err)
+		}
+
+		if err := tx.Delete(entryDoc); err != nil {
+			return fmt.Errorf("firestore delete shopping list item: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return item{}, fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	// the item is read back to return it as stored, like with its times in UTC
+	return repo.GetItem(ctx, itemDoc.ID)
+}
+
+func (repo firestoreRepository) GetExpiryRules(ctx context.Context) ([]expiryRule, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetExpiryRules")
+	defer span.End()
+
+	iter := repo.collection(ctx, "expiryRules").
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Documents(ctx)
+
+	defer iter.Stop()
+
+	rules := []expiryRule{}
+
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("firestore get expiry rules next: %w", err)
+		}
+
+		r := expiryRule{ID: doc.Ref.ID}
+		if err := doc.DataTo(&r); err != nil {
+			return nil, fmt.Errorf("firestore to expiry rule: %w", err)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func (repo firestoreRepository) CreateExpiryRule(
+	ctx context.Context, params writeExpiryRuleParams,
+) (expiryRule, error) {
+	id := uuid.NewString()
+
+	_, err := repo.collection(ctx, "expiryRules").
+		Doc(id).
+		Set(ctx, params)
+	if err != nil {
+		return expiryRule{}, fmt.Errorf("firestore create expiry rule: %w", err)
+	}
+
+	return expiryRuleFromParams(id, params), nil
+}
+
+func (repo firestoreRepository) UpdateExpiryRule(ctx context.Context, id string, params writeExpiryRuleParams) error {
+	doc := repo.collection(ctx, "expiryRules").Doc(id)
+
+	_, err := doc.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%w: %w", errExpiryRuleNotFound, err)
+	} else if err != nil {
+		return fmt.Errorf("firestore get expiry rule: %w", err)
+	}
+
+	_, err = doc.Set(ctx, params)
+	if err != nil {
+		return fmt.Errorf("firestore update expiry rule: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) DeleteExpiryRule(ctx context.Context, id string) error {
+	doc := repo.collection(ctx, "expiryRules").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		// deleting a missing document succeeds, so its existence is checked first
+		_, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %w", errExpiryRuleNotFound, err)
+		} else if err != nil {
+			return fmt.Errorf("firestore get expiry rule: %w", err)
+		}
+
+		if err := tx.Delete(doc); err != nil {
+			return fmt.Errorf("firestore delete expiry rule: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func firestoreToHousehold(doc *firestore.DocumentSnapshot) (household, error) {
+	h := household{ID: doc.Ref.ID}
+	if err := doc.DataTo(&h); err != nil {
+		return household{}, fmt.Errorf("firestore to household: %w", err)
+	}
+
+	return h, nil
+}
+
+// GetHouseholds includes the default household, which has no document as its data is in the root collections.
+func (repo firestoreRepository) GetHouseholds(ctx context.Context) ([]household, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetHouseholds")
+	defer span.End()
+
+	docs, err := repo.client.Collection("households").OrderBy(firestore.DocumentID, firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestore get households: %w", err)
+	}
+
+	households := []household{}
+
+	for _, doc := range docs {
+		h, err := firestoreToHousehold(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		households = append(households, h)
+	}
+
+	if !slices.ContainsFunc(households, func(h household) bool { return h.ID == defaultHouseholdID }) {
+		households = append(households, household{ID: defaultHouseholdID, Name: defaultHouseholdName})
+		slices.SortFunc(households, func(a, b household) int { return cmp.Compare(a.ID, b.ID) })
+	}
+
+	return households, nil
+}
+
+func (repo firestoreRepository) GetHousehold(ctx context.Context, id string) (household, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetHousehold")
+	defer span.End()
+
+	doc, err := repo.client.Collection("households").Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound && id == defaultHouseholdID {
+		return household{ID: defaultHouseholdID, Name: defaultHouseholdName}, nil
+	} else if status.Code(err) == codes.NotFound {
+		return household{}, fmt.Errorf("%w: %w", errHouseholdNotFound, err)
+	} else if err != nil {
+		return household{}, fmt.Errorf("firestore get household: %w", err)
+	}
+
+	return firestoreToHousehold(doc)
+}
+
+// firestoreHouseholdMember is the document of a user in the householdMembers collection.
+type firestoreHouseholdMember struct {
+	HouseholdID string
+	Role        householdRole
+}
+
+// firestoreToHouseholdMember defaults the role to owner for the members added before there were roles.
+func firestoreToHouseholdMember(doc *firestore.DocumentSnapshot) (firestoreHouseholdMember, error) {
+	m := firestoreHouseholdMember{}
+	if err := doc.DataTo(&m); err != nil {
+		return firestoreHouseholdMember{}, fmt.Errorf("firestore to household member: %w", err)
+	}
+
+	m.Role = cmp.Or(m.Role, householdRoleOwner)
+
+	return m, nil
+}
+
+func (repo firestoreRepository) GetUserHousehold(ctx context.Context, uid string) (userHousehold, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetUserHousehold")
+	defer span.End()
+
+	doc, err := repo.client.Collection("householdMembers").Doc(uid).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return userHousehold{}, fmt.Errorf("%w: %w", errHouseholdNotFound, err)
+	} else if err != nil {
+		return userHousehold{}, fmt.Errorf("firestore get household member: %w", err)
+	}
+
+	m, err := firestoreToHouseholdMember(doc)
+	if err != nil {
+		return userHousehold{}, err
+	}
+
+	h, err := repo.GetHousehold(ctx, m.HouseholdID)
+	if err != nil {
+		return userHousehold{}, err
+	}
+
+	return userHousehold{household: h, Role: m.Role}, nil
+}
+
+// addHouseholdMemberTx returns errHouseholdMemberExists if the user already belongs to a household.
+func (repo firestoreRepository) addHouseholdMemberTx(
+	tx *firestore.Transaction, householdID string, uid string, role householdRole,
+) error {
+	doc := repo.client.Collection("householdMembers").Doc(uid)
+
+	_, err := tx.Get(doc)
+	if err == nil {
+		return errHouseholdMemberExists
+	} else if status.Code(err) != codes.NotFound {
+		return fmt.Errorf("firestore get household member: %w", err)
+	}
+
+	if err := tx.Create(doc, firestoreHouseholdMember{HouseholdID: householdID, Role: role}); err != nil {
+		return fmt.Errorf("firestore add household member: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) CreateHousehold(ctx context.Context, name string, uid string) (household, error) {
+	h := household{ID: uuid.NewString(), Name: name}
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		if err := repo.addHouseholdMemberTx(tx, h.ID, uid, householdRoleOwner); err != nil {
+			return err
+		}
+
+		if err := tx.Create(repo.client.Collection("households").Doc(h.ID), map[string]any{"Name": h.Name}); err != nil {
+			return fmt.Errorf("firestore create household: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return household{}, fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return h, nil
+}
+
+func (repo firestoreRepository) AddHouseholdMember(
+	ctx context.Context, householdID string, uid string, role householdRole,
+) error {
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		_, err := tx.Get(repo.client.Collection("households").Doc(householdID))
+		if status.Code(err) == codes.NotFound && householdID != defaultHouseholdID {
+			return fmt.Errorf("%w: %w", errHouseholdNotFound, err)
+		} else if err != nil && status.Code(err) != codes.NotFound {
+			return fmt.Errorf("firestore get household: %w", err)
+		}
+
+		return repo.addHouseholdMemberTx(tx, householdID, uid, role)
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) GetHouseholdMembers(
+	ctx context.Context, householdID string,
+) ([]householdMember, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetHouseholdMembers")
+	defer span.End()
+
+	docs, err := repo.client.
+		Collection("householdMembers").
+		Where("HouseholdID", "==", householdID).
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestore get household members: %w", err)
+	}
+
+	members := []householdMember{}
+
+	for _, doc := range docs {
+		m, err := firestoreToHouseholdMember(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, householdMember{UID: doc.Ref.ID, Role: m.Role})
+	}
+
+	return members, nil
+}
+
+// getHouseholdMemberTx returns errHouseholdMemberNotFound if the user does not belong to the household.
+func getHouseholdMemberTx(tx *firestore.Transaction, doc *firestore.DocumentRef, householdID string) error {
+	snap, err := tx.Get(doc)
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%w: %w", errHouseholdMemberNotFound, err)
+	} else if err != nil {
+		return fmt.Errorf("firestore get household member: %w", err)
+	}
+
+	if m, err := firestoreToHouseholdMember(snap); err != nil || m.HouseholdID != householdID {
+		return errHouseholdMemberNotFound
+	}
+
+	return nil
+}
+
+// checkOtherOwnerTx returns errHouseholdLastOwner if the user is the last owner of the household with other
+// members. The members are read in the transaction, which is retried when they change before it commits.
+func (repo firestoreRepository) checkOtherOwnerTx(tx *firestore.Transaction, householdID string, uid string) error {
+	docs, err := tx.Documents(repo.client.Collection("householdMembers").Where("HouseholdID", "==", householdID)).
+		GetAll()
+	if err != nil {
+		return fmt.Errorf("firestore get household members: %w", err)
+	}
+
+	members := make([]householdMember, 0, len(docs))
+
+	for _, doc := range docs {
+		m, err := firestoreToHouseholdMember(doc)
+		if err != nil {
+			return err
+		}
+
+		members = append(members, householdMember{UID: doc.Ref.ID, Role: m.Role})
+	}
+
+	return checkOtherOwner(members, uid)
+}
+
+func (repo firestoreRepository) UpdateHouseholdMemberRole(
+	ctx context.Context, householdID string, uid string, role householdRole,
+) error {
+	doc := repo.client.Collection("householdMembers").Doc(uid)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		if err := getHouseholdMemberTx(tx, doc, householdID); err != nil {
+			return err
+		}
+
+		if role != householdRoleOwner {
+			if err := repo.checkOtherOwnerTx(tx, householdID, uid); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Update(doc, []firestore.Update{{Path: "Role", Value: role}}); err != nil {
+			return fmt.Errorf("firestore update household member role: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) RemoveHouseholdMember(ctx context.Context, householdID string, uid string) error {
+	doc := repo.client.Collection("householdMembers").Doc(uid)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		if err := getHouseholdMemberTx(tx, doc, householdID); err != nil {
+			return err
+		}
+
+		if err := repo.checkOtherOwnerTx(tx, householdID, uid); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(doc); err != nil {
+			return fmt.Errorf("firestore remove household member: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) CreateHouseholdInvite(ctx context.Context, invite householdInvite) error {
+	_, err := repo.client.
+		Collection("householdInvites").
+		Doc(invite.Code).
+		Set(ctx, map[string]any{
+			"HouseholdID": invite.HouseholdID,
+			"Role":        invite.Role,
+			"CreatedBy":   invite.CreatedBy,
+			"ExpiresAt":   invite.ExpiresAt,
+		})
+	if err != nil {
+		return fmt.Errorf("firestore create household invite: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) AcceptHouseholdInvite(
+	ctx context.Context, code string, uid string, now time.Time,
+) (userHousehold, error) {
+	inviteDoc := repo.client.Collection("householdInvites").Doc(code)
+	memberDoc := repo.client.Collection("householdMembers").Doc(uid)
+
+	var (
+		h    household
+		role householdRole
+	)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(inviteDoc)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %w", errHouseholdInviteNotFound, err)
+		} else if err != nil {
+			return fmt.Errorf("firestore get household invite: %w", err)
+		}
+
+		invite := householdInvite{Code: code}
+		if err := snap.DataTo(&invite); err != nil {
+			return fmt.Errorf("firestore to household invite: %w", err)
+		}
+
+		if !now.Before(invite.ExpiresAt) {
+			return errHouseholdInviteExpired
+		}
+
+		// the invites created before there were roles make the users editors
+		role = cmp.Or(invite.Role, householdRoleEditor)
+
+		householdSnap, err := tx.Get(repo.client.Collection("households").Doc(invite.HouseholdID))
+		switch {
+		case status.Code(err) == codes.NotFound && invite.HouseholdID == defaultHouseholdID:
+			h = household{ID: defaultHouseholdID, Name: defaultHouseholdName}
+		case status.Code(err) == codes.NotFound:
+			return fmt.Errorf("%w: %w", errHouseholdNotFound, err)
+		case err != nil:
+			return fmt.Errorf("firestore get household: %w", err)
+		default:
+			if h, err = firestoreToHousehold(householdSnap); err != nil {
+				return err
+			}
+		}
+
+		memberSnap, err := tx.Get(memberDoc)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return fmt.Errorf("firestore get household member: %w", err)
+		default:
+			current, err := firestoreToHouseholdMember(memberSnap)
+			if err != nil {
+				return err
+			}
+
+			if err := repo.checkOtherOwnerTx(tx, current.HouseholdID, uid); err != nil {
+				return err
+			}
+		}
+
+		// the membership of the user is replaced, moving them out of their current household
+		if err := tx.Set(memberDoc, firestoreHouseholdMember{HouseholdID: h.ID, Role: role}); err != nil {
+			return fmt.Errorf("firestore set household member: %w", err)
+		}
+
+		if err := tx.Delete(inviteDoc); err != nil {
+			return fmt.Errorf("firestore delete household invite: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return userHousehold{}, fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return userHousehold{household: h, Role: role}, nil
+}
+
+func firestoreToAccessToken(doc *firestore.DocumentSnapshot) (accessToken, error) {
+	t := accessToken{ID: doc.Ref.ID}
+	if err := doc.DataTo(&t); err != nil {
+		return accessToken{}, fmt.Errorf("firestore to access token: %w", err)
+	}
+
+	if t.Scopes == nil {
+		t.Scopes = []permission{}
+	}
+
+	return t, nil
+}
+
+func (repo firestoreRepository) GetAccessTokens(ctx context.Context, uid string) ([]accessToken, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetAccessTokens")
+	defer span.End()
+
+	docs, err := repo.client.Collection("accessTokens").Where("UID", "==", uid).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestore get access tokens: %w", err)
+	}
+
+	tokens := []accessToken{}
+
+	for _, doc := range docs {
+		t, err := firestoreToAccessToken(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	// sorted here as ordering by another field than the filtered one needs a composite index
+	slices.SortFunc(tokens, func(a, b accessToken) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return tokens, nil
+}
+
+func (repo firestoreRepository) GetAccessTokenByHash(ctx context.Context, hash string) (accessToken, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetAccessTokenByHash")
+	defer span.End()
+
+	docs, err := repo.client.Collection("accessTokens").Where("Hash", "==", hash).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return accessToken{}, fmt.Errorf("firestore get access token: %w", err)
+	}
+
+	if len(docs) == 0 {
+		return accessToken{}, errAccessTokenNotFound
+	}
+
+	return firestoreToAccessToken(docs[0])
+}
+
+func (repo firestoreRepository) CreateAccessToken(ctx context.Context, token accessToken) error {
+	_, err := repo.client.
+		Collection("accessTokens").
+		Doc(token.ID).
+		Create(ctx, map[string]any{
+			"UID":       token.UID,
+			"Name":      token.Name,
+			"Hash":      token.Hash,
+			"Scopes":    token.Scopes,
+			"CreatedAt": token.CreatedAt,
+			"ExpiresAt": token.ExpiresAt,
+		})
+	if err != nil {
+		return fmt.Errorf("firestore create access token: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) DeleteAccessToken(ctx context.Context, uid string, id string) error {
+	doc := repo.client.Collection("accessTokens").Doc(id)
+
+	err := repo.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %w", errAccessTokenNotFound, err)
+		} else if err != nil {
+			return fmt.Errorf("firestore get access token: %w", err)
+		}
+
+		if tokenUID, err := snap.DataAt("UID"); err != nil || tokenUID != uid {
+			return errAccessTokenNotFound
+		}
+
+		if err := tx.Delete(doc); err != nil {
+			return fmt.Errorf("firestore delete access token: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestore transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) SetUserEmail(ctx context.Context, uid string, email string) error {
+	if _, err := repo.client.Collection("userEmails").Doc(uid).Set(ctx, map[string]any{"Email": email}); err != nil {
+		return fmt.Errorf("firestore set user email: %w", err)
+	}
+
+	return nil
+}
+
+func (repo firestoreRepository) GetUserEmails(ctx context.Context, uids *[]string) ([]string, error) {
+	ctx, span := repo.tracer.Start(ctx, "firestoreRepository.GetUserEmails")
+	defer span.End()
+
+	var (
+		docs []*firestore.DocumentSnapshot
+		err  error
+	)
+
+	if uids == nil {
+		docs, err = repo.client.Collection("userEmails").OrderBy(firestore.DocumentID, firestore.Asc).Documents(ctx).GetAll()
+	} else {
+		refs := []*firestore.DocumentRef{}
+		for _, uid := range slices.Sorted(slices.Values(*uids)) {
+			refs = append(refs, repo.client.Collection("userEmails").Doc(uid))
+		}
+
+		docs, err = repo.client.GetAll(ctx, refs)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("firestore get user emails: %w", err)
+	}
+
+	emails := []string{}
+
+	for _, doc := range docs {
+		// the users without a stored email address are skipped
+		if !doc.Exists() {
+			continue
+		}
+
+		email, err := doc.DataAt("Email")
+		if err != nil {
+			return nil, fmt.Errorf("firestore to user email: %w", err)
+		}
+
+		if s, ok := email.(string); ok {
+			emails = append(emails, s)
+		}
+	}
+
+	return emails, nil
+}
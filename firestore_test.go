@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestFirestoreRepository returns a repository connected to the Firestore emulator.
+// Every repository uses its own project, so that tests do not see each other's documents.
+func newTestFirestoreRepository(t *testing.T) firestoreRepository {
+	t.Helper()
+
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	client, err := firestore.NewClient(context.Background(), "test-"+uuid.NewString())
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	t.Cleanup(func() { client.Close() }) //nolint:errcheck,gosec
+
+	return firestoreRepository{client: client, tracer: otel.Tracer("firestore")}
+}
+
+func TestFirestoreRepositoryContract(t *testing.T) {
+	t.Parallel()
+
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	runRepositoryContract(t, func(t *testing.T) repository { return newTestFirestoreRepository(t) })
+}
+
+func TestFirestoreGetLocationsWithIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newTestFirestoreRepository(t)
+
+	for id, name := range map[string]string{"fridge": "Fridge", "pantry": "Pantry", "freezer": "Freezer"} {
+		if _, err := repo.client.Collection("locations").Doc(id).Set(ctx, map[string]any{"Name": name}); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	locs, err := repo.GetLocations(ctx, locationsQuery{IDs: &[]string{"pantry", "missing", "fridge"}})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	expected := []location{{ID: "pantry", Name: "Pantry"}, {ID: "fridge", Name: "Fridge"}}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Errorf("Got %+v instead of %+v", locs, expected)
+	}
+}
+
+func TestFirestoreGetItemsCombinedFilters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newTestFirestoreRepository(t)
+	docs := map[string]writeItemParams{
+		"cheese": {Name: "Cheese", Tags: []string{"dairy", "smelly"}, LocationID: getPtr("fridge")},
+		"milk":   {Name: "Milk", Tags: []string{"dairy"}, LocationID: getPtr("door")},
+		"garlic": {Name: "Garlic", Tags: []string{"smelly"}, LocationID: getPtr("pantry")},
+		"yogurt": {Name: "Yogurt", Tags: []string{"dairy"}},
+	}
+
+	for id, params := range docs {
+		params.BoughtAt = time.Now()
+		if _, err := repo.client.Collection("items").Doc(id).Set(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	items, err := repo.GetItems(ctx, itemsQuery{
+		Tags: &[]string{"dairy", "smelly"}, LocationIDs: &[]string{"fridge", "door"},
+	})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	ids := []string{}
+	for _, i := range items {
+		ids = append(ids, i.ID)
+	}
+
+	slices.Sort(ids)
+
+	if !reflect.DeepEqual(ids, []string{"cheese", "milk"}) {
+		t.Errorf("Got items %v instead of cheese and milk", ids)
+	}
+}
+
+func TestFirestoreGetItemsManyLocations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newTestFirestoreRepository(t)
+	locationIDs := []string{}
+
+	// more locations than a single "in" filter accepts
+	for idx := range firestoreMaxInValues + 5 {
+		locationID := fmt.Sprintf("location-%02d", idx)
+		locationIDs = append(locationIDs, locationID)
+
+		params := writeItemParams{
+			Name: fmt.Sprintf("Item %02d", idx), Tags: []string{}, BoughtAt: time.Now(), LocationID: &locationID,
+		}
+		if _, err := repo.client.Collection("items").Doc(fmt.Sprintf("item-%02d", idx)).Set(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	items, err := repo.GetItems(ctx, itemsQuery{LocationIDs: &locationIDs, Sort: itemsSortName, Limit: 33})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if len(items) != 33 || items[0].Name != "Item 00" || items[32].Name != "Item 32" {
+		t.Errorf("Expected the first 33 items sorted by name, got %+v", items)
+	}
+}
+
+func TestFirestoreDeleteLocation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newTestFirestoreRepository(t)
+
+	if _, err := repo.client.Collection("locations").Doc("fridge").Set(ctx, map[string]any{"Name": "Fridge"}); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	docs := map[string]*string{"cheese": getPtr("fridge"), "milk": getPtr("fridge"), "rice": getPtr("pantry")}
+
+	for id, locationID := range docs {
+		params := writeItemParams{Name: id, Tags: []string{}, BoughtAt: time.Now(), LocationID: locationID}
+		if _, err := repo.client.Collection("items").Doc(id).Set(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	if err := repo.DeleteLocation(ctx, "fridge", nil); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if _, err := repo.client.Collection("locations").Doc("fridge").Get(ctx); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected the location document to be deleted, got error %v", err)
+	}
+
+	expected := map[string]any{"cheese": nil, "milk": nil, "rice": "pantry"}
+
+	for id, locationID := range expected {
+		doc, err := repo.client.Collection("items").Doc(id).Get(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if val, err := doc.DataAt("LocationID"); err != nil || val != locationID {
+			t.Errorf("Item %s has location %v instead of %v (error: %v)", id, val, locationID, err)
+		}
+	}
+}
+
+func TestFirestoreUpdateItemNotFound(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newTestFirestoreRepository(t)
+
+	err := repo.UpdateItem(ctx, "missing", writeItemParams{Name: "Tea", Tags: []string{}, BoughtAt: time.Now()}, nil)
+	if !errors.Is(err, errItemNotFound) {
+		t.Errorf("Expected errItemNotFound, got %v", err)
+	}
+
+	if _, err := repo.client.Collection("items").Doc("missing").Get(ctx); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected the item not to be created, got error %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nickelghost/nghttp"
+	"github.com/nickelghost/ngtel"
+)
+
+// defaultHouseholdID is the household holding the data stored before there were households. It is also the
+// household of every request when authentication is disabled.
+const defaultHouseholdID = "default"
+
+const defaultHouseholdName = "Default"
+
+// newHouseholdName is the name of the household created for a user who does not belong to any.
+const newHouseholdName = "Household"
+
+// defaultInviteDays is how long an invite can be accepted when its expiry is not given.
+const defaultInviteDays = 7
+
+var (
+	errHouseholdNotFound       = errors.New("household not found")
+	errHouseholdMemberExists   = errors.New("user already belongs to a household")
+	errHouseholdMemberNotFound = errors.New("household member not found")
+	errHouseholdInviteNotFound = errors.New("household invite not found")
+	errHouseholdInviteExpired  = errors.New("household invite expired")
+	errHouseholdLastOwner      = errors.New("household needs another owner")
+	errForbidden               = errors.New("forbidden")
+)
+
+// householdRole is what a member is allowed to do in their household.
+type householdRole string
+
+const (
+	householdRoleOwner  householdRole = "owner"
+	householdRoleEditor householdRole = "editor"
+	householdRoleViewer householdRole = "viewer"
+)
+
+// permission is what a route requires from the role of the user in their household.
+type permission string
+
+const (
+	// permissionView reads the data of the household
+	permissionView permission = "view"
+	// permissionUse records what happens to the items, like consuming them, and writes the shopping list
+	permissionUse permission = "use"
+	// permissionEdit writes the locations, items and expiry rules
+	permissionEdit permission = "edit"
+	// permissionManage invites and removes the members and changes their roles
+	permissionManage permission = "manage"
+)
+
+var householdRolePermissions = map[householdRole][]permission{
+	householdRoleOwner:  {permissionView, permissionUse, permissionEdit, permissionManage},
+	householdRoleEditor: {permissionView, permissionUse, permissionEdit},
+	householdRoleViewer: {permissionView, permissionUse},
+}
+
+// checkPermission returns errForbidden if the role does not have the permission.
+func (role householdRole) checkPermission(p permission) error {
+	if !slices.Contains(householdRolePermissions[role], p) {
+		return fmt.Errorf("%w: the %s role does not have the %s permission", errForbidden, role, p)
+	}
+
+	return nil
+}
+
+type household struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// userHousehold is the household of a user along with the role of the user in it.
+type userHousehold struct {
+	household
+
+	Role householdRole `json:"role"`
+}
+
+type householdMember struct {
+	UID  string        `json:"uid"`
+	Role householdRole `json:"role"`
+}
+
+// householdInvite lets the user accepting it join the household with the role, once and until it expires.
+type householdInvite struct {
+	Code        string        `json:"code"`
+	HouseholdID string        `json:"householdId"`
+	Role        householdRole `json:"role"`
+	CreatedBy   string        `json:"createdBy"`
+	ExpiresAt   time.Time     `json:"expiresAt"`
+}
+
+type createHouseholdInviteParams struct {
+	// Role defaults to editor
+	Role householdRole `json:"role" validate:"omitempty,oneof=owner editor viewer"`
+	// ExpiresInDays is how long the invite can be accepted, defaultInviteDays when 0
+	ExpiresInDays int `json:"expiresInDays" validate:"omitempty,min=1,max=30"`
+}
+
+type updateHouseholdMemberParams struct {
+	Role householdRole `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+type contextKey int
+
+const (
+	uidContextKey contextKey = iota
+	householdIDContextKey
+	householdRoleContextKey
+	scopesContextKey
+	accessTokenIDContextKey
+)
+
+func withUID(ctx context.Context, uid string) context.Context {
+	return context.WithValue(ctx, uidContextKey, uid)
+}
+
+// getUID returns the authenticated user, an empty string if authentication is disabled.
+func getUID(ctx context.Context) string {
+	uid, _ := ctx.Value(uidContextKey).(string)
+
+	return uid
+}
+
+func withHouseholdID(ctx context.Context, householdID string) context.Context {
+	return context.WithValue(ctx, householdIDContextKey, householdID)
+}
+
+// getHouseholdID returns the household the repository scopes the data to, the default one if none is set.
+func getHouseholdID(ctx context.Context) string {
+	if householdID, ok := ctx.Value(householdIDContextKey).(string); ok && householdID != "" {
+		return householdID
+	}
+
+	return defaultHouseholdID
+}
+
+func withHouseholdRole(ctx context.Context, role householdRole) context.Context {
+	return context.WithValue(ctx, householdRoleContextKey, role)
+}
+
+// getHouseholdRole returns the role of the user in the household of the context. Without authentication there is
+// no user, and the requests are allowed everything like an owner.
+func getHouseholdRole(ctx context.Context) householdRole {
+	if role, ok := ctx.Value(householdRoleContextKey).(householdRole); ok && role != "" {
+		return role
+	}
+
+	return householdRoleOwner
+}
+
+// getUserHousehold returns the household of the user, creating one owned by the user if they do not belong to any.
+// The users in defaultUIDs join the default household as owners instead, so that they keep the data stored before
+// there were households.
+func getUserHousehold(ctx context.Context, repo repository, uid string, defaultUIDs []string) (userHousehold, error) {
+	uh, err := repo.GetUserHousehold(ctx, uid)
+	if !errors.Is(err, errHouseholdNotFound) {
+		return uh, err
+	}
+
+	if slices.Contains(defaultUIDs, uid) {
+		err = repo.AddHouseholdMember(ctx, defaultHouseholdID, uid, householdRoleOwner)
+	} else {
+		_, err = repo.CreateHousehold(ctx, newHouseholdName, uid)
+	}
+
+	// errHouseholdMemberExists means a concurrent request of the user has already resolved the household
+	if err != nil && !errors.Is(err, errHouseholdMemberExists) {
+		return userHousehold{}, err
+	}
+
+	return repo.GetUserHousehold(ctx, uid)
+}
+
+// householdMiddleware scopes the request to the household of the user authenticated by authMiddleware, along with
+// the role of the user checked by permissionMiddleware.
+func householdMiddleware(next http.Handler, repo repository, defaultUIDs []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uh, err := getUserHousehold(r.Context(), repo, getUID(r.Context()), defaultUIDs)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		ctx := withHouseholdRole(withHouseholdID(r.Context(), uh.ID), uh.Role)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// createHouseholdInvite creates an invite to the household of the context.
+func createHouseholdInvite(
+	ctx context.Context, repo repository, validate *validator.Validate, params createHouseholdInviteParams,
+) (householdInvite, error) {
+	if err := validate.Struct(params); err != nil {
+		return householdInvite{}, fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	invite := householdInvite{
+		Code:        rand.Text(),
+		HouseholdID: getHouseholdID(ctx),
+		Role:        cmp.Or(params.Role, householdRoleEditor),
+		CreatedBy:   getUID(ctx),
+		ExpiresAt:   time.Now().UTC().Truncate(time.Second).AddDate(0, 0, cmp.Or(params.ExpiresInDays, defaultInviteDays)),
+	}
+
+	if err := repo.CreateHouseholdInvite(ctx, invite); err != nil {
+		return householdInvite{}, fmt.Errorf("create household invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// acceptHouseholdInvite moves the user of the context to the household of the invite, leaving their current one.
+func acceptHouseholdInvite(ctx context.Context, repo repository, code string) (userHousehold, error) {
+	uid := getUID(ctx)
+	if uid == "" {
+		return userHousehold{}, fmt.Errorf("%w: accepting an invite requires authentication", errValidation)
+	}
+
+	uh, err := repo.AcceptHouseholdInvite(ctx, code, uid, time.Now())
+	if err != nil {
+		return userHousehold{}, fmt.Errorf("accept household invite: %w", err)
+	}
+
+	return uh, nil
+}
+
+func listHouseholdMembers(ctx context.Context, repo repository) ([]householdMember, error) {
+	members, err := repo.GetHouseholdMembers(ctx, getHouseholdID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get household members: %w", err)
+	}
+
+	return members, nil
+}
+
+// checkOtherOwner returns errHouseholdLastOwner if the user is the only owner among the members of a household
+// while there are other members, who would be left without an owner once the user leaves or stops being one.
+// The repositories check it within the change of the membership, so that concurrent changes cannot both pass it.
+func checkOtherOwner(members []householdMember, uid string) error {
+	isUserOwner := false
+	otherOwners := 0
+
+	for _, m := range members {
+		switch {
+		case m.Role != householdRoleOwner:
+		case m.UID == uid:
+			isUserOwner = true
+		default:
+			otherOwners++
+		}
+	}
+
+	if isUserOwner && otherOwners == 0 && len(members) > 1 {
+		return errHouseholdLastOwner
+	}
+
+	return nil
+}
+
+// removeHouseholdMember removes the user from the household of the context, the user gets a household of their
+// own on their next request. The last owner can only be removed along with the other members.
+func removeHouseholdMember(ctx context.Context, repo repository, uid string) error {
+	if err := repo.RemoveHouseholdMember(ctx, getHouseholdID(ctx), uid); err != nil {
+		return fmt.Errorf("remove household member: %w", err)
+	}
+
+	return nil
+}
+
+// updateHouseholdMember changes the role of the member of the household of the context, the last owner keeps their
+// role while there are other members.
+func updateHouseholdMember(
+	ctx context.Context, repo repository, validate *validator.Validate, uid string, params updateHouseholdMemberParams,
+) error {
+	if err := validate.Struct(params); err != nil {
+		return fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	if err := repo.UpdateHouseholdMemberRole(ctx, getHouseholdID(ctx), uid, params.Role); err != nil {
+		return fmt.Errorf("update household member role: %w", err)
+	}
+
+	return nil
+}
+
+// notifyHouseholds sends each household the notification about its items, addressed to the emails of its members.
+// The default household without members, like when authentication is disabled, is addressed to all the users as it
+// was before there were households. A failing household does not stop the others from being notified.
+//
+// The notifiers not sending to the emails only get the notification of the default household, so that the items of
+// the other households do not reach the operator.
+func notifyHouseholds(ctx context.Context, repo repository, n notifier, authRepo authenticationRepository) error {
+	if !n.sendsToRecipients() {
+		if err := notifyAboutItems(withHouseholdID(ctx, defaultHouseholdID), repo, n, nil); err != nil {
+			return fmt.Errorf("household %s: %w", defaultHouseholdID, err)
+		}
+
+		return nil
+	}
+
+	households, err := repo.GetHouseholds(ctx)
+	if err != nil {
+		return fmt.Errorf("get households: %w", err)
+	}
+
+	errs := []error{}
+
+	for _, h := range households {
+		members, err := repo.GetHouseholdMembers(ctx, h.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("household %s: get household members: %w", h.ID, err))
+
+			continue
+		}
+
+		var emails []string
+
+		switch {
+		case len(members) > 0:
+			uids := make([]string, 0, len(members))
+			for _, m := range members {
+				uids = append(uids, m.UID)
+			}
+
+			emails, err = authRepo.GetEmails(ctx, uids)
+		case h.ID == defaultHouseholdID:
+			emails, err = authRepo.GetAllEmails(ctx)
+		default:
+			slog.Info("Household has no members, skipping the notification.", "householdId", h.ID)
+
+			continue
+		}
+
+		if errors.Is(err, errNoEmailAddressesFound) {
+			slog.Info("Household has no email addresses, skipping the notification.", "householdId", h.ID)
+
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("household %s: get emails: %w", h.ID, err))
+
+			continue
+		}
+
+		if err := notifyAboutItems(withHouseholdID(ctx, h.ID), repo, n, emails); err != nil {
+			errs = append(errs, fmt.Errorf("household %s: %w", h.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
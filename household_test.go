@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type mockAuthenticationRepository struct {
+	emails map[string]string
+}
+
+func (repo mockAuthenticationRepository) GetEmails(_ context.Context, uids []string) ([]string, error) {
+	emails := []string{}
+
+	for _, uid := range uids {
+		if email, ok := repo.emails[uid]; ok {
+			emails = append(emails, email)
+		}
+	}
+
+	if len(emails) == 0 {
+		return nil, errNoEmailAddressesFound
+	}
+
+	return emails, nil
+}
+
+func (repo mockAuthenticationRepository) GetAllEmails(_ context.Context) ([]string, error) {
+	if len(repo.emails) == 0 {
+		return nil, errNoEmailAddressesFound
+	}
+
+	return slices.Sorted(maps.Values(repo.emails)), nil
+}
+
+func TestGetUserHousehold(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	defaultUIDs := []string{"alice"}
+
+	alice, err := getUserHousehold(ctx, repo, "alice", defaultUIDs)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if alice.ID != defaultHouseholdID || alice.Role != householdRoleOwner {
+		t.Errorf("Expected alice to join the default household as an owner, got %+v", alice)
+	}
+
+	bob, err := getUserHousehold(ctx, repo, "bob", defaultUIDs)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if bob.ID == defaultHouseholdID || bob.Name != newHouseholdName || bob.Role != householdRoleOwner {
+		t.Errorf("Expected bob to get a household of their own, got %+v", bob)
+	}
+
+	again, err := getUserHousehold(ctx, repo, "bob", defaultUIDs)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if again != bob {
+		t.Errorf("Expected bob to stay in %+v, got %+v", bob, again)
+	}
+
+	if households, err := repo.GetHouseholds(ctx); err != nil || len(households) != 2 {
+		t.Errorf("Expected the default household and the one of bob, got %+v with error %v", households, err)
+	}
+}
+
+func TestGetUserHouseholdErr(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test")
+	mockRepo := &mockRepository{GetUserHouseholdErr: errTest}
+
+	if _, err := getUserHousehold(context.Background(), mockRepo, "alice", nil); !errors.Is(err, errTest) {
+		t.Errorf("Expected the repository error, got %v", err)
+	}
+
+	if mockRepo.CreateHouseholdCalls != 0 || mockRepo.AddHouseholdMemberCalls != 0 {
+		t.Error("Added the user to a household although reading their household failed")
+	}
+}
+
+func TestGetHouseholdID(t *testing.T) {
+	t.Parallel()
+
+	if id := getHouseholdID(context.Background()); id != defaultHouseholdID {
+		t.Errorf("Expected the default household without one in the context, got %s", id)
+	}
+
+	if id := getHouseholdID(withHouseholdID(context.Background(), "smiths")); id != "smiths" {
+		t.Errorf("Expected the household of the context, got %s", id)
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		role    householdRole
+		allowed []permission
+	}{
+		{householdRoleOwner, []permission{permissionView, permissionUse, permissionEdit, permissionManage}},
+		{householdRoleEditor, []permission{permissionView, permissionUse, permissionEdit}},
+		{householdRoleViewer, []permission{permissionView, permissionUse}},
+		{"unknown", []permission{}},
+	}
+
+	for _, s := range scenarios {
+		for _, p := range []permission{permissionView, permissionUse, permissionEdit, permissionManage} {
+			err := s.role.checkPermission(p)
+			if slices.Contains(s.allowed, p) && err != nil {
+				t.Errorf("Expected %s to have the %s permission, got %v", s.role, p, err)
+			} else if !slices.Contains(s.allowed, p) && !errors.Is(err, errForbidden) {
+				t.Errorf("Expected errForbidden for %s with the %s permission, got %v", s.role, p, err)
+			}
+		}
+	}
+}
+
+func TestGetHouseholdRole(t *testing.T) {
+	t.Parallel()
+
+	if role := getHouseholdRole(context.Background()); role != householdRoleOwner {
+		t.Errorf("Expected the owner role without authentication, got %s", role)
+	}
+
+	ctx := withHouseholdRole(context.Background(), householdRoleViewer)
+	if role := getHouseholdRole(ctx); role != householdRoleViewer {
+		t.Errorf("Expected the role of the context, got %s", role)
+	}
+}
+
+func TestLastHouseholdOwner(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	repo := newMemoryRepository()
+
+	smiths, err := repo.CreateHousehold(context.Background(), "Smiths", "alice")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	ctx := withHouseholdID(context.Background(), smiths.ID)
+	owner := updateHouseholdMemberParams{Role: householdRoleOwner}
+	viewer := updateHouseholdMemberParams{Role: householdRoleViewer}
+
+	// alone in the household, alice can give up being its owner
+	if err := updateHouseholdMember(ctx, repo, validate, "alice", viewer); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if err := updateHouseholdMember(ctx, repo, validate, "alice", owner); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if err := repo.AddHouseholdMember(ctx, smiths.ID, "bob", householdRoleEditor); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	err = updateHouseholdMember(ctx, repo, validate, "alice", updateHouseholdMemberParams{Role: householdRoleEditor})
+	if !errors.Is(err, errHouseholdLastOwner) {
+		t.Errorf("Expected errHouseholdLastOwner demoting the last owner, got %v", err)
+	}
+
+	if err := removeHouseholdMember(ctx, repo, "alice"); !errors.Is(err, errHouseholdLastOwner) {
+		t.Errorf("Expected errHouseholdLastOwner removing the last owner, got %v", err)
+	}
+
+	err = updateHouseholdMember(ctx, repo, validate, "bob", updateHouseholdMemberParams{})
+	if !errors.Is(err, errValidation) {
+		t.Errorf("Expected errValidation without a role, got %v", err)
+	}
+
+	if err := updateHouseholdMember(ctx, repo, validate, "bob", owner); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	// with bob as another owner, alice can step down and leave
+	if err := updateHouseholdMember(ctx, repo, validate, "alice", viewer); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if err := removeHouseholdMember(ctx, repo, "alice"); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	members, err := repo.GetHouseholdMembers(ctx, smiths.ID)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if !reflect.DeepEqual(members, []householdMember{{UID: "bob", Role: householdRoleOwner}}) {
+		t.Errorf("Expected bob to be the only owner left, got %+v", members)
+	}
+}
+
+func TestCreateHouseholdInvite(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	ctx := withHouseholdID(withUID(context.Background(), "alice"), "smiths")
+	mockRepo := &mockRepository{}
+
+	invite, err := createHouseholdInvite(ctx, mockRepo, validate, createHouseholdInviteParams{})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if mockRepo.CreateHouseholdInviteCalls != 1 || mockRepo.CreateHouseholdInviteInvite != invite {
+		t.Errorf("Stored %+v instead of %+v", mockRepo.CreateHouseholdInviteInvite, invite)
+	}
+
+	if invite.Code == "" || invite.HouseholdID != "smiths" || invite.Role != householdRoleEditor ||
+		invite.CreatedBy != "alice" {
+		t.Errorf("Got invalid invite %+v", invite)
+	}
+
+	if days := time.Until(invite.ExpiresAt).Hours() / 24; days < defaultInviteDays-1 || days > defaultInviteDays {
+		t.Errorf("Expected the invite to expire in %d days, got %+v", defaultInviteDays, invite.ExpiresAt)
+	}
+
+	params := createHouseholdInviteParams{Role: householdRoleViewer, ExpiresInDays: 1}
+
+	other, err := createHouseholdInvite(ctx, mockRepo, validate, params)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if other.Code == invite.Code || other.Role != householdRoleViewer || time.Until(other.ExpiresAt) > 24*time.Hour {
+		t.Errorf("Expected another code for a viewer expiring within a day, got %+v", other)
+	}
+
+	for _, params := range []createHouseholdInviteParams{{ExpiresInDays: 31}, {Role: "admin"}} {
+		if _, err := createHouseholdInvite(ctx, mockRepo, validate, params); !errors.Is(err, errValidation) {
+			t.Errorf("Expected errValidation for %+v, got %v", params, err)
+		}
+	}
+}
+
+func TestAcceptHouseholdInvite(t *testing.T) {
+	t.Parallel()
+
+	smiths := userHousehold{household: household{ID: "smiths", Name: "Smiths"}, Role: householdRoleEditor}
+	mockRepo := &mockRepository{AcceptHouseholdInviteRes: smiths}
+
+	if _, err := acceptHouseholdInvite(context.Background(), mockRepo, "code"); !errors.Is(err, errValidation) {
+		t.Errorf("Expected errValidation without a user, got %v", err)
+	}
+
+	h, err := acceptHouseholdInvite(withUID(context.Background(), "bob"), mockRepo, "code")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if h != smiths || mockRepo.AcceptHouseholdInviteCode != "code" || mockRepo.AcceptHouseholdInviteUID != "bob" {
+		t.Errorf("Got %+v accepting %q for %q", h, mockRepo.AcceptHouseholdInviteCode, mockRepo.AcceptHouseholdInviteUID)
+	}
+}
+
+func TestNotifyHouseholds(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	authRepo := mockAuthenticationRepository{emails: map[string]string{
+		"alice": "alice@example.com", "bob": "bob@example.com", "carol": "carol@example.com",
+	}}
+
+	smiths, err := repo.CreateHousehold(ctx, "Smiths", "alice")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	// dave has no email address and nothing to be notified about
+	if _, err := repo.CreateHousehold(ctx, "Daves", "dave"); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	for householdID, uid := range map[string]string{smiths.ID: "bob", defaultHouseholdID: "carol"} {
+		if err := repo.AddHouseholdMember(ctx, householdID, uid, householdRoleEditor); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	expired := writeItemParams{
+		Name: "Milk", Tags: []string{}, BoughtAt: time.Now().AddDate(0, 0, -7),
+		ExpiresAt: getPtr(time.Now().AddDate(0, 0, -1)),
+	}
+
+	for _, householdID := range []string{smiths.ID, defaultHouseholdID} {
+		if _, err := repo.CreateItem(withHouseholdID(ctx, householdID), expired); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	n := &mockNotifier{}
+
+	if err := notifyHouseholds(ctx, repo, n, authRepo); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	for _, emails := range n.emails {
+		slices.Sort(emails)
+	}
+
+	slices.SortFunc(n.emails, slices.Compare)
+
+	expected := [][]string{{"alice@example.com", "bob@example.com"}, {"carol@example.com"}}
+	if !reflect.DeepEqual(n.emails, expected) {
+		t.Errorf("Notified %v instead of %v", n.emails, expected)
+	}
+}
+
+func TestNotifyHouseholdsDefaultWithoutMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	authRepo := mockAuthenticationRepository{emails: map[string]string{
+		"alice": "alice@example.com", "bob": "bob@example.com",
+	}}
+
+	expired := writeItemParams{
+		Name: "Milk", Tags: []string{}, BoughtAt: time.Now().AddDate(0, 0, -7),
+		ExpiresAt: getPtr(time.Now().AddDate(0, 0, -1)),
+	}
+
+	if _, err := repo.CreateItem(ctx, expired); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	n := &mockNotifier{}
+
+	if err := notifyHouseholds(ctx, repo, n, authRepo); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	expected := [][]string{{"alice@example.com", "bob@example.com"}}
+	if !reflect.DeepEqual(n.emails, expected) {
+		t.Errorf("Notified %v instead of all the users %v", n.emails, expected)
+	}
+}
+
+func TestNotifyHouseholdsSharedNotifier(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	authRepo := mockAuthenticationRepository{emails: map[string]string{"alice": "alice@example.com"}}
+
+	smiths, err := repo.CreateHousehold(ctx, "Smiths", "alice")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	for name, householdID := range map[string]string{"Milk": defaultHouseholdID, "Caviar": smiths.ID} {
+		params := writeItemParams{
+			Name: name, Tags: []string{}, BoughtAt: time.Now().AddDate(0, 0, -7),
+			ExpiresAt: getPtr(time.Now().AddDate(0, 0, -1)),
+		}
+
+		if _, err := repo.CreateItem(withHouseholdID(ctx, householdID), params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	n := &mockNotifier{shared: true}
+
+	if err := notifyHouseholds(ctx, repo, n, authRepo); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	// the items of the other households do not reach the chat of the operator
+	if n.calls != 1 || len(n.expiries) != 1 || n.expiries[0].item.Name != "Milk" || n.emails[0] != nil {
+		t.Errorf("Expected only the default household to be notified, got %d calls with %+v", n.calls, n.expiries)
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +19,8 @@ import (
 	"github.com/nickelghost/ngtel"
 )
 
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
 const (
 	httpTimeout       = 10 * time.Second
 	httpHeaderTimeout = 1 * time.Second
@@ -33,21 +40,66 @@ func getRouter(
 	auth authentication,
 ) http.Handler {
 	apiMux := http.NewServeMux()
+	inviteURLPrefix := os.Getenv("INVITE_URL_PREFIX")
+
+	// every route declares the permission it requires from the role of the user in their household
+	handle := func(pattern string, p permission, handler http.HandlerFunc) {
+		apiMux.Handle(pattern, permissionMiddleware(handler, p))
+	}
+	// the routes managing the user's account cannot be used with an access token, so that a token cannot outlive
+	// its revocation or expiry by minting others, revoke broader ones or move the user to another household
+	handleWithoutAccessToken := func(pattern string, p permission, handler http.HandlerFunc) {
+		apiMux.Handle(pattern, noAccessTokenMiddleware(permissionMiddleware(handler, p)))
+	}
 
-	apiMux.HandleFunc("GET /locations", indexLocationsHandler(repo))
-	apiMux.HandleFunc("GET /locations/{id}", getLocationHandler(repo))
-	apiMux.HandleFunc("POST /locations", createLocationHandler(repo, validate))
-	apiMux.HandleFunc("PUT /locations/{id}", updateLocationHandler(repo, validate))
-	apiMux.HandleFunc("DELETE /locations/{id}", deleteLocationHandler(repo))
-	apiMux.HandleFunc("POST /items", createItemHandler(repo, validate))
-	apiMux.HandleFunc("PUT /items/{id}", updateItemHandler(repo, validate))
-	apiMux.HandleFunc("PATCH /items/{id}/location", updateItemLocationHandler(repo))
-	apiMux.HandleFunc("DELETE /items/{id}", deleteItemHandler(repo))
+	handle("GET /household", permissionView, getHouseholdHandler(repo))
+	handle("GET /household/members", permissionView, indexHouseholdMembersHandler(repo))
+	handle("PUT /household/members/{uid}", permissionManage, updateHouseholdMemberHandler(repo, validate))
+	handle("DELETE /household/members/{uid}", permissionManage, removeHouseholdMemberHandler(repo))
+	handle("POST /household/invites", permissionManage, createHouseholdInviteHandler(repo, validate, inviteURLPrefix))
+	// accepting an invite is allowed to every role, it leaves the household of the user so it requires signing in
+	handleWithoutAccessToken("POST /household/invites/{code}/accept", permissionView, acceptHouseholdInviteHandler(repo))
+	// the access tokens belong to the user, every role manages their own
+	handle("GET /access-tokens", permissionView, indexAccessTokensHandler(repo))
+	handleWithoutAccessToken("POST /access-tokens", permissionView, createAccessTokenHandler(repo, validate))
+	handleWithoutAccessToken("DELETE /access-tokens/{id}", permissionView, revokeAccessTokenHandler(repo))
+	handle("GET /locations", permissionView, indexLocationsHandler(repo, validate))
+	handle("GET /locations/{id}", permissionView, getLocationHandler(repo, validate))
+	handle("POST /locations", permissionEdit, createLocationHandler(repo, validate))
+	handle("PUT /locations/{id}", permissionEdit, updateLocationHandler(repo, validate))
+	handle("DELETE /locations/{id}", permissionEdit, deleteLocationHandler(repo))
+	handle("GET /items", permissionView, indexItemsHandler(repo, validate))
+	handle("GET /items/{id}", permissionView, getItemHandler(repo))
+	handle("POST /items", permissionEdit, createItemHandler(repo, validate))
+	handle("POST /items:batch", permissionEdit, batchItemsHandler(repo, validate))
+	handle("PUT /items/{id}", permissionEdit, updateItemHandler(repo, validate))
+	handle("PATCH /items/{id}", permissionEdit, patchItemHandler(repo, validate))
+	handle("PATCH /items/{id}/location", permissionEdit, updateItemLocationHandler(repo))
+	handle("POST /items/{id}/open", permissionUse, openItemHandler(repo))
+	handle("DELETE /items/{id}/open", permissionUse, unopenItemHandler(repo))
+	handle("POST /items/{id}/freeze", permissionUse, freezeItemHandler(repo))
+	handle("POST /items/{id}/thaw", permissionUse, thawItemHandler(repo))
+	handle("POST /items/{id}/consume", permissionUse, consumeItemHandler(repo, validate))
+	handle("POST /items/{id}/finish", permissionUse, finishItemHandler(repo, validate))
+	handle("DELETE /items/{id}", permissionEdit, deleteItemHandler(repo))
+	handle("GET /stats", permissionView, getStatsHandler(repo))
+	handle("GET /shopping-list", permissionView, indexShoppingListHandler(repo))
+	handle("GET /shopping-list/{id}", permissionView, getShoppingListItemHandler(repo))
+	handle("POST /shopping-list", permissionUse, createShoppingListItemHandler(repo, validate))
+	handle("PUT /shopping-list/{id}", permissionUse, updateShoppingListItemHandler(repo, validate))
+	handle("POST /shopping-list/{id}/buy", permissionUse, buyShoppingListItemHandler(repo, validate))
+	handle("DELETE /shopping-list/{id}", permissionUse, deleteShoppingListItemHandler(repo))
+	handle("GET /expiry-rules", permissionView, indexExpiryRulesHandler(repo))
+	handle("POST /expiry-rules", permissionEdit, createExpiryRuleHandler(repo, validate))
+	handle("PUT /expiry-rules/{id}", permissionEdit, updateExpiryRuleHandler(repo, validate))
+	handle("DELETE /expiry-rules/{id}", permissionEdit, deleteExpiryRuleHandler(repo))
 	apiMux.HandleFunc("/", nghttp.GetNotFoundHandler(ngtel.GetGCPLogArgs))
 
 	var apiHandler http.Handler = apiMux
 
 	if auth != nil {
+		// the household middleware runs after the authentication, which passes it the user
+		apiHandler = householdMiddleware(apiHandler, repo, strings.Split(os.Getenv("DEFAULT_HOUSEHOLD_UIDS"), ","))
 		apiHandler = authMiddleware(apiHandler, auth)
 	}
 
@@ -71,16 +123,143 @@ func getRouter(
 	return handler
 }
 
-func indexLocationsHandler(repo repository) http.HandlerFunc {
+// getListQueryParam returns the comma separated values of a query param or nil when it is not set.
+func getListQueryParam(r *http.Request, key string) *[]string {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return nil
+	}
+
+	vals := strings.Split(val, ",")
+
+	return &vals
+}
+
+// getItemsFilter reads the item filtering and sorting query params, shared by the items and locations endpoints.
+func getItemsFilter(r *http.Request) (itemsFilter, error) {
+	query := r.URL.Query()
+	filter := itemsFilter{
+		itemsQuery: itemsQuery{
+			Tags:        getListQueryParam(r, "tags"),
+			LocationIDs: getListQueryParam(r, "locationIds"),
+			Sort:        itemsSort(query.Get("sort")),
+		},
+	}
+
+	if val := query.Get("openedOnly"); val != "" {
+		openedOnly, err := strconv.ParseBool(val)
+		if err != nil {
+			return itemsFilter{}, fmt.Errorf("%w: openedOnly: %w", errValidation, err)
+		}
+
+		filter.OpenedOnly = openedOnly
+	}
+
+	if val := query.Get("archived"); val != "" {
+		archived, err := strconv.ParseBool(val)
+		if err != nil {
+			return itemsFilter{}, fmt.Errorf("%w: archived: %w", errValidation, err)
+		}
+
+		filter.Archived = archived
+	}
+
+	if val := query.Get("outcome"); val != "" {
+		filter.Outcome = getPtr(itemOutcome(val))
+	}
+
+	if val := query.Get("expired"); val != "" {
+		expired, err := strconv.ParseBool(val)
+		if err != nil {
+			return itemsFilter{}, fmt.Errorf("%w: expired: %w", errValidation, err)
+		}
+
+		filter.Expired = &expired
+	}
+
+	if val := query.Get("expiresWithin"); val != "" {
+		expiresWithin, err := strconv.Atoi(val)
+		if err != nil {
+			return itemsFilter{}, fmt.Errorf("%w: expiresWithin: %w", errValidation, err)
+		}
+
+		filter.ExpiresWithin = &expiresWithin
+	}
+
+	return filter, nil
+}
+
+// getDateQueryParam parses a YYYY-MM-DD query param as the start of the day in UTC, it returns nil when it is not set.
+func getDateQueryParam(r *http.Request, key string) (*time.Time, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	date, err := time.Parse(time.DateOnly, val)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errValidation, key, err)
+	}
+
+	return &date, nil
+}
+
+// getIfMatchVersion returns the version the If-Match header requires, it returns nil when the header is not set or
+// matches any version. Entity tags that are not versions never match.
+func getIfMatchVersion(r *http.Request) (*int, error) {
+	val := r.Header.Get("If-Match")
+	if val == "" || val == "*" {
+		return nil, nil //nolint:nilnil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(val, `"`))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown entity tag %s", errVersionMismatch, val)
+	}
+
+	return &version, nil
+}
+
+// setETag sets the entity tag of the response to the version of the returned location or item.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+func getPageParams(r *http.Request) (pageParams, error) {
+	page := pageParams{Cursor: r.URL.Query().Get("cursor")}
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil {
+			return pageParams{}, fmt.Errorf("%w: limit: %w", errValidation, err)
+		}
+
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
+func getHouseholdHandler(repo repository) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		var tags *[]string
+		h, err := repo.GetHousehold(r.Context(), getHouseholdID(r.Context()))
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
 
-		if val := r.URL.Query().Get("tags"); val != "" {
-			vals := strings.Split(val, ",")
-			tags = &vals
+			return
 		}
 
-		locs, remItems, err := getLocations(r.Context(), repo, tags)
+		res := struct {
+			Household userHousehold `json:"household"`
+		}{Household: userHousehold{household: h, Role: getHouseholdRole(r.Context())}}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func indexHouseholdMembersHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		members, err := listHouseholdMembers(r.Context(), repo)
 		if err != nil {
 			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
 
@@ -88,56 +267,79 @@ func indexLocationsHandler(repo repository) http.HandlerFunc {
 		}
 
 		res := struct {
-			Locations      []location `json:"locations"`
-			RemainingItems []item     `json:"remainingItems"`
-		}{Locations: locs, RemainingItems: remItems}
+			Members []householdMember `json:"members"`
+		}{Members: members}
 
 		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
 	})
 }
 
-func getLocationHandler(repo repository) http.HandlerFunc {
+func updateHouseholdMemberHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
+		uid := r.PathValue("uid")
 
-		var tags *[]string
+		var body updateHouseholdMemberParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
 
-		if val := r.URL.Query().Get("tags"); val != "" {
-			vals := strings.Split(val, ",")
-			tags = &vals
+			return
 		}
 
-		loc, err := getLocation(r.Context(), repo, id, tags)
-		if errors.Is(err, errLocationNotFound) {
-			nghttp.RespondGeneric(w, r, http.StatusNotFound, err, ngtel.GetGCPLogArgs)
+		if err := updateHouseholdMember(r.Context(), repo, validate, uid, body); err != nil {
+			status := http.StatusInternalServerError
 
-			return
-		} else if err != nil {
-			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errHouseholdMemberNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errHouseholdLastOwner):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
-		res := struct {
-			location `json:"location"`
-		}{location: loc}
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+	})
+}
 
-		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+func removeHouseholdMemberHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if err := removeHouseholdMember(r.Context(), repo, r.PathValue("uid")); err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errHouseholdMemberNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errHouseholdLastOwner):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
 	})
 }
 
-func createLocationHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+// createHouseholdInviteHandler responds with the invite, along with its link when urlPrefix is set.
+func createHouseholdInviteHandler(repo repository, validate *validator.Validate, urlPrefix string) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		body := struct {
-			Name string `json:"name"`
-		}{}
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		// the body is optional, the invite expires after the default number of days without it
+		var body createHouseholdInviteParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
 			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
-		if err := createLocation(r.Context(), repo, validate, body.Name); err != nil {
+		invite, err := createHouseholdInvite(r.Context(), repo, validate, body)
+		if err != nil {
 			status := http.StatusInternalServerError
 			if errors.Is(err, errValidation) {
 				status = http.StatusBadRequest
@@ -148,27 +350,90 @@ func createLocationHandler(repo repository, validate *validator.Validate) http.H
 			return
 		}
 
-		nghttp.RespondGeneric(w, r, http.StatusCreated, nil, ngtel.GetGCPLogArgs)
+		type inviteWithURL struct {
+			householdInvite
+
+			URL string `json:"url,omitempty"`
+		}
+
+		res := struct {
+			Invite inviteWithURL `json:"invite"`
+		}{Invite: inviteWithURL{householdInvite: invite}}
+
+		if urlPrefix != "" {
+			res.Invite.URL = urlPrefix + invite.Code
+		}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
 	})
 }
 
-func updateLocationHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+func acceptHouseholdInviteHandler(repo repository) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
+		uh, err := acceptHouseholdInvite(r.Context(), repo, r.PathValue("code"))
+		if err != nil {
+			status := http.StatusInternalServerError
 
-		body := struct {
-			Name string `json:"name"`
-		}{}
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errHouseholdInviteNotFound), errors.Is(err, errHouseholdNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errHouseholdInviteExpired):
+				status = http.StatusGone
+			case errors.Is(err, errHouseholdLastOwner):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			Household userHousehold `json:"household"`
+		}{Household: uh}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func indexAccessTokensHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := listAccessTokens(r.Context(), repo)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			AccessTokens []accessToken `json:"accessTokens"`
+		}{AccessTokens: tokens}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+// createAccessTokenHandler responds with the access token along with its secret, which cannot be retrieved later.
+func createAccessTokenHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		var body createAccessTokenParams
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
-		if err := updateLocation(r.Context(), repo, validate, id, body.Name); err != nil {
+		token, secret, err := createAccessToken(r.Context(), repo, validate, body)
+		if err != nil {
 			status := http.StatusInternalServerError
-			if errors.Is(err, errValidation) {
+
+			switch {
+			case errors.Is(err, errValidation):
 				status = http.StatusBadRequest
+			case errors.Is(err, errForbidden):
+				status = http.StatusForbidden
 			}
 
 			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
@@ -176,16 +441,29 @@ func updateLocationHandler(repo repository, validate *validator.Validate) http.H
 			return
 		}
 
-		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+		type accessTokenWithSecret struct {
+			accessToken
+
+			Token string `json:"token"`
+		}
+
+		res := struct {
+			AccessToken accessTokenWithSecret `json:"accessToken"`
+		}{AccessToken: accessTokenWithSecret{accessToken: token, Token: secret}}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
 	})
 }
 
-func deleteLocationHandler(repo repository) http.HandlerFunc {
+func revokeAccessTokenHandler(repo repository) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
+		if err := revokeAccessToken(r.Context(), repo, r.PathValue("id")); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errAccessTokenNotFound) {
+				status = http.StatusNotFound
+			}
 
-		if err := deleteLocation(r.Context(), repo, id); err != nil {
-			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
@@ -194,16 +472,24 @@ func deleteLocationHandler(repo repository) http.HandlerFunc {
 	})
 }
 
-func createItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+func indexLocationsHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		var body writeItemParams
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		filter, err := getItemsFilter(r)
+		if err != nil {
 			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
-		if err := createItem(r.Context(), repo, validate, body); err != nil {
+		page, err := getPageParams(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		locs, remItems, nextCursor, err := getLocations(r.Context(), repo, validate, filter, page)
+		if err != nil {
 			status := http.StatusInternalServerError
 			if errors.Is(err, errValidation) {
 				status = http.StatusBadRequest
@@ -214,22 +500,65 @@ func createItemHandler(repo repository, validate *validator.Validate) http.Handl
 			return
 		}
 
-		nghttp.RespondGeneric(w, r, http.StatusCreated, nil, ngtel.GetGCPLogArgs)
+		res := struct {
+			Locations      []location `json:"locations"`
+			RemainingItems []item     `json:"remainingItems"`
+			NextCursor     string     `json:"nextCursor,omitempty"`
+		}{Locations: locs, RemainingItems: remItems, NextCursor: nextCursor}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
 	})
 }
 
-func updateItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+func getLocationHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
 
-		var body writeItemParams
+		filter, err := getItemsFilter(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		loc, err := getLocation(r.Context(), repo, validate, id, filter)
+		if errors.Is(err, errLocationNotFound) {
+			nghttp.RespondGeneric(w, r, http.StatusNotFound, err, ngtel.GetGCPLogArgs)
+
+			return
+		} else if errors.Is(err, errValidation) {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		} else if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, loc.Version)
+
+		res := struct {
+			location `json:"location"`
+		}{location: loc}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func createLocationHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Name string `json:"name"`
+		}{}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
-		if err := updateItem(r.Context(), repo, validate, id, body); err != nil {
+		loc, err := createLocation(r.Context(), repo, validate, body.Name)
+		if err != nil {
 			status := http.StatusInternalServerError
 			if errors.Is(err, errValidation) {
 				status = http.StatusBadRequest
@@ -240,16 +569,23 @@ func updateItemHandler(repo repository, validate *validator.Validate) http.Handl
 			return
 		}
 
-		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+		w.Header().Set("Location", "/locations/"+loc.ID)
+		setETag(w, loc.Version)
+
+		res := struct {
+			location `json:"location"`
+		}{location: loc}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
 	})
 }
 
-func updateItemLocationHandler(repo repository) http.HandlerFunc {
+func updateLocationHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
 
 		body := struct {
-			LocationID *string `json:"locationId"`
+			Name string `json:"name"`
 		}{}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
@@ -257,22 +593,843 @@ func updateItemLocationHandler(repo repository) http.HandlerFunc {
 			return
 		}
 
-		if err := updateItemLocation(r.Context(), repo, id, body.LocationID); err != nil {
-			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusPreconditionFailed, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
 
-		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+		loc, err := updateLocation(r.Context(), repo, validate, id, body.Name, version)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errLocationNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errVersionMismatch):
+				status = http.StatusPreconditionFailed
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, loc.Version)
+
+		res := struct {
+			location `json:"location"`
+		}{location: loc}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
 	})
 }
 
-func deleteItemHandler(repo repository) http.HandlerFunc {
+func deleteLocationHandler(repo repository) http.HandlerFunc {
 	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
 
-		if err := deleteItem(r.Context(), repo, id); err != nil {
-			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusPreconditionFailed, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		if err := deleteLocation(r.Context(), repo, id, version); err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errLocationNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errVersionMismatch):
+				status = http.StatusPreconditionFailed
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+	})
+}
+
+func indexItemsHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		filter, err := getItemsFilter(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		page, err := getPageParams(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		items, nextCursor, err := getItems(r.Context(), repo, validate, filter, page)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errValidation) {
+				status = http.StatusBadRequest
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			Items      []item `json:"items"`
+			NextCursor string `json:"nextCursor,omitempty"`
+		}{Items: items, NextCursor: nextCursor}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func getItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		i, err := getItem(r.Context(), repo, id)
+		if errors.Is(err, errItemNotFound) {
+			nghttp.RespondGeneric(w, r, http.StatusNotFound, err, ngtel.GetGCPLogArgs)
+
+			return
+		} else if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func createItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		var body writeItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := createItem(r.Context(), repo, validate, body)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errValidation) {
+				status = http.StatusBadRequest
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		w.Header().Set("Location", "/items/"+i.ID)
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func updateItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body writeItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusPreconditionFailed, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := updateItem(r.Context(), repo, validate, id, body, version)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errItemNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errVersionMismatch):
+				status = http.StatusPreconditionFailed
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func batchItemsHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Operations []itemBatchOperation `json:"operations"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		results, err := batchItems(r.Context(), repo, validate, body.Operations)
+
+		status := http.StatusOK
+
+		switch {
+		case err == nil:
+		case errors.Is(err, errValidation):
+			status = http.StatusBadRequest
+		case errors.Is(err, errItemNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errVersionMismatch):
+			status = http.StatusPreconditionFailed
+		default:
+			status = http.StatusInternalServerError
+		}
+
+		// the results of a failed batch tell which operation failed, except for internal errors
+		if results == nil || status == http.StatusInternalServerError {
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			Results []itemBatchResult `json:"results"`
+		}{Results: results}
+
+		nghttp.Respond(w, r, status, err, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func patchItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != mergePatchContentType {
+			err := fmt.Errorf("%w: expected %s content", errUnsupportedMediaType, mergePatchContentType)
+			nghttp.RespondGeneric(w, r, http.StatusUnsupportedMediaType, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusPreconditionFailed, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := patchItem(r.Context(), repo, validate, id, patch, version)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errItemNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errVersionMismatch):
+				status = http.StatusPreconditionFailed
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func updateItemLocationHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		body := struct {
+			LocationID *string `json:"locationId"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusPreconditionFailed, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := updateItemLocation(r.Context(), repo, id, body.LocationID, version)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errItemNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errVersionMismatch):
+				status = http.StatusPreconditionFailed
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func openItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		// the body is optional, so that opening an item now takes a single tap
+		var body openItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := openItem(r.Context(), repo, id, body)
+		respondItemStateChange(w, r, i, err)
+	})
+}
+
+func unopenItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		i, err := unopenItem(r.Context(), repo, r.PathValue("id"))
+		respondItemStateChange(w, r, i, err)
+	})
+}
+
+func freezeItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body freezeItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := freezeItem(r.Context(), repo, id, body)
+		respondItemStateChange(w, r, i, err)
+	})
+}
+
+func thawItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body thawItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := thawItem(r.Context(), repo, id, body)
+		respondItemStateChange(w, r, i, err)
+	})
+}
+
+// respondItemStateChange responds with the item returned by opening, freezing or thawing it, or with the error.
+func respondItemStateChange(w http.ResponseWriter, r *http.Request, i item, err error) {
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, errValidation):
+			status = http.StatusBadRequest
+		case errors.Is(err, errItemNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errItemArchived), errors.Is(err, errItemOpened), errors.Is(err, errItemNotOpened),
+			errors.Is(err, errItemFrozen), errors.Is(err, errItemNotFrozen), errors.Is(err, errVersionMismatch):
+			status = http.StatusConflict
+		}
+
+		nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+		return
+	}
+
+	setETag(w, i.Version)
+
+	res := struct {
+		item `json:"item"`
+	}{item: i}
+
+	nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+}
+
+func consumeItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body consumeItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := consumeItem(r.Context(), repo, validate, id, body)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errForbidden):
+				status = http.StatusForbidden
+			case errors.Is(err, errItemNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errItemNoQuantity), errors.Is(err, errItemArchived),
+				errors.Is(err, errVersionMismatch):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func finishItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body finishItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := finishItem(r.Context(), repo, validate, id, body)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errItemNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errItemArchived), errors.Is(err, errVersionMismatch):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func deleteItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		version, err := getIfMatchVersion(r)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusPreconditionFailed, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		if err := deleteItem(r.Context(), repo, id, version); err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errItemNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errVersionMismatch):
+				status = http.StatusPreconditionFailed
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+	})
+}
+
+func getStatsHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		from, err := getDateQueryParam(r, "from")
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		to, err := getDateQueryParam(r, "to")
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		// by default the range covers the current month and the 11 before it, to is inclusive
+		today := time.Now().UTC().Truncate(24 * time.Hour) //nolint:mnd
+		to = cmp.Or(to, &today)
+		from = cmp.Or(from, getPtr(time.Date(today.Year(), today.Month()-11, 1, 0, 0, 0, 0, time.UTC))) //nolint:mnd
+
+		s, err := getStats(r.Context(), repo, *from, to.AddDate(0, 0, 1))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errValidation) {
+				status = http.StatusBadRequest
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			Stats stats `json:"stats"`
+		}{Stats: s}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func indexShoppingListHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		entries, err := getShoppingList(r.Context(), repo)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			ShoppingList []shoppingListItem `json:"shoppingList"`
+		}{ShoppingList: entries}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func getShoppingListItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		entry, err := getShoppingListItem(r.Context(), repo, id)
+		if errors.Is(err, errShoppingListItemNotFound) {
+			nghttp.RespondGeneric(w, r, http.StatusNotFound, err, ngtel.GetGCPLogArgs)
+
+			return
+		} else if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			shoppingListItem `json:"shoppingListItem"`
+		}{shoppingListItem: entry}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func createShoppingListItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		var body writeShoppingListItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		entry, err := createShoppingListItem(r.Context(), repo, validate, body)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errValidation) {
+				status = http.StatusBadRequest
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		w.Header().Set("Location", "/shopping-list/"+entry.ID)
+
+		res := struct {
+			shoppingListItem `json:"shoppingListItem"`
+		}{shoppingListItem: entry}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func updateShoppingListItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body writeShoppingListItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		if err := updateShoppingListItem(r.Context(), repo, validate, id, body); err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errShoppingListItemNotFound):
+				status = http.StatusNotFound
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+	})
+}
+
+func buyShoppingListItemHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body buyShoppingListItemParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		i, err := buyShoppingListItem(r.Context(), repo, validate, id, body)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errShoppingListItemNotFound):
+				status = http.StatusNotFound
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		w.Header().Set("Location", "/items/"+i.ID)
+		setETag(w, i.Version)
+
+		res := struct {
+			item `json:"item"`
+		}{item: i}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func deleteShoppingListItemHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if err := deleteShoppingListItem(r.Context(), repo, id); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errShoppingListItemNotFound) {
+				status = http.StatusNotFound
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+	})
+}
+
+func indexExpiryRulesHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		rules, err := listExpiryRules(r.Context(), repo)
+		if err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusInternalServerError, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		res := struct {
+			ExpiryRules []expiryRule `json:"expiryRules"`
+		}{ExpiryRules: rules}
+
+		nghttp.Respond(w, r, http.StatusOK, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func createExpiryRuleHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		var body writeExpiryRuleParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		rule, err := createExpiryRule(r.Context(), repo, validate, body)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errExpiryRuleExists):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		w.Header().Set("Location", "/expiry-rules/"+rule.ID)
+
+		res := struct {
+			expiryRule `json:"expiryRule"`
+		}{expiryRule: rule}
+
+		nghttp.Respond(w, r, http.StatusCreated, nil, res, ngtel.GetGCPLogArgs)
+	})
+}
+
+func updateExpiryRuleHandler(repo repository, validate *validator.Validate) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var body writeExpiryRuleParams
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			nghttp.RespondGeneric(w, r, http.StatusBadRequest, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		if err := updateExpiryRule(r.Context(), repo, validate, id, body); err != nil {
+			status := http.StatusInternalServerError
+
+			switch {
+			case errors.Is(err, errValidation):
+				status = http.StatusBadRequest
+			case errors.Is(err, errExpiryRuleNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, errExpiryRuleExists):
+				status = http.StatusConflict
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
+
+			return
+		}
+
+		nghttp.RespondGeneric(w, r, http.StatusOK, nil, ngtel.GetGCPLogArgs)
+	})
+}
+
+func deleteExpiryRuleHandler(repo repository) http.HandlerFunc {
+	return ngtel.SetSpanNameMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if err := deleteExpiryRule(r.Context(), repo, id); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errExpiryRuleNotFound) {
+				status = http.StatusNotFound
+			}
+
+			nghttp.RespondGeneric(w, r, status, err, ngtel.GetGCPLogArgs)
 
 			return
 		}
@@ -20,22 +20,26 @@ type infobipNotifier struct {
 	from    string
 }
 
+func (n infobipNotifier) sendsToRecipients() bool {
+	return true
+}
+
 func (n infobipNotifier) NotifyAboutItems(
 	ctx context.Context,
 	expiries []itemExpiry,
 	comingExpiries []itemExpiry,
-	authRepo authenticationRepository,
+	frozenExpiries []itemExpiry,
+	emails []string,
 ) error {
-	emails, err := authRepo.GetAllEmails(ctx)
-	if err != nil {
-		return fmt.Errorf("get all emails: %w", err)
+	if len(emails) == 0 {
+		return errNoEmailAddressesFound
 	}
 
 	infobipURL := n.getURL()
 	infobipURL.Path = "/email/3/send"
 
 	payload, contentType, err := n.getEmailPayload(
-		getNotificationTitle(), notificationExpiriesToText(expiries, comingExpiries), emails,
+		getNotificationTitle(), notificationExpiriesToText(expiries, comingExpiries, frozenExpiries), emails,
 	)
 	if err != nil {
 		return err
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
+	"slices"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -22,8 +26,62 @@ type item struct {
 	Lifespan   *int       `json:"lifespan"`
 	LocationID *string    `json:"locationId"`
 	Location   *location  `json:"location,omitempty"`
+	// Quantity is how much of InitialQuantity is left, both measured in Unit
+	Quantity        *float64  `json:"quantity"`
+	InitialQuantity *float64  `json:"initialQuantity"`
+	Unit            *itemUnit `json:"unit"`
+	// ArchivedAt is when the item was finished with the Outcome
+	ArchivedAt *time.Time   `json:"archivedAt"`
+	Outcome    *itemOutcome `json:"outcome"`
+	// FrozenAt is when the item was last put in the freezer and ThawedAt when it was taken out after that
+	FrozenAt *time.Time `json:"frozenAt"`
+	ThawedAt *time.Time `json:"thawedAt"`
+	// Version is incremented by every write of the item
+	Version int `json:"version"`
+	// computed with withExpiry when the item is returned by the API, which also fills in ExpiresAt and Lifespan
+	// from the expiry rules
+	DaysLeft           *int       `firestore:"-" json:"daysLeft"`
+	EffectiveExpiresAt *time.Time `firestore:"-" json:"effectiveExpiresAt"`
+	Status             itemStatus `firestore:"-" json:"status"`
 }
 
+type itemStatus string
+
+const (
+	itemStatusExpired      itemStatus = "expired"
+	itemStatusExpiringSoon itemStatus = "expiring_soon"
+	itemStatusOK           itemStatus = "ok"
+	itemStatusUnknown      itemStatus = "unknown"
+)
+
+type itemUnit string
+
+const (
+	itemUnitCount      itemUnit = "count"
+	itemUnitGram       itemUnit = "g"
+	itemUnitKilogram   itemUnit = "kg"
+	itemUnitMilliliter itemUnit = "ml"
+	itemUnitLiter      itemUnit = "l"
+)
+
+// itemOutcome is what happened to an item that was finished.
+type itemOutcome string
+
+const (
+	itemOutcomeConsumed  itemOutcome = "consumed"
+	itemOutcomeWasted    itemOutcome = "wasted"
+	itemOutcomeGivenAway itemOutcome = "given_away"
+)
+
+// itemEmptyAction is what happens to an item once all of it is consumed.
+type itemEmptyAction string
+
+const (
+	itemEmptyKeep    itemEmptyAction = "keep"
+	itemEmptyDelete  itemEmptyAction = "delete"
+	itemEmptyArchive itemEmptyAction = "archive"
+)
+
 type itemExpiry struct {
 	item     item
 	daysLeft int
@@ -31,6 +89,66 @@ type itemExpiry struct {
 
 const expiresSoonThreshold = 2
 
+var (
+	errItemNotFound   = errors.New("item not found")
+	errItemNoQuantity = errors.New("item has no quantity")
+	errItemArchived   = errors.New("item is already archived")
+	errItemOpened     = errors.New("item is already opened")
+	errItemNotOpened  = errors.New("item is not opened")
+	errItemFrozen     = errors.New("item is already frozen")
+	errItemNotFrozen  = errors.New("item is not frozen")
+)
+
+type itemsSort string
+
+const (
+	itemsSortID       itemsSort = ""
+	itemsSortName     itemsSort = "name"
+	itemsSortBoughtAt itemsSort = "boughtAt"
+	itemsSortPrice    itemsSort = "price"
+	// itemsSortDaysLeft depends on the computed expiry, repositories return the items sorted by ID instead
+	itemsSortDaysLeft itemsSort = "daysLeft"
+)
+
+// stored returns the sort repositories order by, which is the ID for the sorts by computed fields.
+func (s itemsSort) stored() itemsSort {
+	if s == itemsSortDaysLeft {
+		return itemsSortID
+	}
+
+	return s
+}
+
+// itemsQuery selects the items returned by repository.GetItems, nil filters match every item.
+type itemsQuery struct {
+	// Tags matches the items having any of the tags
+	Tags        *[]string
+	LocationIDs *[]string
+	// Unassigned matches only the items without a location
+	Unassigned bool
+	OpenedOnly bool
+	// Archived selects the archived items instead of the active ones
+	Archived bool
+	Outcome  *itemOutcome `validate:"omitempty,oneof=consumed wasted given_away"`
+	// BoughtFrom and BoughtBefore match the items bought in the time range
+	BoughtFrom   *time.Time
+	BoughtBefore *time.Time
+	Sort         itemsSort `validate:"omitempty,oneof=name boughtAt price daysLeft"`
+	// Limit caps the number of returned items, zero means no limit
+	Limit int
+	// After skips the items up to and including the cursor in the order of Sort
+	After *itemsCursor
+}
+
+// itemsFilter extends itemsQuery with the filters on the computed expiry, which are applied after querying.
+type itemsFilter struct {
+	itemsQuery
+
+	// ExpiresWithin keeps the items with at most the given number of days left, including the expired ones
+	ExpiresWithin *int `validate:"omitempty,gte=0"`
+	Expired       *bool
+}
+
 type writeItemParams struct {
 	Name       string     `json:"name"       validate:"required,min=2"`
 	Type       *string    `json:"type"`
@@ -41,50 +159,167 @@ type writeItemParams struct {
 	ExpiresAt  *time.Time `json:"expiresAt"`
 	Lifespan   *int       `json:"lifespan"   validate:"omitempty,gte=0"`
 	LocationID *string    `json:"locationId"`
+	// InitialQuantity defaults to Quantity, which cannot exceed it
+	Quantity        *float64     `json:"quantity"        validate:"required_with=InitialQuantity Unit,omitempty,gte=0"`
+	InitialQuantity *float64     `json:"initialQuantity" validate:"omitempty,gt=0,gtefield=Quantity"`
+	Unit            *itemUnit    `json:"unit"            validate:"required_with=Quantity,omitempty,oneof=count g kg ml l"`
+	ArchivedAt      *time.Time   `json:"archivedAt"`
+	Outcome         *itemOutcome `json:"outcome"         validate:"omitempty,oneof=consumed wasted given_away"`
+	FrozenAt        *time.Time   `json:"frozenAt"`
+	ThawedAt        *time.Time   `json:"thawedAt"        validate:"excluded_without=FrozenAt,omitempty,gtefield=FrozenAt"`
+}
+
+type finishItemParams struct {
+	Outcome itemOutcome `json:"outcome" validate:"required,oneof=consumed wasted given_away"`
+	// FinishedAt defaults to the current time
+	FinishedAt        *time.Time `json:"finishedAt"`
+	AddToShoppingList bool       `json:"addToShoppingList"`
 }
 
-func getItemDaysLeft(item item) *int {
-	daysOpts := []int{}
+type openItemParams struct {
+	// OpenedAt defaults to the current time
+	OpenedAt *time.Time `json:"openedAt"`
+}
+
+type freezeItemParams struct {
+	// FrozenAt defaults to the current time
+	FrozenAt *time.Time `json:"frozenAt"`
+}
 
-	// if has an expiry date, add number of remaining days to opts
-	if item.ExpiresAt != nil {
-		daysOpt := int(math.Ceil(time.Until(*item.ExpiresAt).Hours() / 24)) //nolint:mnd
-		daysOpts = append(daysOpts, daysOpt)
+type thawItemParams struct {
+	// ThawedAt defaults to the current time
+	ThawedAt *time.Time `json:"thawedAt"`
+}
+
+type consumeItemParams struct {
+	Amount float64 `json:"amount" validate:"gt=0"`
+	// WhenEmpty defaults to keeping the item with zero quantity
+	WhenEmpty itemEmptyAction `json:"whenEmpty" validate:"omitempty,oneof=keep delete archive"`
+	// AddToShoppingList adds the item to the shopping list once nothing is left
+	AddToShoppingList bool `json:"addToShoppingList"`
+}
+
+// withDefaultQuantity returns the params with InitialQuantity defaulting to Quantity.
+func (params writeItemParams) withDefaultQuantity() writeItemParams {
+	if params.Quantity != nil && params.InitialQuantity == nil {
+		params.InitialQuantity = getPtr(*params.Quantity)
+	}
+
+	return params
+}
+
+// itemToParams returns the params that store the item as it is.
+func itemToParams(i item) writeItemParams {
+	return writeItemParams{
+		Name:            i.Name,
+		Type:            i.Type,
+		Tags:            i.Tags,
+		Price:           i.Price,
+		BoughtAt:        i.BoughtAt,
+		OpenedAt:        i.OpenedAt,
+		ExpiresAt:       i.ExpiresAt,
+		Lifespan:        i.Lifespan,
+		LocationID:      i.LocationID,
+		Quantity:        i.Quantity,
+		InitialQuantity: i.InitialQuantity,
+		Unit:            i.Unit,
+		ArchivedAt:      i.ArchivedAt,
+		Outcome:         i.Outcome,
+		FrozenAt:        i.FrozenAt,
+		ThawedAt:        i.ThawedAt,
+	}
+}
+
+// getItemEffectiveExpiresAt returns when the item expires, the rules fill in the durations the item does not have.
+// A frozen item expires at the end of its frozen shelf life, a thawed one at the end of its lifespan after thawing
+// or opening. Otherwise it is the earliest of the item's expiry date and the end of its lifespan after opening.
+func getItemEffectiveExpiresAt(item item, rules expiryRules) *time.Time {
+	defaults := rules.defaults(item.Type, item.Tags)
+	item = item.withDefaultExpiry(rules)
+	expiryOpts := []time.Time{}
+
+	switch {
+	case isItemFrozen(item):
+		return getPtr(addDays(*item.FrozenAt, *defaults.FrozenShelfLife))
+	case item.ThawedAt != nil:
+		expiryOpts = append(expiryOpts, addDays(*item.ThawedAt, *defaults.ThawedLifespan))
+	case item.ExpiresAt != nil:
+		// if has an expiry date, add it to opts
+		expiryOpts = append(expiryOpts, *item.ExpiresAt)
 	}
 
-	// if was opened and has lifespan, set the remaining lifetime days to opts
-	if item.OpenedAt != nil && item.Lifespan != nil {
-		lifespanHours := time.Duration(*item.Lifespan) * 24 * time.Hour                      //nolint:mnd
-		daysOpt := int(math.Ceil(time.Until(item.OpenedAt.Add(lifespanHours)).Hours() / 24)) //nolint:mnd
-		daysOpts = append(daysOpts, daysOpt)
+	// if was opened and has lifespan, add the end of its lifetime to opts, opening before freezing is covered by
+	// the thawed lifespan
+	if item.OpenedAt != nil && item.Lifespan != nil && (item.ThawedAt == nil || !item.OpenedAt.Before(*item.ThawedAt)) {
+		expiryOpts = append(expiryOpts, addDays(*item.OpenedAt, *item.Lifespan))
 	}
 
-	if len(daysOpts) == 0 {
+	if len(expiryOpts) == 0 {
 		return nil
 	}
 
-	var daysLeft int
+	// find the earliest possible expiry and return it
+	return getPtr(slices.MinFunc(expiryOpts, func(a, b time.Time) int { return a.Compare(b) }))
+}
 
-	// find the smallest possible expiry and set it
-	for i, do := range daysOpts {
-		if i == 0 || do < daysLeft {
-			daysLeft = do
-		}
+// isItemFrozen returns whether the item is in the freezer.
+func isItemFrozen(i item) bool {
+	return i.FrozenAt != nil && i.ThawedAt == nil
+}
+
+func getItemDaysLeft(item item, rules expiryRules) *int {
+	return getDaysLeft(getItemEffectiveExpiresAt(item, rules))
+}
+
+// getDaysLeft returns the days left until the effective expiry of an item, nil if it is not known.
+func getDaysLeft(expiresAt *time.Time) *int {
+	if expiresAt == nil {
+		return nil
+	}
+
+	return getPtr(int(math.Ceil(time.Until(*expiresAt).Hours() / 24))) //nolint:mnd
+}
+
+func getItemStatus(daysLeft *int) itemStatus {
+	switch {
+	case daysLeft == nil:
+		return itemStatusUnknown
+	case *daysLeft < 0:
+		return itemStatusExpired
+	case *daysLeft <= expiresSoonThreshold:
+		return itemStatusExpiringSoon
+	default:
+		return itemStatusOK
 	}
+}
 
-	return &daysLeft
+// withExpiry returns the item with its computed expiry fields set and its expiry filled from the rules, the days
+// left and the status are derived from the effective expiry computed once.
+func withExpiry(i item, rules expiryRules) item {
+	i = i.withDefaultExpiry(rules)
+	i.EffectiveExpiresAt = getItemEffectiveExpiresAt(i, rules)
+	i.DaysLeft = getDaysLeft(i.EffectiveExpiresAt)
+	i.Status = getItemStatus(i.DaysLeft)
+
+	return i
 }
 
-func notifyAboutItems(ctx context.Context, repo repository, n notifier, authRepo authenticationRepository) error {
-	items, err := repo.GetItems(ctx, nil, nil)
+// notifyAboutItems sends the notification about the expiring items of the household of the context to the emails.
+func notifyAboutItems(ctx context.Context, repo repository, n notifier, emails []string) error {
+	items, err := repo.GetItems(ctx, itemsQuery{})
 	if err != nil {
 		return fmt.Errorf("get items: %w", err)
 	}
 
-	expiries, comingExpiries := []itemExpiry{}, []itemExpiry{}
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	expiries, comingExpiries, frozenExpiries := []itemExpiry{}, []itemExpiry{}, []itemExpiry{}
 
 	for _, item := range items {
-		daysLeft := getItemDaysLeft(item)
+		daysLeft := getItemDaysLeft(item, rules)
 
 		if daysLeft == nil {
 			continue
@@ -93,66 +328,525 @@ func notifyAboutItems(ctx context.Context, repo repository, n notifier, authRepo
 		expiry := itemExpiry{item, *daysLeft}
 
 		// we only want to notify about items that are expired or are soon to be expired
-		if *daysLeft < 0 {
+		switch status := getItemStatus(daysLeft); {
+		case isItemFrozen(item) && (status == itemStatusExpired || status == itemStatusExpiringSoon):
+			frozenExpiries = append(frozenExpiries, expiry)
+		case isItemFrozen(item):
+		case status == itemStatusExpired:
 			expiries = append(expiries, expiry)
-		} else if *daysLeft <= expiresSoonThreshold {
+		case status == itemStatusExpiringSoon:
 			comingExpiries = append(comingExpiries, expiry)
 		}
 	}
 
-	if len(expiries) == 0 && len(comingExpiries) == 0 {
+	if len(expiries) == 0 && len(comingExpiries) == 0 && len(frozenExpiries) == 0 {
 		slog.Info("No items expired nor expired soon, skipping the notification.")
 
 		return nil
 	}
 
-	if err := n.NotifyAboutItems(ctx, expiries, comingExpiries, authRepo); err != nil {
+	if err := n.NotifyAboutItems(ctx, expiries, comingExpiries, frozenExpiries, emails); err != nil {
 		return fmt.Errorf("notify about items: %w", err)
 	}
 
 	return nil
 }
 
-func createItem(ctx context.Context, repo repository, validate *validator.Validate, params writeItemParams) error {
+// fillItemsExpiry sets the computed expiry fields on each of the items.
+func fillItemsExpiry(items []item, rules expiryRules) {
+	for i := range items {
+		items[i] = withExpiry(items[i], rules)
+	}
+}
+
+// compareItems orders the items by the sort and then by ID, the way repositories return them.
+func compareItems(a, b item, sort itemsSort) int {
+	byField := 0
+
+	switch sort {
+	case itemsSortName:
+		byField = cmp.Compare(a.Name, b.Name)
+	case itemsSortBoughtAt:
+		byField = a.BoughtAt.Compare(b.BoughtAt)
+	case itemsSortPrice:
+		// items without a price come first
+		byField = cmp.Compare(*cmp.Or(a.Price, getPtr(-1)), *cmp.Or(b.Price, getPtr(-1)))
+	case itemsSortDaysLeft:
+		// comparing the expiry dates keeps the order stable as days pass, items without a known expiry come last
+		switch {
+		case a.EffectiveExpiresAt == nil && b.EffectiveExpiresAt == nil:
+		case a.EffectiveExpiresAt == nil:
+			byField = 1
+		case b.EffectiveExpiresAt == nil:
+			byField = -1
+		default:
+			byField = a.EffectiveExpiresAt.Compare(*b.EffectiveExpiresAt)
+		}
+	case itemsSortID:
+	}
+
+	return cmp.Or(byField, cmp.Compare(a.ID, b.ID))
+}
+
+// applyItemsFilter keeps the items matching the expiry filters and sorts them by days left if requested,
+// the items need to have their expiry filled.
+func applyItemsFilter(items []item, filter itemsFilter) []item {
+	items = slices.DeleteFunc(items, func(i item) bool {
+		if filter.ExpiresWithin != nil && (i.DaysLeft == nil || *i.DaysLeft > *filter.ExpiresWithin) {
+			return true
+		}
+
+		return filter.Expired != nil && *filter.Expired != (i.Status == itemStatusExpired)
+	})
+
+	if filter.Sort == itemsSortDaysLeft {
+		slices.SortFunc(items, func(a, b item) int { return compareItems(a, b, itemsSortDaysLeft) })
+	}
+
+	return items
+}
+
+// queryItemsPages returns more than limit matching items after the cursor if there are enough of them, querying
+// the repository page by page as the expiry filters can only be applied to the queried items.
+func queryItemsPages(
+	ctx context.Context, repo repository, filter itemsFilter, rules expiryRules, after *itemsCursor, limit int,
+) ([]item, error) {
+	query := filter.itemsQuery
+	query.After = after
+
+	if limit > 0 {
+		query.Limit = limit + 1
+	}
+
+	items := []item{}
+
+	for {
+		page, err := repo.GetItems(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("get items: %w", err)
+		}
+
+		fillItemsExpiry(page, rules)
+
+		if len(page) > 0 {
+			query.After = getPtr(newItemsCursor(page[len(page)-1], query.Sort))
+		}
+
+		items = append(items, applyItemsFilter(page, filter)...)
+
+		if query.Limit == 0 || len(page) < query.Limit || len(items) > limit {
+			return items, nil
+		}
+	}
+}
+
+func getItems(
+	ctx context.Context, repo repository, validate *validator.Validate, filter itemsFilter, page pageParams,
+) ([]item, string, error) {
+	if err := validate.Struct(filter); err != nil {
+		return nil, "", fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	if err := validate.Struct(page); err != nil {
+		return nil, "", fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	after, err := decodeCursor[itemsCursor](page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if after != nil && after.Sort != filter.Sort {
+		return nil, "", fmt.Errorf("%w: the cursor was returned for a different sort", errValidation)
+	}
+
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []item
+
+	if filter.Sort == itemsSortDaysLeft {
+		// days left are computed, so all the items are sorted and paginated here
+		items, err = queryItemsPages(ctx, repo, filter, rules, nil, 0)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if after != nil {
+			items = slices.DeleteFunc(items, func(i item) bool {
+				return compareItems(i, after.item(), itemsSortDaysLeft) <= 0
+			})
+		}
+	} else {
+		items, err = queryItemsPages(ctx, repo, filter, rules, after, page.Limit)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if page.Limit == 0 || len(items) <= page.Limit {
+		return items, "", nil
+	}
+
+	items = items[:page.Limit]
+
+	cursor, err := encodeCursor(newItemsCursor(items[len(items)-1], filter.Sort))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, cursor, nil
+}
+
+func getItem(ctx context.Context, repo repository, id string) (item, error) {
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return item{}, err
+	}
+
+	return withExpiry(i, rules), nil
+}
+
+func createItem(
+	ctx context.Context, repo repository, validate *validator.Validate, params writeItemParams,
+) (item, error) {
+	return storeNewItem(ctx, repo, validate, params, func(ctx context.Context, params writeItemParams) (item, error) {
+		i, err := repo.CreateItem(ctx, params)
+		if err != nil {
+			return item{}, fmt.Errorf("create item: %w", err)
+		}
+
+		return i, nil
+	})
+}
+
+// storeNewItem validates the params of a new item and fills in their defaults before passing them to store, which
+// writes the item and returns it as stored.
+func storeNewItem(
+	ctx context.Context,
+	repo repository,
+	validate *validator.Validate,
+	params writeItemParams,
+	store func(ctx context.Context, params writeItemParams) (item, error),
+) (item, error) {
+	params = params.withDefaultQuantity()
+
 	if err := validate.Struct(params); err != nil {
-		return fmt.Errorf("%w: %w", errValidation, err)
+		return item{}, fmt.Errorf("%w: %w", errValidation, err)
 	}
 
-	if err := repo.CreateItem(ctx, params); err != nil {
-		return fmt.Errorf("create item: %w", err)
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return item{}, err
 	}
 
-	return nil
+	i, err := store(ctx, params)
+	if err != nil {
+		return item{}, err
+	}
+
+	return withExpiry(i, rules), nil
 }
 
+// updateItem replaces the stored fields of the item, returning it as stored with its new version.
 func updateItem(
 	ctx context.Context,
 	repo repository,
 	validate *validator.Validate,
 	id string,
 	params writeItemParams,
-) error {
+	version *int,
+) (item, error) {
+	params = params.withDefaultQuantity()
+
 	if err := validate.Struct(params); err != nil {
-		return fmt.Errorf("%w: %w", errValidation, err)
+		return item{}, fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	if err := repo.UpdateItem(ctx, id, params, version); err != nil {
+		return item{}, fmt.Errorf("update item: %w", err)
+	}
+
+	return getItem(ctx, repo, id)
+}
+
+// patchItem applies the JSON Merge Patch to the stored fields of the item, so that only the fields in the patch
+// change. The result is validated like a full update and the patched item is returned. The item is only updated if
+// it did not change since it was read, and if it has the version when one is given.
+func patchItem(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, patch []byte, version *int,
+) (item, error) {
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	if version != nil && *version != i.Version {
+		return item{}, fmt.Errorf("update item: %w", errVersionMismatch)
+	}
+
+	doc, err := json.Marshal(itemToParams(i))
+	if err != nil {
+		return item{}, fmt.Errorf("marshal item: %w", err)
+	}
+
+	patched, err := applyMergePatch(doc, patch)
+	if err != nil {
+		return item{}, err
+	}
+
+	var params writeItemParams
+	if err := json.Unmarshal(patched, &params); err != nil {
+		return item{}, fmt.Errorf("%w: %w", errValidation, err)
 	}
 
-	if err := repo.UpdateItem(ctx, id, params); err != nil {
+	return updateItem(ctx, repo, validate, id, params, &i.Version)
+}
+
+// consumeItem takes the amount from the quantity of the item, once nothing is left the item is kept, deleted or
+// archived. The item is returned as it is after consuming.
+func consumeItem(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, params consumeItemParams,
+) (item, error) {
+	if err := validate.Struct(params); err != nil {
+		return item{}, fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	// consuming only requires the use permission, deleting the item requires the edit one like DELETE /items/{id}
+	if params.WhenEmpty == itemEmptyDelete {
+		if err := checkRequestPermission(ctx, permissionEdit); err != nil {
+			return item{}, err
+		}
+	}
+
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	if i.ArchivedAt != nil {
+		return item{}, errItemArchived
+	}
+
+	if i.Quantity == nil {
+		return item{}, errItemNoQuantity
+	}
+
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return item{}, err
+	}
+
+	// the item runs out only once, consuming an empty item does not add it to the shopping list again
+	runOut := *i.Quantity > 0 && params.Amount >= *i.Quantity
+	i.Quantity = getPtr(max(*i.Quantity-params.Amount, 0))
+
+	if err := writeConsumedItem(ctx, repo, id, &i, params.WhenEmpty); err != nil {
+		return item{}, err
+	}
+
+	if runOut && params.AddToShoppingList {
+		if err := addItemToShoppingList(ctx, repo, i); err != nil {
+			return item{}, err
+		}
+	}
+
+	return withExpiry(i, rules), nil
+}
+
+// writeConsumedItem stores the item with its consumed quantity, once nothing is left the item is kept, deleted or
+// archived.
+func writeConsumedItem(ctx context.Context, repo repository, id string, i *item, whenEmpty itemEmptyAction) error {
+	if *i.Quantity == 0 {
+		switch whenEmpty {
+		case itemEmptyDelete:
+			if err := repo.DeleteItem(ctx, id, &i.Version); err != nil {
+				return fmt.Errorf("delete item: %w", err)
+			}
+
+			return nil
+		case itemEmptyArchive:
+			i.ArchivedAt = getPtr(time.Now())
+			i.Outcome = getPtr(itemOutcomeConsumed)
+		case itemEmptyKeep, "":
+		}
+	}
+
+	if err := repo.UpdateItem(ctx, id, itemToParams(*i), &i.Version); err != nil {
 		return fmt.Errorf("update item: %w", err)
 	}
 
+	i.Version++
+
 	return nil
 }
 
-func updateItemLocation(ctx context.Context, repo repository, id string, locationID *string) error {
-	if err := repo.UpdateItemLocation(ctx, id, locationID); err != nil {
-		return fmt.Errorf("update item location: %w", err)
+// finishItem archives the item with the outcome, keeping it in the history instead of deleting it.
+func finishItem(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, params finishItemParams,
+) (item, error) {
+	if err := validate.Struct(params); err != nil {
+		return item{}, fmt.Errorf("%w: %w", errValidation, err)
 	}
 
-	return nil
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	if i.ArchivedAt != nil {
+		return item{}, errItemArchived
+	}
+
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return item{}, err
+	}
+
+	i.ArchivedAt = cmp.Or(params.FinishedAt, getPtr(time.Now()))
+	i.Outcome = &params.Outcome
+
+	if err := repo.UpdateItem(ctx, id, itemToParams(i), &i.Version); err != nil {
+		return item{}, fmt.Errorf("update item: %w", err)
+	}
+
+	i.Version++
+
+	if params.AddToShoppingList {
+		if err := addItemToShoppingList(ctx, repo, i); err != nil {
+			return item{}, err
+		}
+	}
+
+	return withExpiry(i, rules), nil
+}
+
+// openItem sets when the item was opened, starting its lifespan. The item is returned with its new expiry.
+func openItem(ctx context.Context, repo repository, id string, params openItemParams) (item, error) {
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	switch {
+	case i.ArchivedAt != nil:
+		return item{}, errItemArchived
+	case i.OpenedAt != nil:
+		return item{}, errItemOpened
+	}
+
+	i.OpenedAt = cmp.Or(params.OpenedAt, getPtr(time.Now()))
+
+	if i.OpenedAt.Before(i.BoughtAt) {
+		return item{}, fmt.Errorf("%w: the item cannot be opened before it was bought", errValidation)
+	}
+
+	return updateItemReturningExpiry(ctx, repo, id, i)
+}
+
+// unopenItem undoes openItem, clearing when the item was opened.
+func unopenItem(ctx context.Context, repo repository, id string) (item, error) {
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	switch {
+	case i.ArchivedAt != nil:
+		return item{}, errItemArchived
+	case i.OpenedAt == nil:
+		return item{}, errItemNotOpened
+	}
+
+	i.OpenedAt = nil
+
+	return updateItemReturningExpiry(ctx, repo, id, i)
+}
+
+// freezeItem puts the item in the freezer, its expiry follows the frozen shelf life until it is thawed.
+func freezeItem(ctx context.Context, repo repository, id string, params freezeItemParams) (item, error) {
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	switch {
+	case i.ArchivedAt != nil:
+		return item{}, errItemArchived
+	case isItemFrozen(i):
+		return item{}, errItemFrozen
+	}
+
+	i.FrozenAt = cmp.Or(params.FrozenAt, getPtr(time.Now()))
+	i.ThawedAt = nil
+
+	if i.FrozenAt.Before(i.BoughtAt) {
+		return item{}, fmt.Errorf("%w: the item cannot be frozen before it was bought", errValidation)
+	}
+
+	return updateItemReturningExpiry(ctx, repo, id, i)
+}
+
+// thawItem takes the item out of the freezer, its expiry follows the thawed lifespan from then on.
+func thawItem(ctx context.Context, repo repository, id string, params thawItemParams) (item, error) {
+	i, err := repo.GetItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get item: %w", err)
+	}
+
+	switch {
+	case i.ArchivedAt != nil:
+		return item{}, errItemArchived
+	case !isItemFrozen(i):
+		return item{}, errItemNotFrozen
+	}
+
+	i.ThawedAt = cmp.Or(params.ThawedAt, getPtr(time.Now()))
+
+	if i.ThawedAt.Before(*i.FrozenAt) {
+		return item{}, fmt.Errorf("%w: the item cannot be thawed before it was frozen", errValidation)
+	}
+
+	return updateItemReturningExpiry(ctx, repo, id, i)
+}
+
+// updateItemReturningExpiry stores the item read from the repository unless it changed since, returning it with its
+// new version and expiry.
+func updateItemReturningExpiry(ctx context.Context, repo repository, id string, i item) (item, error) {
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return item{}, err
+	}
+
+	if err := repo.UpdateItem(ctx, id, itemToParams(i), &i.Version); err != nil {
+		return item{}, fmt.Errorf("update item: %w", err)
+	}
+
+	i.Version++
+
+	return withExpiry(i, rules), nil
+}
+
+// updateItemLocation moves the item to the location, returning it with its new version.
+func updateItemLocation(
+	ctx context.Context, repo repository, id string, locationID *string, version *int,
+) (item, error) {
+	if err := repo.UpdateItemLocation(ctx, id, locationID, version); err != nil {
+		return item{}, fmt.Errorf("update item location: %w", err)
+	}
+
+	return getItem(ctx, repo, id)
 }
 
-func deleteItem(ctx context.Context, repo repository, id string) error {
-	if err := repo.DeleteItem(ctx, id); err != nil {
+func deleteItem(ctx context.Context, repo repository, id string, version *int) error {
+	if err := repo.DeleteItem(ctx, id, version); err != nil {
 		return fmt.Errorf("delete item: %w", err)
 	}
 
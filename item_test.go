@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -24,11 +27,15 @@ func TestCreateItem(t *testing.T) {
 		LocationID: getPtr("my-loc"),
 	}
 
-	err := createItem(context.Background(), mockRepo, validate, params)
+	i, err := createItem(context.Background(), mockRepo, validate, params)
 	if err != nil {
 		t.Errorf("Got error: %s", err)
 	}
 
+	if i.ID != "created" || i.Name != params.Name || i.Status != itemStatusOK {
+		t.Errorf("Expected the created item with its expiry, got %+v", i)
+	}
+
 	if mockRepo.CreateItemCalls != 1 {
 		t.Errorf("CreateItem called %d times instead of once", mockRepo.CreateLocationCalls)
 	}
@@ -42,7 +49,7 @@ func TestUpdateItem(t *testing.T) {
 	t.Parallel()
 
 	validate := validator.New(validator.WithRequiredStructEnabled())
-	mockRepo := &mockRepository{}
+	mockRepo := &mockRepository{GetItemRes: item{ID: "cheese", Name: "Cheese", Version: 2}}
 	id := "cheese"
 	params := writeItemParams{
 		Name:       "Cheese",
@@ -54,11 +61,15 @@ func TestUpdateItem(t *testing.T) {
 		LocationID: getPtr("my-loc"),
 	}
 
-	err := updateItem(context.Background(), mockRepo, validate, id, params)
+	i, err := updateItem(context.Background(), mockRepo, validate, id, params, nil)
 	if err != nil {
 		t.Errorf("Got error: %s", err)
 	}
 
+	if mockRepo.GetItemID != id || i.Version != 2 {
+		t.Errorf("Expected the stored item with its new version, got %+v", i)
+	}
+
 	if mockRepo.UpdateItemCalls != 1 {
 		t.Errorf("UpdateItem called %d times instead of once", mockRepo.UpdateLocationCalls)
 	}
@@ -72,6 +83,393 @@ func TestUpdateItem(t *testing.T) {
 	}
 }
 
+func TestCreateItemQuantity(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	scenarios := []struct {
+		quantity        *float64
+		initialQuantity *float64
+		unit            *itemUnit
+		valid           bool
+	}{
+		{valid: true},
+		{quantity: getPtr(300.0), initialQuantity: getPtr(1000.0), unit: getPtr(itemUnitGram), valid: true},
+		{quantity: getPtr(2.0), unit: getPtr(itemUnitCount), valid: true},
+		{quantity: getPtr(0.0), initialQuantity: getPtr(1.5), unit: getPtr(itemUnitLiter), valid: true},
+		{quantity: getPtr(2.0), valid: false},
+		{unit: getPtr(itemUnitKilogram), valid: false},
+		{initialQuantity: getPtr(6.0), unit: getPtr(itemUnitCount), valid: false},
+		{quantity: getPtr(7.0), initialQuantity: getPtr(6.0), unit: getPtr(itemUnitCount), valid: false},
+		{quantity: getPtr(-1.0), unit: getPtr(itemUnitMilliliter), valid: false},
+		{quantity: getPtr(1.0), unit: getPtr(itemUnit("lb")), valid: false},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{}
+		params := writeItemParams{
+			Name: "Flour", Tags: []string{}, BoughtAt: time.Now(),
+			Quantity: s.quantity, InitialQuantity: s.initialQuantity, Unit: s.unit,
+		}
+
+		_, err := createItem(context.Background(), mockRepo, validate, params)
+		if s.valid && err != nil {
+			t.Errorf("Got error for %+v: %s", s, err)
+		} else if !s.valid && !errors.Is(err, errValidation) {
+			t.Errorf("Expected a validation error for %+v, got %v", s, err)
+		}
+	}
+
+	mockRepo := &mockRepository{}
+	params := writeItemParams{
+		Name: "Yogurt", Tags: []string{}, BoughtAt: time.Now(), Quantity: getPtr(6.0), Unit: getPtr(itemUnitCount),
+	}
+
+	if _, err := createItem(context.Background(), mockRepo, validate, params); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if q := mockRepo.CreateItemParams.InitialQuantity; q == nil || *q != 6 {
+		t.Errorf("Expected the initial quantity to default to 6, got %v", q)
+	}
+}
+
+func TestConsumeItem(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	scenarios := []struct {
+		quantity float64
+		params   consumeItemParams
+		left     float64
+		updated  bool
+		deleted  bool
+		archived bool
+		listed   bool
+	}{
+		{quantity: 6, params: consumeItemParams{Amount: 2}, left: 4, updated: true},
+		{quantity: 2, params: consumeItemParams{Amount: 2}, left: 0, updated: true},
+		{quantity: 0.3, params: consumeItemParams{Amount: 1, WhenEmpty: itemEmptyKeep}, left: 0, updated: true},
+		{quantity: 2, params: consumeItemParams{Amount: 2, WhenEmpty: itemEmptyDelete}, left: 0, deleted: true},
+		{quantity: 6, params: consumeItemParams{Amount: 2, WhenEmpty: itemEmptyDelete}, left: 4, updated: true},
+		{
+			quantity: 1, params: consumeItemParams{Amount: 1, WhenEmpty: itemEmptyArchive},
+			left: 0, updated: true, archived: true,
+		},
+		{quantity: 6, params: consumeItemParams{Amount: 2, AddToShoppingList: true}, left: 4, updated: true},
+		{
+			quantity: 2, params: consumeItemParams{Amount: 3, WhenEmpty: itemEmptyDelete, AddToShoppingList: true},
+			left: 0, deleted: true, listed: true,
+		},
+		{
+			quantity: 2, params: consumeItemParams{Amount: 2, WhenEmpty: itemEmptyArchive, AddToShoppingList: true},
+			left: 0, updated: true, archived: true, listed: true,
+		},
+		{quantity: 0, params: consumeItemParams{Amount: 1, AddToShoppingList: true}, left: 0, updated: true},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{GetItemRes: item{
+			ID: "yogurt", Name: "Yogurt", Quantity: getPtr(s.quantity), InitialQuantity: getPtr(6.0),
+			Unit: getPtr(itemUnitCount),
+		}}
+
+		i, err := consumeItem(context.Background(), mockRepo, validate, "yogurt", s.params)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if i.Quantity == nil || *i.Quantity != s.left {
+			t.Errorf("Consuming %+v of %v left %v instead of %v", s.params, s.quantity, i.Quantity, s.left)
+		}
+
+		if (mockRepo.UpdateItemCalls == 1) != s.updated || (mockRepo.DeleteItemCalls == 1) != s.deleted {
+			t.Errorf("Consuming %+v of %v called UpdateItem %d times and DeleteItem %d times",
+				s.params, s.quantity, mockRepo.UpdateItemCalls, mockRepo.DeleteItemCalls)
+		}
+
+		if s.updated && !reflect.DeepEqual(mockRepo.UpdateItemParams.Quantity, getPtr(s.left)) {
+			t.Errorf("Updated the quantity to %v instead of %v", mockRepo.UpdateItemParams.Quantity, s.left)
+		}
+
+		if (mockRepo.UpdateItemParams.ArchivedAt != nil) != s.archived {
+			t.Errorf("Consuming %+v of %v set archived at to %v", s.params, s.quantity, mockRepo.UpdateItemParams.ArchivedAt)
+		}
+
+		if s.archived && !reflect.DeepEqual(mockRepo.UpdateItemParams.Outcome, getPtr(itemOutcomeConsumed)) {
+			t.Errorf("Archived the consumed item with outcome %v", mockRepo.UpdateItemParams.Outcome)
+		}
+
+		if (mockRepo.CreateShoppingListItemCalls == 1) != s.listed {
+			t.Errorf("Consuming %+v of %v added it to the shopping list %d times",
+				s.params, s.quantity, mockRepo.CreateShoppingListItemCalls)
+		}
+
+		if s.listed && !reflect.DeepEqual(mockRepo.CreateShoppingListItemParams.Quantity, getPtr(6.0)) {
+			t.Errorf("Listed %+v instead of the initial quantity", mockRepo.CreateShoppingListItemParams)
+		}
+	}
+}
+
+func TestConsumeItemErrs(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	scenarios := []struct {
+		repo   *mockRepository
+		params consumeItemParams
+		err    error
+	}{
+		{repo: &mockRepository{GetItemRes: item{ID: "salt"}}, params: consumeItemParams{Amount: 1}, err: errItemNoQuantity},
+		{repo: &mockRepository{GetItemErr: errItemNotFound}, params: consumeItemParams{Amount: 1}, err: errItemNotFound},
+		{
+			repo:   &mockRepository{GetItemRes: item{ID: "salt", Quantity: getPtr(1.0), ArchivedAt: getPtr(time.Now())}},
+			params: consumeItemParams{Amount: 1},
+			err:    errItemArchived,
+		},
+		{repo: &mockRepository{}, params: consumeItemParams{Amount: 0}, err: errValidation},
+		{repo: &mockRepository{}, params: consumeItemParams{Amount: 1, WhenEmpty: "eat"}, err: errValidation},
+		{
+			repo: &mockRepository{
+				GetItemRes: item{ID: "salt", Quantity: getPtr(1.0)}, UpdateItemErr: errVersionMismatch,
+			},
+			params: consumeItemParams{Amount: 1, AddToShoppingList: true},
+			err:    errVersionMismatch,
+		},
+	}
+
+	for _, s := range scenarios {
+		_, err := consumeItem(context.Background(), s.repo, validate, "salt", s.params)
+		if !errors.Is(err, s.err) {
+			t.Errorf("Expected %v for %+v, got %v", s.err, s.params, err)
+		}
+
+		if s.repo.UpdateItemErr == nil && (s.repo.UpdateItemCalls != 0 || s.repo.DeleteItemCalls != 0) {
+			t.Errorf("Modified the item for %+v", s.params)
+		}
+
+		if s.repo.CreateShoppingListItemCalls != 0 {
+			t.Errorf("Added the item to the shopping list for %+v", s.params)
+		}
+	}
+}
+
+func TestConsumeItemDeletePermission(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	viewer := withHouseholdRole(context.Background(), householdRoleViewer)
+	scenarios := []struct {
+		ctx    context.Context
+		params consumeItemParams
+		err    error
+	}{
+		{ctx: viewer, params: consumeItemParams{Amount: 1}},
+		{ctx: viewer, params: consumeItemParams{Amount: 1, WhenEmpty: itemEmptyArchive}},
+		{ctx: viewer, params: consumeItemParams{Amount: 1, WhenEmpty: itemEmptyDelete}, err: errForbidden},
+		{
+			ctx:    withScopes(context.Background(), []permission{permissionUse}),
+			params: consumeItemParams{Amount: 1, WhenEmpty: itemEmptyDelete},
+			err:    errForbidden,
+		},
+		{
+			ctx:    withHouseholdRole(context.Background(), householdRoleEditor),
+			params: consumeItemParams{Amount: 1, WhenEmpty: itemEmptyDelete},
+		},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{GetItemRes: item{ID: "salt", Quantity: getPtr(1.0)}}
+
+		_, err := consumeItem(s.ctx, mockRepo, validate, "salt", s.params)
+		if !errors.Is(err, s.err) {
+			t.Errorf("Expected %v for %+v, got %v", s.err, s.params, err)
+		}
+
+		if s.err != nil && (mockRepo.UpdateItemCalls != 0 || mockRepo.DeleteItemCalls != 0) {
+			t.Errorf("Modified the item for %+v", s.params)
+		}
+	}
+}
+
+func TestFinishItem(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	finishedAt := time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		params     finishItemParams
+		finishedAt *time.Time
+	}{
+		{params: finishItemParams{Outcome: itemOutcomeWasted, FinishedAt: &finishedAt}, finishedAt: &finishedAt},
+		{params: finishItemParams{Outcome: itemOutcomeGivenAway}},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{GetItemRes: item{ID: "milk", Name: "Milk", Tags: []string{"dairy"}}}
+
+		i, err := finishItem(context.Background(), mockRepo, validate, "milk", s.params)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if mockRepo.UpdateItemCalls != 1 || mockRepo.UpdateItemID != "milk" || mockRepo.DeleteItemCalls != 0 {
+			t.Errorf("Expected only UpdateItem to be called with milk, got %+v", mockRepo)
+		}
+
+		params := mockRepo.UpdateItemParams
+		if !reflect.DeepEqual(params.Outcome, &s.params.Outcome) || params.ArchivedAt == nil ||
+			!reflect.DeepEqual(params.Tags, []string{"dairy"}) {
+			t.Errorf("Finishing with %+v stored %+v", s.params, params)
+		}
+
+		if s.finishedAt != nil && !params.ArchivedAt.Equal(*s.finishedAt) {
+			t.Errorf("Archived at %v instead of %v", params.ArchivedAt, s.finishedAt)
+		}
+
+		if !reflect.DeepEqual(i.ArchivedAt, params.ArchivedAt) || !reflect.DeepEqual(i.Outcome, params.Outcome) {
+			t.Errorf("Returned %+v instead of the finished item", i)
+		}
+	}
+}
+
+func TestFinishItemErrs(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	scenarios := []struct {
+		repo   *mockRepository
+		params finishItemParams
+		err    error
+	}{
+		{repo: &mockRepository{}, params: finishItemParams{}, err: errValidation},
+		{repo: &mockRepository{}, params: finishItemParams{Outcome: "eaten"}, err: errValidation},
+		{
+			repo:   &mockRepository{GetItemErr: errItemNotFound},
+			params: finishItemParams{Outcome: itemOutcomeConsumed},
+			err:    errItemNotFound,
+		},
+		{
+			repo:   &mockRepository{GetItemRes: item{ID: "milk", ArchivedAt: getPtr(time.Now())}},
+			params: finishItemParams{Outcome: itemOutcomeConsumed},
+			err:    errItemArchived,
+		},
+	}
+
+	for _, s := range scenarios {
+		_, err := finishItem(context.Background(), s.repo, validate, "milk", s.params)
+		if !errors.Is(err, s.err) {
+			t.Errorf("Expected %v for %+v, got %v", s.err, s.params, err)
+		}
+
+		if s.repo.UpdateItemCalls != 0 {
+			t.Errorf("Updated the item for %+v", s.params)
+		}
+	}
+}
+
+func TestOpenItem(t *testing.T) {
+	t.Parallel()
+
+	boughtAt := time.Now().Add(-48 * time.Hour)
+	openedAt := time.Now().Add(-24 * time.Hour)
+	scenarios := []struct {
+		params   openItemParams
+		openedAt *time.Time
+		daysLeft int
+	}{
+		{params: openItemParams{}, daysLeft: 4},
+		{params: openItemParams{OpenedAt: &openedAt}, openedAt: &openedAt, daysLeft: 3},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{
+			GetItemRes:        item{ID: "milk", Name: "Milk", Tags: []string{"dairy"}, BoughtAt: boughtAt},
+			GetExpiryRulesRes: []expiryRule{{ID: "dairy", Tag: getPtr("dairy"), OpenedLifespan: getPtr(4)}},
+		}
+
+		i, err := openItem(context.Background(), mockRepo, "milk", s.params)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		params := mockRepo.UpdateItemParams
+		if mockRepo.UpdateItemCalls != 1 || mockRepo.UpdateItemID != "milk" || params.OpenedAt == nil ||
+			!reflect.DeepEqual(params.Tags, []string{"dairy"}) {
+			t.Fatalf("Opening with %+v stored %+v", s.params, params)
+		}
+
+		if s.openedAt != nil && !params.OpenedAt.Equal(*s.openedAt) {
+			t.Errorf("Opened at %v instead of %v", params.OpenedAt, s.openedAt)
+		} else if s.openedAt == nil && time.Since(*params.OpenedAt) > time.Minute {
+			t.Errorf("Opened at %v instead of now", params.OpenedAt)
+		}
+
+		if i.DaysLeft == nil || *i.DaysLeft != s.daysLeft {
+			t.Errorf("Opening with %+v left %v days instead of %d", s.params, i.DaysLeft, s.daysLeft)
+		}
+	}
+}
+
+func TestOpenItemErrs(t *testing.T) {
+	t.Parallel()
+
+	boughtAt := time.Now().Add(-48 * time.Hour)
+	scenarios := []struct {
+		repo   *mockRepository
+		params openItemParams
+		err    error
+	}{
+		{repo: &mockRepository{GetItemErr: errItemNotFound}, err: errItemNotFound},
+		{repo: &mockRepository{GetItemRes: item{ID: "milk", ArchivedAt: getPtr(time.Now())}}, err: errItemArchived},
+		{repo: &mockRepository{GetItemRes: item{ID: "milk", OpenedAt: getPtr(time.Now())}}, err: errItemOpened},
+		{
+			repo:   &mockRepository{GetItemRes: item{ID: "milk", BoughtAt: boughtAt}},
+			params: openItemParams{OpenedAt: getPtr(boughtAt.Add(-time.Hour))},
+			err:    errValidation,
+		},
+	}
+
+	for _, s := range scenarios {
+		if _, err := openItem(context.Background(), s.repo, "milk", s.params); !errors.Is(err, s.err) {
+			t.Errorf("Expected %v for %+v, got %v", s.err, s.params, err)
+		}
+
+		if s.repo.UpdateItemCalls != 0 {
+			t.Errorf("Updated the item for %+v", s.params)
+		}
+	}
+}
+
+func TestUnopenItem(t *testing.T) {
+	t.Parallel()
+
+	expiresAt := time.Now().Add(10 * 24 * time.Hour)
+	mockRepo := &mockRepository{GetItemRes: item{
+		ID: "milk", Name: "Milk", Tags: []string{}, OpenedAt: getPtr(time.Now()), Lifespan: getPtr(2),
+		ExpiresAt: &expiresAt,
+	}}
+
+	i, err := unopenItem(context.Background(), mockRepo, "milk")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if mockRepo.UpdateItemCalls != 1 || mockRepo.UpdateItemParams.OpenedAt != nil ||
+		!reflect.DeepEqual(mockRepo.UpdateItemParams.Lifespan, getPtr(2)) {
+		t.Errorf("Undoing the opening stored %+v", mockRepo.UpdateItemParams)
+	}
+
+	if i.DaysLeft == nil || *i.DaysLeft != 10 {
+		t.Errorf("Undoing the opening left %v days instead of 10", i.DaysLeft)
+	}
+
+	mockRepo = &mockRepository{GetItemRes: item{ID: "milk"}}
+	if _, err := unopenItem(context.Background(), mockRepo, "milk"); !errors.Is(err, errItemNotOpened) {
+		t.Errorf("Expected errItemNotOpened, got %v", err)
+	}
+}
+
 func TestUpdateItemLocation(t *testing.T) {
 	t.Parallel()
 
@@ -88,7 +486,7 @@ func TestUpdateItemLocation(t *testing.T) {
 	for _, row := range data {
 		mockRepo := &mockRepository{}
 
-		err := updateItemLocation(context.Background(), mockRepo, row.id, row.locationID)
+		_, err := updateItemLocation(context.Background(), mockRepo, row.id, row.locationID, nil)
 		if err != nil {
 			t.Errorf("Got error: %s", err)
 		}
@@ -114,7 +512,7 @@ func TestDeleteItem(t *testing.T) {
 	for _, id := range ids {
 		repo := &mockRepository{}
 
-		err := deleteItem(context.Background(), repo, id)
+		err := deleteItem(context.Background(), repo, id, nil)
 		if err != nil {
 			t.Errorf("Returned unexpected error for %s: %+v", id, err)
 		}
@@ -124,3 +522,590 @@ func TestDeleteItem(t *testing.T) {
 		}
 	}
 }
+
+func TestGetItems(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	query := itemsQuery{Tags: getPtr([]string{"dairy"}), LocationIDs: getPtr([]string{"fridge"}), Sort: itemsSortName}
+	mockRepo := &mockRepository{GetItemsRes: []item{
+		{Name: "Cheese", ExpiresAt: getPtr(time.Now().Add(time.Hour * 36))},
+		{Name: "Salt"},
+	}}
+
+	items, _, err := getItems(context.Background(), mockRepo, validate, itemsFilter{itemsQuery: query}, pageParams{})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if !reflect.DeepEqual(mockRepo.GetItemsQuery, query) {
+		t.Errorf("Called GetItems with %+v instead of %+v", mockRepo.GetItemsQuery, query)
+	}
+
+	if items[0].DaysLeft == nil || *items[0].DaysLeft != 2 {
+		t.Errorf("Expected Cheese to have 2 days left, got %v", items[0].DaysLeft)
+	}
+
+	if items[1].DaysLeft != nil {
+		t.Errorf("Expected Salt to have unknown days left, got %d", *items[1].DaysLeft)
+	}
+}
+
+func TestGetItemsExpiryFilters(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	now := time.Now()
+	repoItems := []item{
+		{Name: "Cheese", ExpiresAt: getPtr(now.Add(time.Hour * 36))},
+		{Name: "Salt"},
+		{Name: "Milk", ExpiresAt: getPtr(now.Add(-time.Hour * 36))},
+		{Name: "Rice", ExpiresAt: getPtr(now.Add(time.Hour * 24 * 30))},
+	}
+
+	scenarios := []struct {
+		filter itemsFilter
+		names  []string
+	}{
+		{filter: itemsFilter{}, names: []string{"Cheese", "Salt", "Milk", "Rice"}},
+		{filter: itemsFilter{ExpiresWithin: getPtr(3)}, names: []string{"Cheese", "Milk"}},
+		{filter: itemsFilter{Expired: getPtr(true)}, names: []string{"Milk"}},
+		{filter: itemsFilter{Expired: getPtr(false)}, names: []string{"Cheese", "Salt", "Rice"}},
+		{filter: itemsFilter{ExpiresWithin: getPtr(3), Expired: getPtr(false)}, names: []string{"Cheese"}},
+		{
+			filter: itemsFilter{itemsQuery: itemsQuery{Sort: itemsSortDaysLeft}},
+			names:  []string{"Milk", "Cheese", "Rice", "Salt"},
+		},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{GetItemsRes: slices.Clone(repoItems)}
+
+		items, _, err := getItems(context.Background(), mockRepo, validate, s.filter, pageParams{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		names := []string{}
+		for _, i := range items {
+			names = append(names, i.Name)
+		}
+
+		if !reflect.DeepEqual(names, s.names) {
+			t.Errorf("Filtering with %+v returned %v instead of %v", s.filter, names, s.names)
+		}
+	}
+}
+
+func TestGetItemsValidation(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	filters := []itemsFilter{
+		{itemsQuery: itemsQuery{Sort: "expiresAt"}},
+		{ExpiresWithin: getPtr(-1)},
+	}
+
+	for _, filter := range filters {
+		mockRepo := &mockRepository{}
+
+		_, _, err := getItems(context.Background(), mockRepo, validate, filter, pageParams{})
+		if !errors.Is(err, errValidation) {
+			t.Errorf("Expected a validation error for %+v, got %v", filter, err)
+		}
+
+		if mockRepo.GetItemsCalls != 0 {
+			t.Errorf("Called GetItems %d times for an invalid filter", mockRepo.GetItemsCalls)
+		}
+	}
+}
+
+func TestGetItemsPagination(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	repo := newMemoryRepository()
+	now := time.Now()
+
+	for idx := range 9 {
+		params := writeItemParams{Name: fmt.Sprintf("Item %d", idx), Tags: []string{}, BoughtAt: now}
+		if idx%3 != 0 {
+			params.ExpiresAt = getPtr(now.Add(time.Duration(idx-4) * 24 * time.Hour))
+		}
+
+		if _, err := repo.CreateItem(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	filters := []itemsFilter{
+		{itemsQuery: itemsQuery{Sort: itemsSortName}},
+		{itemsQuery: itemsQuery{Sort: itemsSortName}, Expired: getPtr(false)},
+		{ExpiresWithin: getPtr(2)},
+		{itemsQuery: itemsQuery{Sort: itemsSortDaysLeft}},
+		{itemsQuery: itemsQuery{Sort: itemsSortDaysLeft}, Expired: getPtr(true)},
+	}
+
+	for _, filter := range filters {
+		all, _, err := getItems(ctx, repo, validate, filter, pageParams{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		paged := []item{}
+		page := pageParams{Limit: 2}
+
+		for range all {
+			items, nextCursor, err := getItems(ctx, repo, validate, filter, page)
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			if len(items) > page.Limit {
+				t.Fatalf("Got %d items with limit %d", len(items), page.Limit)
+			}
+
+			paged = append(paged, items...)
+
+			if nextCursor == "" {
+				break
+			}
+
+			page.Cursor = nextCursor
+		}
+
+		if !reflect.DeepEqual(paged, all) {
+			t.Errorf("Paging with %+v returned %+v instead of %+v", filter, paged, all)
+		}
+	}
+}
+
+func TestGetItemsInvalidCursor(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	nameCursor, err := encodeCursor(itemsCursor{Sort: itemsSortName, ID: "milk", Name: "Milk"})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	scenarios := []struct {
+		filter itemsFilter
+		page   pageParams
+	}{
+		{filter: itemsFilter{}, page: pageParams{Limit: 2, Cursor: "not a cursor"}},
+		{filter: itemsFilter{itemsQuery: itemsQuery{Sort: itemsSortPrice}}, page: pageParams{Limit: 2, Cursor: nameCursor}},
+		{filter: itemsFilter{}, page: pageParams{Limit: -1}},
+	}
+
+	for _, s := range scenarios {
+		_, _, err := getItems(context.Background(), &mockRepository{}, validate, s.filter, s.page)
+		if !errors.Is(err, errValidation) {
+			t.Errorf("Expected a validation error for %+v, got %v", s.page, err)
+		}
+	}
+}
+
+func TestGetItem(t *testing.T) {
+	t.Parallel()
+
+	mockRepo := &mockRepository{GetItemRes: item{
+		ID: "milk", Name: "Milk", OpenedAt: getPtr(time.Now()), Lifespan: getPtr(4),
+	}}
+
+	i, err := getItem(context.Background(), mockRepo, "milk")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if mockRepo.GetItemID != "milk" {
+		t.Errorf("Called GetItem with %s instead of milk", mockRepo.GetItemID)
+	}
+
+	if i.DaysLeft == nil || *i.DaysLeft != 4 {
+		t.Errorf("Expected Milk to have 4 days left, got %v", i.DaysLeft)
+	}
+
+	mockRepo = &mockRepository{GetItemErr: errItemNotFound}
+
+	if _, err := getItem(context.Background(), mockRepo, "missing"); !errors.Is(err, errItemNotFound) {
+		t.Errorf("Expected errItemNotFound, got %v", err)
+	}
+}
+
+func TestWithExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	data := []struct {
+		item      item
+		expiresAt *time.Time
+		status    itemStatus
+	}{
+		{item: item{Name: "Salt"}, expiresAt: nil, status: itemStatusUnknown},
+		{item: item{Name: "Rice", Lifespan: getPtr(3)}, expiresAt: nil, status: itemStatusUnknown},
+		{
+			item:      item{Name: "Honey", ExpiresAt: getPtr(now.Add(time.Hour * 24 * 30))},
+			expiresAt: getPtr(now.Add(time.Hour * 24 * 30)),
+			status:    itemStatusOK,
+		},
+		{
+			item:      item{Name: "Yogurt", ExpiresAt: getPtr(now.Add(time.Hour * 36))},
+			expiresAt: getPtr(now.Add(time.Hour * 36)),
+			status:    itemStatusExpiringSoon,
+		},
+		{
+			item: item{
+				Name:      "Milk",
+				ExpiresAt: getPtr(now.Add(time.Hour * 24 * 10)),
+				OpenedAt:  getPtr(now.Add(-time.Hour * 24 * 5)),
+				Lifespan:  getPtr(3),
+			},
+			expiresAt: getPtr(now.Add(-time.Hour * 24 * 2)),
+			status:    itemStatusExpired,
+		},
+		{
+			item: item{
+				Name:      "Peas",
+				ExpiresAt: getPtr(now.Add(-time.Hour * 24 * 10)),
+				FrozenAt:  getPtr(now.Add(-time.Hour * 24 * 30)),
+			},
+			expiresAt: getPtr(now.Add(time.Hour * 24 * 60)),
+			status:    itemStatusOK,
+		},
+		{
+			item: item{
+				Name:      "Fish",
+				ExpiresAt: getPtr(now.Add(time.Hour * 24 * 10)),
+				FrozenAt:  getPtr(now.Add(-time.Hour * 24 * 30)),
+				ThawedAt:  getPtr(now.Add(-time.Hour * 24)),
+			},
+			expiresAt: getPtr(now.Add(time.Hour * 24)),
+			status:    itemStatusExpiringSoon,
+		},
+		{
+			item: item{
+				Name:     "Bread",
+				FrozenAt: getPtr(now.Add(-time.Hour * 24 * 30)),
+				ThawedAt: getPtr(now.Add(-time.Hour * 24 * 5)),
+				OpenedAt: getPtr(now.Add(-time.Hour * 24 * 40)),
+				Lifespan: getPtr(3),
+			},
+			expiresAt: getPtr(now.Add(-time.Hour * 24 * 3)),
+			status:    itemStatusExpired,
+		},
+	}
+
+	for _, row := range data {
+		i := withExpiry(row.item, expiryRules{})
+
+		if !contractEqualTimes(i.EffectiveExpiresAt, row.expiresAt) {
+			t.Errorf("%s expires at %v instead of %v", i.Name, i.EffectiveExpiresAt, row.expiresAt)
+		}
+
+		if i.Status != row.status {
+			t.Errorf("%s has status %s instead of %s", i.Name, i.Status, row.status)
+		}
+
+		if (i.DaysLeft == nil) != (row.expiresAt == nil) {
+			t.Errorf("%s has days left %v while expiring at %v", i.Name, i.DaysLeft, row.expiresAt)
+		}
+	}
+}
+
+func TestFreezeItem(t *testing.T) {
+	t.Parallel()
+
+	boughtAt := time.Now().Add(-48 * time.Hour)
+	mockRepo := &mockRepository{
+		GetItemRes: item{
+			ID: "peas", Name: "Peas", Tags: []string{"vegetables"}, BoughtAt: boughtAt,
+			FrozenAt: getPtr(boughtAt), ThawedAt: getPtr(boughtAt.Add(time.Hour)),
+		},
+		GetExpiryRulesRes: []expiryRule{{ID: "vegetables", Tag: getPtr("vegetables"), FrozenShelfLife: getPtr(30)}},
+	}
+
+	i, err := freezeItem(context.Background(), mockRepo, "peas", freezeItemParams{})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	params := mockRepo.UpdateItemParams
+	if mockRepo.UpdateItemCalls != 1 || params.FrozenAt == nil || time.Since(*params.FrozenAt) > time.Minute ||
+		params.ThawedAt != nil {
+		t.Errorf("Freezing stored %+v", params)
+	}
+
+	if i.DaysLeft == nil || *i.DaysLeft != 30 {
+		t.Errorf("Freezing left %v days instead of 30", i.DaysLeft)
+	}
+}
+
+func TestThawItem(t *testing.T) {
+	t.Parallel()
+
+	frozenAt := time.Now().Add(-30 * 24 * time.Hour)
+	thawedAt := time.Now().Add(-24 * time.Hour)
+	mockRepo := &mockRepository{GetItemRes: item{
+		ID: "fish", Name: "Fish", Tags: []string{}, BoughtAt: frozenAt, FrozenAt: &frozenAt,
+	}}
+
+	i, err := thawItem(context.Background(), mockRepo, "fish", thawItemParams{ThawedAt: &thawedAt})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	params := mockRepo.UpdateItemParams
+	if mockRepo.UpdateItemCalls != 1 || !contractEqualTimes(params.ThawedAt, &thawedAt) ||
+		!contractEqualTimes(params.FrozenAt, &frozenAt) {
+		t.Errorf("Thawing stored %+v", params)
+	}
+
+	if i.DaysLeft == nil || *i.DaysLeft != defaultThawedLifespan-1 {
+		t.Errorf("Thawing left %v days instead of %d", i.DaysLeft, defaultThawedLifespan-1)
+	}
+}
+
+func TestFreezeThawItemErrs(t *testing.T) {
+	t.Parallel()
+
+	boughtAt := time.Now().Add(-48 * time.Hour)
+	frozen := item{ID: "peas", BoughtAt: boughtAt, FrozenAt: &boughtAt}
+	archived := item{ID: "peas", BoughtAt: boughtAt, ArchivedAt: getPtr(time.Now())}
+
+	scenarios := []struct {
+		name string
+		repo *mockRepository
+		fn   func(ctx context.Context, repo repository) (item, error)
+		err  error
+	}{
+		{
+			name: "freeze frozen", repo: &mockRepository{GetItemRes: frozen}, err: errItemFrozen,
+			fn: func(ctx context.Context, repo repository) (item, error) {
+				return freezeItem(ctx, repo, "peas", freezeItemParams{})
+			},
+		},
+		{
+			name: "freeze archived", repo: &mockRepository{GetItemRes: archived}, err: errItemArchived,
+			fn: func(ctx context.Context, repo repository) (item, error) {
+				return freezeItem(ctx, repo, "peas", freezeItemParams{})
+			},
+		},
+		{
+			name: "freeze before bought", repo: &mockRepository{GetItemRes: item{ID: "peas", BoughtAt: boughtAt}},
+			err: errValidation,
+			fn: func(ctx context.Context, repo repository) (item, error) {
+				return freezeItem(ctx, repo, "peas", freezeItemParams{FrozenAt: getPtr(boughtAt.Add(-time.Hour))})
+			},
+		},
+		{
+			name: "thaw unfrozen", repo: &mockRepository{GetItemRes: item{ID: "peas"}}, err: errItemNotFrozen,
+			fn: func(ctx context.Context, repo repository) (item, error) {
+				return thawItem(ctx, repo, "peas", thawItemParams{})
+			},
+		},
+		{
+			name: "thaw before frozen", repo: &mockRepository{GetItemRes: frozen}, err: errValidation,
+			fn: func(ctx context.Context, repo repository) (item, error) {
+				return thawItem(ctx, repo, "peas", thawItemParams{ThawedAt: getPtr(boughtAt.Add(-time.Hour))})
+			},
+		},
+		{
+			name: "thaw missing", repo: &mockRepository{GetItemErr: errItemNotFound}, err: errItemNotFound,
+			fn: func(ctx context.Context, repo repository) (item, error) {
+				return thawItem(ctx, repo, "peas", thawItemParams{})
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		if _, err := s.fn(context.Background(), s.repo); !errors.Is(err, s.err) {
+			t.Errorf("Expected %v for %s, got %v", s.err, s.name, err)
+		}
+
+		if s.repo.UpdateItemCalls != 0 {
+			t.Errorf("Updated the item for %s", s.name)
+		}
+	}
+}
+
+type mockNotifier struct {
+	calls          int
+	expiries       []itemExpiry
+	comingExpiries []itemExpiry
+	frozenExpiries []itemExpiry
+	// emails holds the recipients of every call
+	emails [][]string
+	// shared makes the notifier ignore the recipients, like Telegram
+	shared bool
+}
+
+func (n *mockNotifier) sendsToRecipients() bool {
+	return !n.shared
+}
+
+func (n *mockNotifier) NotifyAboutItems(
+	_ context.Context,
+	expiries []itemExpiry,
+	comingExpiries []itemExpiry,
+	frozenExpiries []itemExpiry,
+	emails []string,
+) error {
+	n.calls++
+	n.expiries, n.comingExpiries, n.frozenExpiries = expiries, comingExpiries, frozenExpiries
+	n.emails = append(n.emails, emails)
+
+	return nil
+}
+
+func TestNotifyAboutItemsFrozen(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	mockRepo := &mockRepository{GetItemsRes: []item{
+		{Name: "Milk", ExpiresAt: getPtr(now.Add(-24 * time.Hour))},
+		{Name: "Yogurt", ExpiresAt: getPtr(now.Add(24 * time.Hour))},
+		{Name: "Peas", FrozenAt: getPtr(now.Add(-89 * 24 * time.Hour))},
+		{Name: "Fish", FrozenAt: getPtr(now.Add(-10 * 24 * time.Hour))},
+		{Name: "Bread", FrozenAt: getPtr(now.Add(-10 * 24 * time.Hour)), ThawedAt: getPtr(now.Add(-72 * time.Hour))},
+	}}
+	n := &mockNotifier{}
+
+	if err := notifyAboutItems(context.Background(), mockRepo, n, nil); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	names := func(expiries []itemExpiry) []string {
+		res := []string{}
+		for _, exp := range expiries {
+			res = append(res, exp.item.Name)
+		}
+
+		return res
+	}
+
+	if n.calls != 1 ||
+		!reflect.DeepEqual(names(n.expiries), []string{"Milk", "Bread"}) ||
+		!reflect.DeepEqual(names(n.comingExpiries), []string{"Yogurt"}) ||
+		!reflect.DeepEqual(names(n.frozenExpiries), []string{"Peas"}) {
+		t.Errorf("Notified about %v, %v and frozen %v", names(n.expiries), names(n.comingExpiries), names(n.frozenExpiries))
+	}
+}
+
+func TestPatchItem(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	params := writeItemParams{
+		Name: "Milk", Type: getPtr("1l"), Tags: []string{"dairy"}, Price: getPtr(299), BoughtAt: boughtAt,
+		LocationID: getPtr("fridge"), Quantity: getPtr(1.0), Unit: getPtr(itemUnitLiter),
+	}
+	scenarios := []struct {
+		patch    string
+		expected func(params writeItemParams) writeItemParams
+		err      error
+	}{
+		{
+			patch: `{"name": "Oat milk", "tags": ["vegan"]}`,
+			expected: func(params writeItemParams) writeItemParams {
+				params.Name, params.Tags = "Oat milk", []string{"vegan"}
+
+				return params
+			},
+		},
+		{
+			patch: `{"price": null, "locationId": "cellar"}`,
+			expected: func(params writeItemParams) writeItemParams {
+				params.Price, params.LocationID = nil, getPtr("cellar")
+
+				return params
+			},
+		},
+		{patch: `{"name": "M"}`, err: errValidation},
+		{patch: `{"unit": null}`, err: errValidation},
+		{patch: `{"price": "free"}`, err: errValidation},
+		{patch: `[]`, err: errValidation},
+	}
+
+	for _, s := range scenarios {
+		repo := newMemoryRepository()
+		contractCreateItem(t, repo, params.withDefaultQuantity())
+
+		id := contractGetItemsByName(t, repo, itemsQuery{})["Milk"].ID
+
+		i, err := patchItem(ctx, repo, validate, id, []byte(s.patch), nil)
+		if !errors.Is(err, s.err) || (s.err == nil && err != nil) {
+			t.Fatalf("Patching with %s returned %v instead of %v", s.patch, err, s.err)
+		}
+
+		stored, err := repo.GetItem(ctx, id)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		expected := params.withDefaultQuantity()
+		if s.expected != nil {
+			expected = s.expected(expected)
+
+			contractCompareItem(t, i, expected)
+		}
+
+		contractCompareItem(t, stored, expected)
+	}
+
+	_, err := patchItem(ctx, newMemoryRepository(), validate, "missing", []byte(`{"name": "Tea"}`), nil)
+	if !errors.Is(err, errItemNotFound) {
+		t.Errorf("Expected errItemNotFound, got %v", err)
+	}
+}
+
+func TestItemVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	repo := newMemoryRepository()
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	contractCreateItem(t, repo, writeItemParams{
+		Name: "Milk", Tags: []string{}, BoughtAt: boughtAt, Quantity: getPtr(1.0), Unit: getPtr(itemUnitLiter),
+	})
+
+	id := contractGetItemsByName(t, repo, itemsQuery{})["Milk"].ID
+
+	i, err := patchItem(ctx, repo, validate, id, []byte(`{"name": "Oat milk"}`), getPtr(1))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if i.Version != 2 {
+		t.Errorf("Patched item has version %d instead of 2", i.Version)
+	}
+
+	_, err = patchItem(ctx, repo, validate, id, []byte(`{"name": "Soy milk"}`), getPtr(1))
+	if !errors.Is(err, errVersionMismatch) {
+		t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+	}
+
+	i, err = consumeItem(ctx, repo, validate, id, consumeItemParams{Amount: 0.5})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	stored, err := repo.GetItem(ctx, id)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if i.Version != 3 || stored.Version != 3 || stored.Name != "Oat milk" {
+		t.Errorf("Expected Oat milk at version 3, got %d and stored %+v", i.Version, stored)
+	}
+
+	if err := deleteItem(ctx, repo, id, getPtr(2)); !errors.Is(err, errVersionMismatch) {
+		t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// itemBatchOp is the kind of an operation in a batch of item operations.
+type itemBatchOp string
+
+const (
+	itemBatchCreate itemBatchOp = "create"
+	itemBatchUpdate itemBatchOp = "update"
+	itemBatchMove   itemBatchOp = "move"
+	itemBatchDelete itemBatchOp = "delete"
+)
+
+// maxItemBatchOps caps the number of operations in a batch, which are all written in a single transaction.
+const maxItemBatchOps = 100
+
+type itemBatchOperation struct {
+	Op itemBatchOp `json:"op" validate:"required,oneof=create update move delete"`
+	// ID is the item to update, move or delete
+	ID string `json:"id" validate:"required_unless=Op create"`
+	// Version applies the operation only to the version of the item, like the If-Match header
+	Version *int `json:"version"`
+	// Item is the created item or the update of the item
+	Item *writeItemParams `json:"item" validate:"required_if=Op create,required_if=Op update"`
+	// LocationID is where the item is moved, nil removes it from its location
+	LocationID *string `json:"locationId"`
+}
+
+type itemBatchResult struct {
+	Op itemBatchOp `json:"op"`
+	// ID is the written item, including the ID given to a created item
+	ID string `json:"id,omitempty"`
+	// Applied is false for all the operations when any of them fails
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// itemWrite is a write of repository.ApplyItemWrites, it uses the fields the repository method writing the same
+// way takes.
+type itemWrite struct {
+	Op         itemBatchOp
+	ID         string
+	Params     writeItemParams
+	LocationID *string
+	Version    *int
+}
+
+// itemWriteError is returned by repository.ApplyItemWrites when one of the writes fails, none of them are applied.
+type itemWriteError struct {
+	Index int
+	Err   error
+}
+
+func (e itemWriteError) Error() string {
+	return fmt.Sprintf("item write %d: %s", e.Index, e.Err)
+}
+
+func (e itemWriteError) Unwrap() error {
+	return e.Err
+}
+
+// toWrite validates the operation and returns the write applying it, the items are validated and get their
+// defaults like when they are created or updated one by one.
+func (op itemBatchOperation) toWrite(validate *validator.Validate) (itemWrite, error) {
+	if op.Item != nil {
+		op.Item = getPtr(op.Item.withDefaultQuantity())
+	}
+
+	if err := validate.Struct(op); err != nil {
+		return itemWrite{}, fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	w := itemWrite{Op: op.Op, ID: op.ID, LocationID: op.LocationID, Version: op.Version}
+
+	if op.Op == itemBatchCreate || op.Op == itemBatchUpdate {
+		w.Params = *op.Item
+	}
+
+	return w, nil
+}
+
+// batchItems applies all the operations or none of them. The results tell which of the operations failed when the
+// returned error is not nil.
+func batchItems(
+	ctx context.Context, repo repository, validate *validator.Validate, ops []itemBatchOperation,
+) ([]itemBatchResult, error) {
+	if len(ops) == 0 || len(ops) > maxItemBatchOps {
+		return nil, fmt.Errorf("%w: a batch takes from 1 to %d operations", errValidation, maxItemBatchOps)
+	}
+
+	results := make([]itemBatchResult, len(ops))
+	writes := make([]itemWrite, len(ops))
+
+	var invalidErr error
+
+	for idx, op := range ops {
+		results[idx] = itemBatchResult{Op: op.Op, ID: op.ID}
+
+		w, err := op.toWrite(validate)
+		if err != nil {
+			results[idx].Error = err.Error()
+
+			if invalidErr == nil {
+				invalidErr = fmt.Errorf("operation %d: %w", idx, err)
+			}
+		}
+
+		writes[idx] = w
+	}
+
+	if invalidErr != nil {
+		return results, invalidErr
+	}
+
+	ids, err := repo.ApplyItemWrites(ctx, writes)
+	if err != nil {
+		if writeErr := (itemWriteError{}); errors.As(err, &writeErr) {
+			results[writeErr.Index].Error = writeErr.Err.Error()
+		}
+
+		return results, fmt.Errorf("apply item writes: %w", err)
+	}
+
+	for idx := range results {
+		results[idx].ID = ids[idx]
+		results[idx].Applied = true
+	}
+
+	return results, nil
+}
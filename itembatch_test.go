@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestBatchItems(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	repo := newMemoryRepository()
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := createExpiryRule(ctx, repo, validate, writeExpiryRuleParams{
+		Tag: getPtr("dairy"), ShelfLife: getPtr(10),
+	}); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	contractCreateItem(t, repo, writeItemParams{Name: "Cheese", Tags: []string{}, BoughtAt: boughtAt})
+
+	cheese := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"]
+	milk := writeItemParams{
+		Name: "Milk", Tags: []string{"dairy"}, BoughtAt: boughtAt, Quantity: getPtr(1.0), Unit: getPtr(itemUnitLiter),
+	}
+
+	results, err := batchItems(ctx, repo, validate, []itemBatchOperation{
+		{Op: itemBatchCreate, Item: &milk},
+		{Op: itemBatchMove, ID: cheese.ID, LocationID: getPtr("fridge"), Version: getPtr(1)},
+	})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	items := contractGetItemsByName(t, repo, itemsQuery{})
+
+	if len(results) != 2 || !results[0].Applied || results[0].ID != items["Milk"].ID || results[1].ID != cheese.ID {
+		t.Fatalf("Got results %+v for items %+v", results, items)
+	}
+
+	contractCompareItem(t, items["Milk"], milk.withDefaultQuantity())
+
+	if i, err := getItem(ctx, repo, items["Milk"].ID); err != nil || i.ExpiresAt == nil {
+		t.Errorf("Created item did not get the expiry of its rule, got %+v with error %v", i, err)
+	}
+}
+
+func TestBatchItemsErrs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tea := writeItemParams{Name: "Tea", Tags: []string{}, BoughtAt: boughtAt}
+
+	scenarios := []struct {
+		ops      []itemBatchOperation
+		err      error
+		failedAt int
+	}{
+		{ops: []itemBatchOperation{}, err: errValidation, failedAt: -1},
+		{ops: make([]itemBatchOperation, maxItemBatchOps+1), err: errValidation, failedAt: -1},
+		{ops: []itemBatchOperation{{Op: itemBatchCreate, Item: &tea}, {Op: "rename"}}, err: errValidation, failedAt: 1},
+		{ops: []itemBatchOperation{{Op: itemBatchCreate}}, err: errValidation, failedAt: 0},
+		{ops: []itemBatchOperation{{Op: itemBatchDelete}}, err: errValidation, failedAt: 0},
+		{
+			ops: []itemBatchOperation{{Op: itemBatchCreate, Item: &writeItemParams{Name: "T", Tags: []string{}}}},
+			err: errValidation, failedAt: 0,
+		},
+		{
+			ops: []itemBatchOperation{{Op: itemBatchCreate, Item: &tea}, {Op: itemBatchUpdate, ID: "missing", Item: &tea}},
+			err: errItemNotFound, failedAt: 1,
+		},
+	}
+
+	for _, s := range scenarios {
+		repo := newMemoryRepository()
+
+		results, err := batchItems(ctx, repo, validate, s.ops)
+		if !errors.Is(err, s.err) {
+			t.Errorf("Expected %v, got %v", s.err, err)
+		}
+
+		if s.failedAt >= 0 && (len(results) != len(s.ops) || results[s.failedAt].Error == "") {
+			t.Errorf("Expected operation %d to fail, got %+v", s.failedAt, results)
+		}
+
+		for _, res := range results {
+			if res.Applied {
+				t.Errorf("Operation of a failed batch was applied: %+v", res)
+			}
+		}
+
+		if items := contractGetItemsByName(t, repo, itemsQuery{}); len(items) != 0 {
+			t.Errorf("Failed batch created %+v", items)
+		}
+	}
+}
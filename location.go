@@ -10,9 +10,11 @@ import (
 )
 
 type location struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Items []item `json:"items,omitempty"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Version is incremented by every write of the location
+	Version int    `json:"version"`
+	Items   []item `json:"items,omitempty"`
 }
 
 func (location) GetNameConstraints() string {
@@ -40,9 +42,20 @@ func fillLocations(locations []location, items []item) ([]location, []item) {
 	return locations, remainingItems
 }
 
+// locationsQuery selects the locations returned by repository.GetLocations, which are sorted by name and ID.
+type locationsQuery struct {
+	IDs *[]string
+	// Limit caps the number of returned locations, zero means no limit
+	Limit int
+	// After skips the locations up to and including the cursor
+	After *locationsCursor
+}
+
 func getLocationsCommon(
-	ctx context.Context, repo repository, ids *[]string, tags *[]string,
+	ctx context.Context, repo repository, ids *[]string, filter itemsFilter,
 ) ([]location, []item, error) {
+	filter.LocationIDs = ids
+
 	wg := new(sync.WaitGroup)
 	locs := []location{}
 	locsErr := error(nil)
@@ -52,12 +65,12 @@ func getLocationsCommon(
 	wg.Add(2) //nolint:mnd
 
 	go func() {
-		locs, locsErr = repo.GetLocations(ctx, ids)
+		locs, locsErr = repo.GetLocations(ctx, locationsQuery{IDs: ids})
 
 		wg.Done()
 	}()
 	go func() {
-		items, itemsErr = repo.GetItems(ctx, tags, ids)
+		items, itemsErr = repo.GetItems(ctx, filter.itemsQuery)
 
 		wg.Done()
 	}()
@@ -72,19 +85,128 @@ func getLocationsCommon(
 		return nil, nil, fmt.Errorf("get items: %w", itemsErr)
 	}
 
-	filledLocs, remainingItems := fillLocations(locs, items)
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fillItemsExpiry(items, rules)
+
+	filledLocs, remainingItems := fillLocations(locs, applyItemsFilter(items, filter))
 
 	return filledLocs, remainingItems, nil
 }
 
-func getLocations(ctx context.Context, repo repository, tags *[]string) ([]location, []item, error) {
-	return getLocationsCommon(ctx, repo, nil, tags)
+// fillLocationsPage queries the items of a page of locations,
+// the items without a location are only returned with the first page.
+func fillLocationsPage(
+	ctx context.Context, repo repository, locs []location, firstPage bool, filter itemsFilter,
+) ([]location, []item, error) {
+	ids := []string{}
+	for _, l := range locs {
+		ids = append(ids, l.ID)
+	}
+
+	items := []item{}
+
+	if len(ids) > 0 {
+		query := filter.itemsQuery
+		query.LocationIDs = &ids
+
+		locItems, err := repo.GetItems(ctx, query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get items: %w", err)
+		}
+
+		items = append(items, locItems...)
+	}
+
+	if firstPage {
+		query := filter.itemsQuery
+		query.LocationIDs = nil
+		query.Unassigned = true
+
+		unassignedItems, err := repo.GetItems(ctx, query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get items: %w", err)
+		}
+
+		items = append(items, unassignedItems...)
+	}
+
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fillItemsExpiry(items, rules)
+
+	filledLocs, remainingItems := fillLocations(locs, applyItemsFilter(items, filter))
+
+	return filledLocs, remainingItems, nil
+}
+
+func getLocations(
+	ctx context.Context, repo repository, validate *validator.Validate, filter itemsFilter, page pageParams,
+) ([]location, []item, string, error) {
+	if err := validate.Struct(filter); err != nil {
+		return nil, nil, "", fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	if err := validate.Struct(page); err != nil {
+		return nil, nil, "", fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	after, err := decodeCursor[locationsCursor](page.Cursor)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if page.Limit == 0 && after == nil {
+		locs, remainingItems, err := getLocationsCommon(ctx, repo, nil, filter)
+
+		return locs, remainingItems, "", err
+	}
+
+	query := locationsQuery{After: after}
+	if page.Limit > 0 {
+		query.Limit = page.Limit + 1
+	}
+
+	locs, err := repo.GetLocations(ctx, query)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("get locations: %w", err)
+	}
+
+	nextCursor := ""
+
+	if page.Limit > 0 && len(locs) > page.Limit {
+		locs = locs[:page.Limit]
+		last := locs[len(locs)-1]
+
+		if nextCursor, err = encodeCursor(locationsCursor{ID: last.ID, Name: last.Name}); err != nil {
+			return nil, nil, "", err
+		}
+	}
+
+	filledLocs, remainingItems, err := fillLocationsPage(ctx, repo, locs, after == nil, filter)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return filledLocs, remainingItems, nextCursor, nil
 }
 
 var errLocationNotFound = errors.New("location not found")
 
-func getLocation(ctx context.Context, repo repository, id string, tags *[]string) (location, error) {
-	locations, _, err := getLocationsCommon(ctx, repo, getPtr([]string{id}), tags)
+func getLocation(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, filter itemsFilter,
+) (location, error) {
+	if err := validate.Struct(filter); err != nil {
+		return location{}, fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	locations, _, err := getLocationsCommon(ctx, repo, getPtr([]string{id}), filter)
 	if err != nil {
 		return location{}, err
 	}
@@ -96,32 +218,47 @@ func getLocation(ctx context.Context, repo repository, id string, tags *[]string
 	return locations[0], nil
 }
 
-func createLocation(ctx context.Context, repo repository, validate *validator.Validate, name string) error {
+func createLocation(
+	ctx context.Context, repo repository, validate *validator.Validate, name string,
+) (location, error) {
 	if err := validate.Var(name, location{}.GetNameConstraints()); err != nil {
-		return fmt.Errorf("%w: %w", errValidation, err)
+		return location{}, fmt.Errorf("%w: %w", errValidation, err)
 	}
 
-	if err := repo.CreateLocation(ctx, name); err != nil {
-		return fmt.Errorf("create location: %w", err)
+	l, err := repo.CreateLocation(ctx, name)
+	if err != nil {
+		return location{}, fmt.Errorf("create location: %w", err)
 	}
 
-	return nil
+	return l, nil
 }
 
-func updateLocation(ctx context.Context, repo repository, validate *validator.Validate, id string, name string) error {
+// updateLocation renames the location, returning it without its items and with its new version.
+func updateLocation(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, name string, version *int,
+) (location, error) {
 	if err := validate.Var(name, location{}.GetNameConstraints()); err != nil {
-		return fmt.Errorf("%w: %w", errValidation, err)
+		return location{}, fmt.Errorf("%w: %w", errValidation, err)
 	}
 
-	if err := repo.UpdateLocation(ctx, id, name); err != nil {
-		return fmt.Errorf("update location: %w", err)
+	if err := repo.UpdateLocation(ctx, id, name, version); err != nil {
+		return location{}, fmt.Errorf("update location: %w", err)
 	}
 
-	return nil
+	locations, err := repo.GetLocations(ctx, locationsQuery{IDs: &[]string{id}})
+	if err != nil {
+		return location{}, fmt.Errorf("get locations: %w", err)
+	}
+
+	if len(locations) == 0 {
+		return location{}, errLocationNotFound
+	}
+
+	return locations[0], nil
 }
 
-func deleteLocation(ctx context.Context, repo repository, id string) error {
-	if err := repo.DeleteLocation(ctx, id); err != nil {
+func deleteLocation(ctx context.Context, repo repository, id string, version *int) error {
+	if err := repo.DeleteLocation(ctx, id, version); err != nil {
 		return fmt.Errorf("delete location: %w", err)
 	}
 
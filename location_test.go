@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -14,11 +15,15 @@ import (
 func TestGetLocationsParams(t *testing.T) {
 	t.Parallel()
 
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
 	tags := getPtr([]string{"fruit", "sweet"})
 
 	mockRepo := &mockRepository{}
 
-	_, _, err := getLocations(context.Background(), mockRepo, tags)
+	_, _, _, err := getLocations(context.Background(), mockRepo, validate, itemsFilter{
+		itemsQuery: itemsQuery{Tags: tags, OpenedOnly: true},
+	}, pageParams{})
 	if err != nil {
 		t.Errorf("Got error: %+v", err)
 	}
@@ -31,16 +36,22 @@ func TestGetLocationsParams(t *testing.T) {
 		t.Errorf("Called GetItems %d times instead of once", mockRepo.GetLocationsCalls)
 	}
 
-	if mockRepo.GetItemsTags == nil {
+	if mockRepo.GetItemsQuery.Tags == nil {
 		t.Error("Did not call GetItems with tags")
-	} else if mockRepo.GetItemsTags != tags {
-		t.Errorf("Called GetItems with %v tags instead of %v tags", *mockRepo.GetItemsTags, *tags)
+	} else if mockRepo.GetItemsQuery.Tags != tags {
+		t.Errorf("Called GetItems with %v tags instead of %v tags", *mockRepo.GetItemsQuery.Tags, *tags)
+	}
+
+	if !mockRepo.GetItemsQuery.OpenedOnly {
+		t.Error("Did not call GetItems with opened only")
 	}
 }
 
 func TestGetLocationsRes(t *testing.T) {
 	t.Parallel()
 
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
 	locations := []location{
 		{ID: "pantry", Name: "Pantry"},
 		{ID: "fridge", Name: "Fridge"},
@@ -48,12 +59,14 @@ func TestGetLocationsRes(t *testing.T) {
 	items := []item{
 		{Name: "Potato", LocationID: nil},
 		{Name: "Cheese", LocationID: getPtr("fridge")},
-		{Name: "Milk", LocationID: getPtr("fridge")},
+		{Name: "Milk", LocationID: getPtr("fridge"), ExpiresAt: getPtr(time.Now().Add(-time.Hour * 48))},
 	}
 
 	mockRepo := &mockRepository{GetLocationsRes: locations, GetItemsRes: items}
 
-	filledLocations, remainingItems, err := getLocations(context.Background(), mockRepo, nil)
+	filledLocations, remainingItems, _, err := getLocations(
+		context.Background(), mockRepo, validate, itemsFilter{}, pageParams{},
+	)
 	if err != nil {
 		t.Errorf("Got error: %+v", err)
 	}
@@ -86,12 +99,22 @@ func TestGetLocationsRes(t *testing.T) {
 		)
 	} else if remainingItems[0].Name != "Potato" {
 		t.Errorf("Remaining items does not contain Potato, instead contains %+v", remainingItems)
+	} else if remainingItems[0].Status != itemStatusUnknown {
+		t.Errorf("Potato got status %s instead of %s", remainingItems[0].Status, itemStatusUnknown)
+	}
+
+	if len(filledLocations[1].Items) == 2 {
+		if milk := filledLocations[1].Items[1]; milk.Status != itemStatusExpired || milk.EffectiveExpiresAt == nil {
+			t.Errorf("Milk got status %s expiring at %v instead of being expired", milk.Status, milk.EffectiveExpiresAt)
+		}
 	}
 }
 
 func TestGetLocationsErrs(t *testing.T) {
 	t.Parallel()
 
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
 	errScenarios := []struct {
 		repo   *mockRepository
 		errStr string
@@ -107,24 +130,85 @@ func TestGetLocationsErrs(t *testing.T) {
 	}
 
 	for _, s := range errScenarios {
-		_, _, err := getLocations(context.Background(), s.repo, nil)
+		_, _, _, err := getLocations(context.Background(), s.repo, validate, itemsFilter{}, pageParams{})
 		if err == nil || !strings.Contains(err.Error(), s.errStr) {
 			t.Errorf(`Expected "%s" to contain "%s"`, err, s.errStr)
 		}
 	}
 }
 
+func TestGetLocationsPagination(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	repo := newMemoryRepository()
+
+	for _, name := range []string{"Pantry", "Fridge", "Cellar"} {
+		if _, err := repo.CreateLocation(ctx, name); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	locs, _, _, err := getLocations(ctx, repo, validate, itemsFilter{}, pageParams{})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	for _, params := range []writeItemParams{
+		{Name: "Salt", Tags: []string{}, BoughtAt: time.Now()},
+		{Name: "Potatoes", Tags: []string{}, BoughtAt: time.Now(), LocationID: &locs[0].ID},
+		{Name: "Pasta", Tags: []string{}, BoughtAt: time.Now(), LocationID: &locs[2].ID},
+	} {
+		if _, err := repo.CreateItem(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	firstPage, remainingItems, nextCursor, err := getLocations(ctx, repo, validate, itemsFilter{}, pageParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if len(firstPage) != 2 || firstPage[0].Name != "Cellar" || len(firstPage[0].Items) != 1 || nextCursor == "" {
+		t.Errorf("Got first page %+v with cursor %q", firstPage, nextCursor)
+	}
+
+	if len(remainingItems) != 1 || remainingItems[0].Name != "Salt" {
+		t.Errorf("Expected Salt to be returned with the first page, got %+v", remainingItems)
+	}
+
+	secondPage, remainingItems, nextCursor, err := getLocations(
+		ctx, repo, validate, itemsFilter{}, pageParams{Limit: 2, Cursor: nextCursor},
+	)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if len(secondPage) != 1 || secondPage[0].Name != "Pantry" || len(secondPage[0].Items) != 1 || nextCursor != "" {
+		t.Errorf("Got second page %+v with cursor %q", secondPage, nextCursor)
+	}
+
+	if len(remainingItems) != 0 {
+		t.Errorf("Expected no remaining items with the second page, got %+v", remainingItems)
+	}
+}
+
 //nolint:cyclop
 func TestGetLocation(t *testing.T) {
 	t.Parallel()
 
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
 	l := location{ID: "freezer", Name: "Freezer"}
 	mockRepo := &mockRepository{
 		GetLocationsRes: []location{l},
 	}
 	tags := getPtr([]string{"microwave", "oven"})
 
-	res, err := getLocation(context.Background(), mockRepo, l.ID, tags)
+	res, err := getLocation(context.Background(), mockRepo, validate, l.ID, itemsFilter{
+		itemsQuery: itemsQuery{Tags: tags},
+	})
 	if err != nil {
 		t.Errorf("Got error: %s", err)
 	}
@@ -137,32 +221,34 @@ func TestGetLocation(t *testing.T) {
 		t.Errorf("Called GetLocations %d times instead of once", mockRepo.GetLocationsCalls)
 	}
 
-	if mockRepo.GetLocationsIDs == nil {
+	if mockRepo.GetLocationsQuery.IDs == nil {
 		t.Error("Did not call GetLocations with loc IDs")
-	} else if !reflect.DeepEqual(*mockRepo.GetLocationsIDs, []string{l.ID}) {
-		t.Errorf("Called GetLocations with ids %+v instead of %s", *mockRepo.GetLocationsIDs, l.ID)
+	} else if !reflect.DeepEqual(*mockRepo.GetLocationsQuery.IDs, []string{l.ID}) {
+		t.Errorf("Called GetLocations with ids %+v instead of %s", *mockRepo.GetLocationsQuery.IDs, l.ID)
 	}
 
 	if mockRepo.GetItemsCalls != 1 {
 		t.Errorf("Called GetItems %d times instead of once", mockRepo.GetItemsCalls)
 	}
 
-	if mockRepo.GetItemsTags == nil {
+	if mockRepo.GetItemsQuery.Tags == nil {
 		t.Error("Did not call GetItems with tags")
-	} else if mockRepo.GetItemsTags != tags {
-		t.Errorf("Called GetItems with %v tags instead of %v tags", *mockRepo.GetItemsTags, *tags)
+	} else if mockRepo.GetItemsQuery.Tags != tags {
+		t.Errorf("Called GetItems with %v tags instead of %v tags", *mockRepo.GetItemsQuery.Tags, *tags)
 	}
 
-	if mockRepo.GetItemsLocationIDs == nil {
+	if mockRepo.GetItemsQuery.LocationIDs == nil {
 		t.Error("Did not call GetItems with loc IDs")
-	} else if !reflect.DeepEqual(*mockRepo.GetItemsLocationIDs, []string{l.ID}) {
-		t.Errorf("Called GetItems with %v loc IDs instead of %s", *mockRepo.GetItemsLocationIDs, l.ID)
+	} else if !reflect.DeepEqual(*mockRepo.GetItemsQuery.LocationIDs, []string{l.ID}) {
+		t.Errorf("Called GetItems with %v loc IDs instead of %s", *mockRepo.GetItemsQuery.LocationIDs, l.ID)
 	}
 }
 
 func TestLocationErrs(t *testing.T) {
 	t.Parallel()
 
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
 	errScenarios := []struct {
 		repo   *mockRepository
 		errStr string
@@ -178,7 +264,7 @@ func TestLocationErrs(t *testing.T) {
 	}
 
 	for _, s := range errScenarios {
-		_, err := getLocation(context.Background(), s.repo, uuid.NewString(), nil)
+		_, err := getLocation(context.Background(), s.repo, validate, uuid.NewString(), itemsFilter{})
 		if !strings.Contains(err.Error(), s.errStr) {
 			t.Errorf(`Expected "%s" to contain "%s"`, err, s.errStr)
 		}
@@ -200,11 +286,15 @@ func TestCreateLocation(t *testing.T) {
 	for _, cn := range correctNames {
 		repo := &mockRepository{}
 
-		err := createLocation(context.Background(), repo, validate, cn)
+		l, err := createLocation(context.Background(), repo, validate, cn)
 		if err != nil {
 			t.Errorf("Returned unexpected error for %s: %+v", cn, err)
 		}
 
+		if l.ID != "created" || l.Name != cn {
+			t.Errorf("Returned %+v instead of the created location", l)
+		}
+
 		if repo.CreateLocationCalls != 1 {
 			t.Errorf(
 				`Called repo wrong number of times: %d instead of 1 on "%s"`,
@@ -227,7 +317,7 @@ func TestCreateLocation(t *testing.T) {
 	for _, in := range incorrectNames {
 		repo := &mockRepository{}
 
-		err := createLocation(context.Background(), repo, validate, in)
+		_, err := createLocation(context.Background(), repo, validate, in)
 		if err == nil {
 			t.Errorf("Did not return error on %s", in)
 		}
@@ -250,14 +340,18 @@ func TestUpdateLocation(t *testing.T) {
 	}
 
 	for _, cn := range correctNames {
-		repo := &mockRepository{}
 		id := uuid.New().String()
+		repo := &mockRepository{GetLocationsRes: []location{{ID: id, Name: cn, Version: 1}}}
 
-		err := updateLocation(context.Background(), repo, validate, id, cn)
+		loc, err := updateLocation(context.Background(), repo, validate, id, cn, nil)
 		if err != nil {
 			t.Errorf("Returned unexpected error for %s: %+v", cn, err)
 		}
 
+		if loc.Version != 1 || !reflect.DeepEqual(repo.GetLocationsQuery.IDs, &[]string{id}) {
+			t.Errorf("Expected the stored location with its new version, got %+v", loc)
+		}
+
 		if repo.UpdateLocationCalls != 1 {
 			t.Errorf(
 				`Called repo wrong number of times: %d instead of 1 on "%s"`,
@@ -284,7 +378,7 @@ func TestUpdateLocation(t *testing.T) {
 	for _, in := range incorrectNames {
 		repo := &mockRepository{}
 
-		err := updateLocation(context.Background(), repo, validate, "id", in)
+		_, err := updateLocation(context.Background(), repo, validate, "id", in, nil)
 		if err == nil {
 			t.Errorf("Did not return error on %s", in)
 		}
@@ -303,7 +397,7 @@ func TestDeleteLocation(t *testing.T) {
 	for _, id := range ids {
 		repo := &mockRepository{}
 
-		err := deleteLocation(context.Background(), repo, id)
+		err := deleteLocation(context.Background(), repo, id, nil)
 		if err != nil {
 			t.Errorf("Returned unexpected error for %s: %+v", id, err)
 		}
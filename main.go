@@ -21,7 +21,11 @@ const (
 	otelFailExitCode = 2
 )
 
-var errOtelConfigFail = errors.New("failed configuring otel")
+var (
+	errOtelConfigFail = errors.New("failed configuring otel")
+	errUnknownStorage = errors.New("unknown storage")
+	errUnknownAuth    = errors.New("unknown authentication provider")
+)
 
 func main() {
 	ctx := context.Background()
@@ -60,19 +64,26 @@ func start(ctx context.Context) error {
 func initAPI(ctx context.Context) error {
 	validate := getValidate()
 
-	firestoreRepo, err := getFirestoreRepository(ctx)
+	repo, closeRepo, err := getRepository(ctx)
 	if err != nil {
 		return err
 	}
 
-	defer firestoreRepo.client.Close() //nolint:errcheck
+	defer closeRepo() //nolint:errcheck
 
-	auth, err := getFirebaseAuthentication(ctx)
-	if err != nil {
-		return err
+	var auth authentication
+
+	if !isAuthDisabled() {
+		idTokenAuth, err := getIDTokenAuthentication(ctx, repo)
+		if err != nil {
+			return err
+		}
+
+		// the personal access tokens are told apart by their prefix, the other tokens are checked by the provider
+		auth = chainedAuthentication{accessTokenAuthentication{repo: repo}, idTokenAuth}
 	}
 
-	srv := getServer(getRouter(firestoreRepo, validate, auth))
+	srv := getServer(getRouter(repo, validate, auth))
 	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
@@ -83,20 +94,18 @@ func initAPI(ctx context.Context) error {
 func initNotifyJob(ctx context.Context) error {
 	httpClient := &http.Client{Timeout: httpTimeout}
 
-	firestoreRepo, err := getFirestoreRepository(ctx)
+	repo, closeRepo, err := getRepository(ctx)
 	if err != nil {
 		return err
 	}
 
-	defer firestoreRepo.client.Close() //nolint:errcheck
+	defer closeRepo() //nolint:errcheck
 
-	auth, err := getFirebaseAuthentication(ctx)
+	authRepo, err := getAuthenticationRepository(ctx, repo)
 	if err != nil {
 		return err
 	}
 
-	authRepo := firebaseAuthenticationRepository{client: auth.client}
-
 	var n notifier
 
 	if baseURL := os.Getenv("INFOBIP_API_BASE_URL"); baseURL != "" {
@@ -117,13 +126,105 @@ func initNotifyJob(ctx context.Context) error {
 		n = terminalNotifier{}
 	}
 
-	if err := notifyAboutItems(ctx, firestoreRepo, n, authRepo); err != nil {
+	if err := notifyHouseholds(ctx, repo, n, authRepo); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// getRepository returns the repository selected by the STORAGE env var, along with a function closing it.
+func getRepository(ctx context.Context) (repository, func() error, error) {
+	switch storage := strings.ToLower(os.Getenv("STORAGE")); storage {
+	case "", "firestore":
+		firestoreRepo, err := getFirestoreRepository(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return firestoreRepo, firestoreRepo.client.Close, nil
+	case "sqlite":
+		sqliteRepo, err := getSQLiteRepository(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return sqliteRepo, sqliteRepo.db.Close, nil
+	case "postgres":
+		postgresRepo, err := getPostgresRepository(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return postgresRepo, postgresRepo.db.Close, nil
+	case "memory":
+		memoryRepo, err := getMemoryRepository()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return memoryRepo, func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", errUnknownStorage, storage)
+	}
+}
+
+// isAuthDisabled returns whether AUTH_DISABLED disables authentication, FIREBASE_AUTH_DISABLED is still read when it
+// is not set.
+func isAuthDisabled() bool {
+	if value, ok := os.LookupEnv("AUTH_DISABLED"); ok {
+		return value == "true"
+	}
+
+	if os.Getenv("FIREBASE_AUTH_DISABLED") == "true" {
+		slog.Warn("FIREBASE_AUTH_DISABLED is deprecated, use AUTH_DISABLED instead.")
+
+		return true
+	}
+
+	return false
+}
+
+// getIDTokenAuthentication returns the authentication of the ID tokens of the provider selected by AUTH_PROVIDER.
+func getIDTokenAuthentication(ctx context.Context, repo repository) (authentication, error) {
+	switch provider := strings.ToLower(os.Getenv("AUTH_PROVIDER")); provider {
+	case "", "firebase":
+		firebaseAuth, err := getFirebaseAuthentication(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return firebaseAuth, nil
+	case "oidc":
+		oidcAuth, err := getOIDCAuthentication(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		return oidcAuth, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownAuth, provider)
+	}
+}
+
+// getAuthenticationRepository returns the lookup of the email addresses of the users for the AUTH_PROVIDER env var.
+func getAuthenticationRepository(ctx context.Context, repo repository) (authenticationRepository, error) {
+	switch provider := strings.ToLower(os.Getenv("AUTH_PROVIDER")); provider {
+	case "", "firebase":
+		firebaseAuth, err := getFirebaseAuthentication(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return firebaseAuthenticationRepository{client: firebaseAuth.client}, nil
+	case "oidc":
+		// the issuer is not asked, the email addresses are stored when the users authenticate
+		return oidcAuthenticationRepository{repo: repo}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownAuth, provider)
+	}
+}
+
 func getValidate() *validator.Validate {
 	return validator.New(validator.WithRequiredStructEnabled())
 }
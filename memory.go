@@ -0,0 +1,905 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memorySeed is the format of the JSON file the memory repository can be seeded with.
+type memorySeed struct {
+	Locations []location `json:"locations"`
+	Items     []item     `json:"items"`
+}
+
+func getMemoryRepository() (*memoryRepository, error) {
+	repo := newMemoryRepository()
+
+	if path := os.Getenv("MEMORY_SEED_PATH"); path != "" {
+		if err := repo.loadSeedFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		households: map[string]household{defaultHouseholdID: {ID: defaultHouseholdID, Name: defaultHouseholdName}},
+		members:    map[string]memoryHouseholdMember{},
+		invites:    map[string]householdInvite{},
+		tokens:     map[string]accessToken{},
+		emails:     map[string]string{},
+		data:       map[string]*memoryHouseholdData{},
+	}
+}
+
+// memoryRepository keeps all the data in memory, it is meant for local development and demos.
+type memoryRepository struct {
+	mu         sync.RWMutex
+	households map[string]household
+	// members maps the users to their households
+	members map[string]memoryHouseholdMember
+	invites map[string]householdInvite
+	tokens  map[string]accessToken
+	// emails maps the users to their email addresses
+	emails map[string]string
+	data   map[string]*memoryHouseholdData
+}
+
+type memoryHouseholdMember struct {
+	householdID string
+	role        householdRole
+}
+
+// memoryHouseholdData is the data of a single household.
+type memoryHouseholdData struct {
+	locations    map[string]location
+	items        map[string]item
+	shoppingList map[string]shoppingListItem
+	expiryRules  map[string]expiryRule
+}
+
+func newMemoryHouseholdData() *memoryHouseholdData {
+	return &memoryHouseholdData{
+		locations:    map[string]location{},
+		items:        map[string]item{},
+		shoppingList: map[string]shoppingListItem{},
+		expiryRules:  map[string]expiryRule{},
+	}
+}
+
+// readData returns the data of the household of the context, which is empty if nothing was written to it yet.
+func (repo *memoryRepository) readData(ctx context.Context) *memoryHouseholdData {
+	if data, ok := repo.data[getHouseholdID(ctx)]; ok {
+		return data
+	}
+
+	return newMemoryHouseholdData()
+}
+
+// writeData returns the data of the household of the context, creating it on the first write.
+func (repo *memoryRepository) writeData(ctx context.Context) *memoryHouseholdData {
+	householdID := getHouseholdID(ctx)
+	if _, ok := repo.data[householdID]; !ok {
+		repo.data[householdID] = newMemoryHouseholdData()
+	}
+
+	return repo.data[householdID]
+}
+
+func (repo *memoryRepository) loadSeedFile(path string) error {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to read memory seed file: %w", err)
+	}
+
+	var seed memorySeed
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return fmt.Errorf("failed to parse memory seed file: %w", err)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	// the seed is the data of the default household, which is the one used without authentication
+	defaultData := repo.writeData(context.Background())
+
+	for _, l := range seed.Locations {
+		if l.ID == "" {
+			l.ID = uuid.NewString()
+		}
+
+		defaultData.locations[l.ID] = location{ID: l.ID, Name: l.Name, Version: max(l.Version, 1)}
+	}
+
+	for _, i := range seed.Items {
+		if i.ID == "" {
+			i.ID = uuid.NewString()
+		}
+
+		i.Location = nil
+		i.Version = max(i.Version, 1)
+		defaultData.items[i.ID] = cloneItem(i)
+	}
+
+	return nil
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+
+	return getPtr(*p)
+}
+
+// cloneItem deep copies the item so that callers cannot modify the stored data.
+func cloneItem(i item) item {
+	i.Type = clonePtr(i.Type)
+	i.Tags = append([]string{}, i.Tags...)
+	i.Price = clonePtr(i.Price)
+	i.OpenedAt = clonePtr(i.OpenedAt)
+	i.ExpiresAt = clonePtr(i.ExpiresAt)
+	i.Lifespan = clonePtr(i.Lifespan)
+	i.LocationID = clonePtr(i.LocationID)
+	i.Quantity = clonePtr(i.Quantity)
+	i.InitialQuantity = clonePtr(i.InitialQuantity)
+	i.Unit = clonePtr(i.Unit)
+	i.ArchivedAt = clonePtr(i.ArchivedAt)
+	i.Outcome = clonePtr(i.Outcome)
+	i.FrozenAt = clonePtr(i.FrozenAt)
+	i.ThawedAt = clonePtr(i.ThawedAt)
+
+	return i
+}
+
+func itemFromParams(id string, params writeItemParams, version int) item {
+	return cloneItem(item{
+		ID:              id,
+		Version:         version,
+		Name:            params.Name,
+		Type:            params.Type,
+		Tags:            params.Tags,
+		Price:           params.Price,
+		BoughtAt:        params.BoughtAt,
+		OpenedAt:        params.OpenedAt,
+		ExpiresAt:       params.ExpiresAt,
+		Lifespan:        params.Lifespan,
+		LocationID:      params.LocationID,
+		Quantity:        params.Quantity,
+		InitialQuantity: params.InitialQuantity,
+		Unit:            params.Unit,
+		ArchivedAt:      params.ArchivedAt,
+		Outcome:         params.Outcome,
+		FrozenAt:        params.FrozenAt,
+		ThawedAt:        params.ThawedAt,
+	})
+}
+
+// memoryCheckVersion returns errVersionMismatch if the version is given and differs from the stored one.
+func memoryCheckVersion(stored int, version *int) error {
+	if version != nil && *version != stored {
+		return errVersionMismatch
+	}
+
+	return nil
+}
+
+func memoryCompareLocations(a, b location) int {
+	return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+}
+
+func (repo *memoryRepository) GetLocations(ctx context.Context, query locationsQuery) ([]location, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data := repo.readData(ctx)
+
+	locations := []location{}
+
+	for _, l := range data.locations {
+		if query.IDs != nil && !slices.Contains(*query.IDs, l.ID) {
+			continue
+		}
+
+		if query.After != nil && memoryCompareLocations(l, location{ID: query.After.ID, Name: query.After.Name}) <= 0 {
+			continue
+		}
+
+		locations = append(locations, l)
+	}
+
+	slices.SortFunc(locations, memoryCompareLocations)
+
+	return limitEntries(locations, query.Limit), nil
+}
+
+func (repo *memoryRepository) CreateLocation(ctx context.Context, name string) (location, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	id := uuid.NewString()
+	data.locations[id] = location{ID: id, Name: name, Version: 1}
+
+	return data.locations[id], nil
+}
+
+func (repo *memoryRepository) UpdateLocation(ctx context.Context, id string, name string, version *int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	l, ok := data.locations[id]
+	if !ok {
+		return errLocationNotFound
+	}
+
+	if err := memoryCheckVersion(l.Version, version); err != nil {
+		return err
+	}
+
+	l.Name = name
+	l.Version++
+	data.locations[id] = l
+
+	return nil
+}
+
+func (repo *memoryRepository) DeleteLocation(ctx context.Context, id string, version *int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	if version != nil {
+		l, ok := data.locations[id]
+		if !ok {
+			return errVersionMismatch
+		}
+
+		if err := memoryCheckVersion(l.Version, version); err != nil {
+			return err
+		}
+	}
+
+	for itemID, i := range data.items {
+		if i.LocationID != nil && *i.LocationID == id {
+			i.LocationID = nil
+			i.Version++
+			data.items[itemID] = i
+		}
+	}
+
+	delete(data.locations, id)
+
+	return nil
+}
+
+func (repo *memoryRepository) GetItems(ctx context.Context, query itemsQuery) ([]item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data := repo.readData(ctx)
+
+	sort := query.Sort.stored()
+
+	items := []item{}
+
+	for _, i := range data.items {
+		if query.Tags != nil &&
+			!slices.ContainsFunc(i.Tags, func(tag string) bool { return slices.Contains(*query.Tags, tag) }) {
+			continue
+		}
+
+		if query.LocationIDs != nil && (i.LocationID == nil || !slices.Contains(*query.LocationIDs, *i.LocationID)) {
+			continue
+		}
+
+		if query.Unassigned && i.LocationID != nil {
+			continue
+		}
+
+		if query.OpenedOnly && i.OpenedAt == nil {
+			continue
+		}
+
+		if query.Archived != (i.ArchivedAt != nil) {
+			continue
+		}
+
+		if query.Outcome != nil && (i.Outcome == nil || *i.Outcome != *query.Outcome) {
+			continue
+		}
+
+		if (query.BoughtFrom != nil && i.BoughtAt.Before(*query.BoughtFrom)) ||
+			(query.BoughtBefore != nil && !i.BoughtAt.Before(*query.BoughtBefore)) {
+			continue
+		}
+
+		if query.After != nil && compareItems(i, query.After.item(), sort) <= 0 {
+			continue
+		}
+
+		items = append(items, cloneItem(i))
+	}
+
+	slices.SortFunc(items, func(a, b item) int { return compareItems(a, b, sort) })
+
+	return limitEntries(items, query.Limit), nil
+}
+
+func (repo *memoryRepository) GetItem(ctx context.Context, id string) (item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data := repo.readData(ctx)
+
+	i, ok := data.items[id]
+	if !ok {
+		return item{}, errItemNotFound
+	}
+
+	return cloneItem(i), nil
+}
+
+// memoryWriteItem applies the write to the items, returning the ID of the written item.
+func memoryWriteItem(items map[string]item, w itemWrite) (string, error) {
+	if w.Op == itemBatchCreate {
+		id := uuid.NewString()
+		items[id] = itemFromParams(id, w.Params, 1)
+
+		return id, nil
+	}
+
+	i, ok := items[w.ID]
+
+	if w.Op == itemBatchDelete {
+		// deleting an item that does not exist does not match the version either
+		if w.Version != nil && (!ok || *w.Version != i.Version) {
+			return "", errVersionMismatch
+		}
+
+		delete(items, w.ID)
+
+		return w.ID, nil
+	}
+
+	if !ok {
+		return "", errItemNotFound
+	}
+
+	if err := memoryCheckVersion(i.Version, w.Version); err != nil {
+		return "", err
+	}
+
+	switch w.Op {
+	case itemBatchUpdate:
+		i = itemFromParams(w.ID, w.Params, i.Version)
+	case itemBatchMove:
+		i.LocationID = clonePtr(w.LocationID)
+	case itemBatchCreate, itemBatchDelete:
+	}
+
+	i.Version++
+	items[w.ID] = i
+
+	return w.ID, nil
+}
+
+func (repo *memoryRepository) writeItem(ctx context.Context, w itemWrite) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	_, err := memoryWriteItem(data.items, w)
+
+	return err
+}
+
+func (repo *memoryRepository) CreateItem(ctx context.Context, params writeItemParams) (item, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	id := uuid.NewString()
+	data.items[id] = itemFromParams(id, params, 1)
+
+	return cloneItem(data.items[id]), nil
+}
+
+func (repo *memoryRepository) UpdateItem(ctx context.Context, id string, params writeItemParams, version *int) error {
+	return repo.writeItem(ctx, itemWrite{Op: itemBatchUpdate, ID: id, Params: params, Version: version})
+}
+
+func (repo *memoryRepository) UpdateItemLocation(
+	ctx context.Context, id string, locationID *string, version *int,
+) error {
+	return repo.writeItem(ctx, itemWrite{Op: itemBatchMove, ID: id, LocationID: locationID, Version: version})
+}
+
+func (repo *memoryRepository) DeleteItem(ctx context.Context, id string, version *int) error {
+	return repo.writeItem(ctx, itemWrite{Op: itemBatchDelete, ID: id, Version: version})
+}
+
+func (repo *memoryRepository) ApplyItemWrites(ctx context.Context, writes []itemWrite) ([]string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	// the writes are applied to a copy of the items, which replaces them once all of the writes succeed
+	items := maps.Clone(data.items)
+	ids := make([]string, len(writes))
+
+	for idx, w := range writes {
+		id, err := memoryWriteItem(items, w)
+		if err != nil {
+			return nil, itemWriteError{Index: idx, Err: err}
+		}
+
+		ids[idx] = id
+	}
+
+	data.items = items
+
+	return ids, nil
+}
+
+// cloneShoppingListItem deep copies the list entry so that callers cannot modify the stored data.
+func cloneShoppingListItem(e shoppingListItem) shoppingListItem {
+	e.Type = clonePtr(e.Type)
+	e.Tags = append([]string{}, e.Tags...)
+	e.Quantity = clonePtr(e.Quantity)
+	e.Unit = clonePtr(e.Unit)
+	e.LocationID = clonePtr(e.LocationID)
+
+	return e
+}
+
+func shoppingListItemFromParams(id string, params writeShoppingListItemParams) shoppingListItem {
+	return cloneShoppingListItem(shoppingListItem{
+		ID:         id,
+		Name:       params.Name,
+		Type:       params.Type,
+		Tags:       params.Tags,
+		Quantity:   params.Quantity,
+		Unit:       params.Unit,
+		LocationID: params.LocationID,
+	})
+}
+
+func (repo *memoryRepository) GetShoppingListItems(ctx context.Context) ([]shoppingListItem, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data := repo.readData(ctx)
+
+	entries := []shoppingListItem{}
+	for _, e := range data.shoppingList {
+		entries = append(entries, cloneShoppingListItem(e))
+	}
+
+	slices.SortFunc(entries, func(a, b shoppingListItem) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return entries, nil
+}
+
+func (repo *memoryRepository) GetShoppingListItem(ctx context.Context, id string) (shoppingListItem, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data := repo.readData(ctx)
+
+	e, ok := data.shoppingList[id]
+	if !ok {
+		return shoppingListItem{}, errShoppingListItemNotFound
+	}
+
+	return cloneShoppingListItem(e), nil
+}
+
+func (repo *memoryRepository) CreateShoppingListItem(
+	ctx context.Context, params writeShoppingListItemParams,
+) (shoppingListItem, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	id := uuid.NewString()
+	data.shoppingList[id] = shoppingListItemFromParams(id, params)
+
+	return cloneShoppingListItem(data.shoppingList[id]), nil
+}
+
+func (repo *memoryRepository) UpdateShoppingListItem(
+	ctx context.Context, id string, params writeShoppingListItemParams,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	if _, ok := data.shoppingList[id]; !ok {
+		return errShoppingListItemNotFound
+	}
+
+	data.shoppingList[id] = shoppingListItemFromParams(id, params)
+
+	return nil
+}
+
+func (repo *memoryRepository) DeleteShoppingListItem(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	if _, ok := data.shoppingList[id]; !ok {
+		return errShoppingListItemNotFound
+	}
+
+	delete(data.shoppingList, id)
+
+	return nil
+}
+
+func (repo *memoryRepository) BuyShoppingListItem(
+	ctx context.Context, id string, params writeItemParams,
+) (item, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	if _, ok := data.shoppingList[id]; !ok {
+		return item{}, errShoppingListItemNotFound
+	}
+
+	delete(data.shoppingList, id)
+
+	itemID := uuid.NewString()
+	data.items[itemID] = itemFromParams(itemID, params, 1)
+
+	return cloneItem(data.items[itemID]), nil
+}
+
+func cloneExpiryRule(r expiryRule) expiryRule {
+	r.Type = clonePtr(r.Type)
+	r.Tag = clonePtr(r.Tag)
+	r.ShelfLife = clonePtr(r.ShelfLife)
+	r.OpenedLifespan = clonePtr(r.OpenedLifespan)
+	r.FrozenShelfLife = clonePtr(r.FrozenShelfLife)
+	r.ThawedLifespan = clonePtr(r.ThawedLifespan)
+
+	return r
+}
+
+func (repo *memoryRepository) GetExpiryRules(ctx context.Context) ([]expiryRule, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	data := repo.readData(ctx)
+
+	rules := []expiryRule{}
+	for _, r := range data.expiryRules {
+		rules = append(rules, cloneExpiryRule(r))
+	}
+
+	slices.SortFunc(rules, func(a, b expiryRule) int { return cmp.Compare(a.ID, b.ID) })
+
+	return rules, nil
+}
+
+func (repo *memoryRepository) CreateExpiryRule(ctx context.Context, params writeExpiryRuleParams) (expiryRule, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	id := uuid.NewString()
+	data.expiryRules[id] = cloneExpiryRule(expiryRuleFromParams(id, params))
+
+	return cloneExpiryRule(data.expiryRules[id]), nil
+}
+
+func (repo *memoryRepository) UpdateExpiryRule(ctx context.Context, id string, params writeExpiryRuleParams) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	if _, ok := data.expiryRules[id]; !ok {
+		return errExpiryRuleNotFound
+	}
+
+	data.expiryRules[id] = cloneExpiryRule(expiryRuleFromParams(id, params))
+
+	return nil
+}
+
+func (repo *memoryRepository) DeleteExpiryRule(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data := repo.writeData(ctx)
+
+	if _, ok := data.expiryRules[id]; !ok {
+		return errExpiryRuleNotFound
+	}
+
+	delete(data.expiryRules, id)
+
+	return nil
+}
+
+func (repo *memoryRepository) GetHouseholds(_ context.Context) ([]household, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	households := slices.Collect(maps.Values(repo.households))
+	slices.SortFunc(households, func(a, b household) int { return cmp.Compare(a.ID, b.ID) })
+
+	return households, nil
+}
+
+func (repo *memoryRepository) GetHousehold(_ context.Context, id string) (household, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	h, ok := repo.households[id]
+	if !ok {
+		return household{}, errHouseholdNotFound
+	}
+
+	return h, nil
+}
+
+func (repo *memoryRepository) GetUserHousehold(_ context.Context, uid string) (userHousehold, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	member, ok := repo.members[uid]
+	if !ok {
+		return userHousehold{}, errHouseholdNotFound
+	}
+
+	return userHousehold{household: repo.households[member.householdID], Role: member.role}, nil
+}
+
+func (repo *memoryRepository) CreateHousehold(_ context.Context, name string, uid string) (household, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.members[uid]; ok {
+		return household{}, errHouseholdMemberExists
+	}
+
+	h := household{ID: uuid.NewString(), Name: name}
+	repo.households[h.ID] = h
+	repo.members[uid] = memoryHouseholdMember{householdID: h.ID, role: householdRoleOwner}
+
+	return h, nil
+}
+
+func (repo *memoryRepository) AddHouseholdMember(
+	_ context.Context, householdID string, uid string, role householdRole,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.households[householdID]; !ok {
+		return errHouseholdNotFound
+	}
+
+	if _, ok := repo.members[uid]; ok {
+		return errHouseholdMemberExists
+	}
+
+	repo.members[uid] = memoryHouseholdMember{householdID: householdID, role: role}
+
+	return nil
+}
+
+func (repo *memoryRepository) GetHouseholdMembers(_ context.Context, householdID string) ([]householdMember, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.householdMembers(householdID), nil
+}
+
+// householdMembers requires the caller to hold the lock of the repository.
+func (repo *memoryRepository) householdMembers(householdID string) []householdMember {
+	members := []householdMember{}
+
+	for uid, member := range repo.members {
+		if member.householdID == householdID {
+			members = append(members, householdMember{UID: uid, Role: member.role})
+		}
+	}
+
+	slices.SortFunc(members, func(a, b householdMember) int { return cmp.Compare(a.UID, b.UID) })
+
+	return members
+}
+
+func (repo *memoryRepository) UpdateHouseholdMemberRole(
+	_ context.Context, householdID string, uid string, role householdRole,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	member, ok := repo.members[uid]
+	if !ok || member.householdID != householdID {
+		return errHouseholdMemberNotFound
+	}
+
+	if role != householdRoleOwner {
+		if err := checkOtherOwner(repo.householdMembers(householdID), uid); err != nil {
+			return err
+		}
+	}
+
+	member.role = role
+	repo.members[uid] = member
+
+	return nil
+}
+
+func (repo *memoryRepository) RemoveHouseholdMember(_ context.Context, householdID string, uid string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if member, ok := repo.members[uid]; !ok || member.householdID != householdID {
+		return errHouseholdMemberNotFound
+	}
+
+	if err := checkOtherOwner(repo.householdMembers(householdID), uid); err != nil {
+		return err
+	}
+
+	delete(repo.members, uid)
+
+	return nil
+}
+
+func (repo *memoryRepository) CreateHouseholdInvite(_ context.Context, invite householdInvite) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.invites[invite.Code] = invite
+
+	return nil
+}
+
+func (repo *memoryRepository) AcceptHouseholdInvite(
+	_ context.Context, code string, uid string, now time.Time,
+) (userHousehold, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	invite, ok := repo.invites[code]
+	if !ok {
+		return userHousehold{}, errHouseholdInviteNotFound
+	}
+
+	if !now.Before(invite.ExpiresAt) {
+		return userHousehold{}, errHouseholdInviteExpired
+	}
+
+	h, ok := repo.households[invite.HouseholdID]
+	if !ok {
+		return userHousehold{}, errHouseholdNotFound
+	}
+
+	if member, ok := repo.members[uid]; ok {
+		if err := checkOtherOwner(repo.householdMembers(member.householdID), uid); err != nil {
+			return userHousehold{}, err
+		}
+	}
+
+	// the invites without a role make the users editors, like in the other repositories
+	role := cmp.Or(invite.Role, householdRoleEditor)
+
+	repo.members[uid] = memoryHouseholdMember{householdID: h.ID, role: role}
+	delete(repo.invites, code)
+
+	return userHousehold{household: h, Role: role}, nil
+}
+
+func (repo *memoryRepository) GetAccessTokens(_ context.Context, uid string) ([]accessToken, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	tokens := []accessToken{}
+
+	for _, token := range repo.tokens {
+		if token.UID == uid {
+			tokens = append(tokens, token)
+		}
+	}
+
+	slices.SortFunc(tokens, func(a, b accessToken) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return tokens, nil
+}
+
+func (repo *memoryRepository) GetAccessTokenByHash(_ context.Context, hash string) (accessToken, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, token := range repo.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+
+	return accessToken{}, errAccessTokenNotFound
+}
+
+func (repo *memoryRepository) CreateAccessToken(_ context.Context, token accessToken) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.tokens[token.ID] = token
+
+	return nil
+}
+
+func (repo *memoryRepository) DeleteAccessToken(_ context.Context, uid string, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if token, ok := repo.tokens[id]; !ok || token.UID != uid {
+		return errAccessTokenNotFound
+	}
+
+	delete(repo.tokens, id)
+
+	return nil
+}
+
+func (repo *memoryRepository) SetUserEmail(_ context.Context, uid string, email string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.emails[uid] = email
+
+	return nil
+}
+
+func (repo *memoryRepository) GetUserEmails(_ context.Context, uids *[]string) ([]string, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	keys := slices.Sorted(maps.Keys(repo.emails))
+	if uids != nil {
+		keys = slices.DeleteFunc(keys, func(uid string) bool { return !slices.Contains(*uids, uid) })
+	}
+
+	emails := make([]string, 0, len(keys))
+	for _, uid := range keys {
+		emails = append(emails, repo.emails[uid])
+	}
+
+	return emails, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryLoadSeedFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "seed.json")
+	seed := `{
+		"locations": [{"id": "fridge", "name": "Fridge"}],
+		"items": [
+			{"id": "cheese", "name": "Cheese", "tags": ["dairy"], "boughtAt": "2024-05-01T12:00:00Z", "locationId": "fridge"},
+			{"name": "Potato", "boughtAt": "2024-05-01T12:00:00Z"}
+		]
+	}`
+
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	repo := newMemoryRepository()
+	if err := repo.loadSeedFile(path); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	locs, err := repo.GetLocations(ctx, locationsQuery{})
+	if err != nil || len(locs) != 1 || locs[0].ID != "fridge" {
+		t.Errorf("Expected the Fridge location, got %+v with error %v", locs, err)
+	}
+
+	items, err := repo.GetItems(ctx, itemsQuery{Sort: itemsSortName})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if len(items) != 2 || items[0].ID != "cheese" || items[1].ID == "" {
+		t.Fatalf("Expected Cheese and Potato with generated ID, got %+v", items)
+	}
+
+	if items[1].Tags == nil {
+		t.Error("Expected Potato tags to be an empty list instead of nil")
+	}
+}
+
+func TestMemoryGetItemsReturnsCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+
+	if _, err := repo.CreateItem(ctx, writeItemParams{Name: "Milk", Tags: []string{"dairy"}}); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	items, err := repo.GetItems(ctx, itemsQuery{})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	items[0].Tags[0] = "changed"
+
+	items, err = repo.GetItems(ctx, itemsQuery{Tags: getPtr([]string{"dairy"})})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if len(items) != 1 {
+		t.Errorf("Modifying a returned item changed the stored one, got %+v", items)
+	}
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	t.Parallel()
+
+	runRepositoryContract(t, func(_ *testing.T) repository { return newMemoryRepository() })
+}
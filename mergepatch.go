@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// mergePatchContentType is the media type of JSON Merge Patch documents (RFC 7386).
+const mergePatchContentType = "application/merge-patch+json"
+
+// applyMergePatch applies the JSON Merge Patch to the JSON document, returning errValidation for a malformed patch.
+// Members of the patch replace the ones of the document, null members remove them and objects are merged
+// recursively.
+func applyMergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("%w: invalid merge patch: %w", errValidation, err)
+	}
+
+	if _, ok := patchValue.(map[string]any); !ok {
+		return nil, fmt.Errorf("%w: the merge patch has to be an object", errValidation)
+	}
+
+	var docValue any
+	if err := json.Unmarshal(doc, &docValue); err != nil {
+		return nil, fmt.Errorf("unmarshal merge patch target: %w", err)
+	}
+
+	res, err := json.Marshal(mergePatchValue(docValue, patchValue))
+	if err != nil {
+		return nil, fmt.Errorf("marshal merge patch result: %w", err)
+	}
+
+	return res, nil
+}
+
+func mergePatchValue(target any, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = mergePatchValue(targetObj[key], value)
+		}
+	}
+
+	return targetObj
+}
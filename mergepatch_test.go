@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	t.Parallel()
+
+	// examples from RFC 7386 appendix A, with object targets as the patch has to be an object
+	scenarios := []struct {
+		doc    string
+		patch  string
+		result string
+	}{
+		{doc: `{"a":"b"}`, patch: `{"a":"c"}`, result: `{"a":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"b":"c"}`, result: `{"a":"b","b":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"a":null}`, result: `{}`},
+		{doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, result: `{"b":"c"}`},
+		{doc: `{"a":["b"]}`, patch: `{"a":"c"}`, result: `{"a":"c"}`},
+		{doc: `{"a":"c"}`, patch: `{"a":["b"]}`, result: `{"a":["b"]}`},
+		{doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, result: `{"a":{"b":"d"}}`},
+		{doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, result: `{"a":[1]}`},
+		{doc: `{"e":null}`, patch: `{"a":1}`, result: `{"a":1,"e":null}`},
+		{doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, result: `{"a":{"bb":{}}}`},
+	}
+
+	for _, s := range scenarios {
+		res, err := applyMergePatch([]byte(s.doc), []byte(s.patch))
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		var got, expected any
+
+		if err := json.Unmarshal(res, &got); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := json.Unmarshal([]byte(s.result), &expected); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Patching %s with %s resulted in %s instead of %s", s.doc, s.patch, res, s.result)
+		}
+	}
+}
+
+func TestApplyMergePatchInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, patch := range []string{`{"a":`, `["a"]`, `"a"`, `null`} {
+		if _, err := applyMergePatch([]byte(`{"a":"b"}`), []byte(patch)); !errors.Is(err, errValidation) {
+			t.Errorf("Expected a validation error for %s, got %v", patch, err)
+		}
+	}
+}
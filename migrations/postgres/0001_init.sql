@@ -0,0 +1,27 @@
+CREATE TABLE locations (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE items (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	type        TEXT,
+	price       INTEGER,
+	bought_at   TIMESTAMPTZ NOT NULL,
+	opened_at   TIMESTAMPTZ,
+	expires_at  TIMESTAMPTZ,
+	lifespan    INTEGER,
+	location_id TEXT
+);
+
+CREATE INDEX items_location_id_idx ON items (location_id);
+
+CREATE TABLE item_tags (
+	item_id  TEXT    NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	tag      TEXT    NOT NULL,
+	PRIMARY KEY (item_id, position)
+);
+
+CREATE INDEX item_tags_tag_idx ON item_tags (tag, item_id);
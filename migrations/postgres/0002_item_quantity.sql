@@ -0,0 +1,4 @@
+ALTER TABLE items ADD COLUMN quantity DOUBLE PRECISION;
+ALTER TABLE items ADD COLUMN initial_quantity DOUBLE PRECISION;
+ALTER TABLE items ADD COLUMN unit TEXT;
+ALTER TABLE items ADD COLUMN archived_at TIMESTAMPTZ;
@@ -0,0 +1,15 @@
+CREATE TABLE shopping_list_items (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	type        TEXT,
+	quantity    DOUBLE PRECISION,
+	unit        TEXT,
+	location_id TEXT
+);
+
+CREATE TABLE shopping_list_item_tags (
+	shopping_list_item_id TEXT    NOT NULL REFERENCES shopping_list_items (id) ON DELETE CASCADE,
+	position              INTEGER NOT NULL,
+	tag                   TEXT    NOT NULL,
+	PRIMARY KEY (shopping_list_item_id, position)
+);
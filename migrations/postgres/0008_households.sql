@@ -0,0 +1,30 @@
+CREATE TABLE households (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+-- the data stored before there were households belongs to the default household
+INSERT INTO households (id, name) VALUES ('default', 'Default');
+
+-- a user belongs to a single household
+CREATE TABLE household_members (
+	uid          TEXT PRIMARY KEY,
+	household_id TEXT NOT NULL REFERENCES households (id) ON DELETE CASCADE
+);
+
+CREATE INDEX household_members_household_id_idx ON household_members (household_id);
+
+ALTER TABLE locations ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE items ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shopping_list_items ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE expiry_rules ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX locations_household_id_idx ON locations (household_id);
+CREATE INDEX items_household_id_idx ON items (household_id);
+CREATE INDEX shopping_list_items_household_id_idx ON shopping_list_items (household_id);
+
+-- the rules are unique within their household
+ALTER TABLE expiry_rules DROP CONSTRAINT expiry_rules_type_key;
+ALTER TABLE expiry_rules DROP CONSTRAINT expiry_rules_tag_key;
+ALTER TABLE expiry_rules ADD UNIQUE (household_id, type);
+ALTER TABLE expiry_rules ADD UNIQUE (household_id, tag);
@@ -0,0 +1,6 @@
+CREATE TABLE household_invites (
+	code         TEXT PRIMARY KEY,
+	household_id TEXT        NOT NULL REFERENCES households (id) ON DELETE CASCADE,
+	created_by   TEXT        NOT NULL,
+	expires_at   TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,28 @@
+-- tables use IF NOT EXISTS as they predate versioned migrations
+CREATE TABLE IF NOT EXISTS locations (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS items (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	type        TEXT,
+	price       INTEGER,
+	bought_at   TIMESTAMP NOT NULL,
+	opened_at   TIMESTAMP,
+	expires_at  TIMESTAMP,
+	lifespan    INTEGER,
+	location_id TEXT
+);
+
+CREATE INDEX IF NOT EXISTS items_location_id_idx ON items (location_id);
+
+CREATE TABLE IF NOT EXISTS item_tags (
+	item_id  TEXT    NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	tag      TEXT    NOT NULL,
+	PRIMARY KEY (item_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS item_tags_tag_idx ON item_tags (tag);
@@ -0,0 +1,4 @@
+ALTER TABLE items ADD COLUMN quantity REAL;
+ALTER TABLE items ADD COLUMN initial_quantity REAL;
+ALTER TABLE items ADD COLUMN unit TEXT;
+ALTER TABLE items ADD COLUMN archived_at TIMESTAMP;
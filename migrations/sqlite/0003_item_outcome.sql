@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN outcome TEXT;
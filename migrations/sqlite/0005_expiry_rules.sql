@@ -0,0 +1,7 @@
+CREATE TABLE expiry_rules (
+	id              TEXT PRIMARY KEY,
+	type            TEXT UNIQUE,
+	tag             TEXT UNIQUE,
+	shelf_life      INTEGER,
+	opened_lifespan INTEGER
+);
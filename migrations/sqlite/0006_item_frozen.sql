@@ -0,0 +1,5 @@
+ALTER TABLE items ADD COLUMN frozen_at TIMESTAMP;
+ALTER TABLE items ADD COLUMN thawed_at TIMESTAMP;
+
+ALTER TABLE expiry_rules ADD COLUMN frozen_shelf_life INTEGER;
+ALTER TABLE expiry_rules ADD COLUMN thawed_lifespan INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE locations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE items ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,44 @@
+CREATE TABLE households (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+-- the data stored before there were households belongs to the default household
+INSERT INTO households (id, name) VALUES ('default', 'Default');
+
+-- a user belongs to a single household
+CREATE TABLE household_members (
+	uid          TEXT PRIMARY KEY,
+	household_id TEXT NOT NULL REFERENCES households (id) ON DELETE CASCADE
+);
+
+CREATE INDEX household_members_household_id_idx ON household_members (household_id);
+
+ALTER TABLE locations ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE items ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shopping_list_items ADD COLUMN household_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX locations_household_id_idx ON locations (household_id);
+CREATE INDEX items_household_id_idx ON items (household_id);
+CREATE INDEX shopping_list_items_household_id_idx ON shopping_list_items (household_id);
+
+-- the rules become unique within their household, which SQLite can only change by recreating the table
+CREATE TABLE expiry_rules_new (
+	id                TEXT PRIMARY KEY,
+	household_id      TEXT NOT NULL DEFAULT 'default',
+	type              TEXT,
+	tag               TEXT,
+	shelf_life        INTEGER,
+	opened_lifespan   INTEGER,
+	frozen_shelf_life INTEGER,
+	thawed_lifespan   INTEGER,
+	UNIQUE (household_id, type),
+	UNIQUE (household_id, tag)
+);
+
+INSERT INTO expiry_rules_new (id, type, tag, shelf_life, opened_lifespan, frozen_shelf_life, thawed_lifespan)
+SELECT id, type, tag, shelf_life, opened_lifespan, frozen_shelf_life, thawed_lifespan FROM expiry_rules;
+
+DROP TABLE expiry_rules;
+
+ALTER TABLE expiry_rules_new RENAME TO expiry_rules;
@@ -0,0 +1,3 @@
+-- the members from before there were roles keep being allowed everything
+ALTER TABLE household_members ADD COLUMN role TEXT NOT NULL DEFAULT 'owner';
+ALTER TABLE household_invites ADD COLUMN role TEXT NOT NULL DEFAULT 'editor';
@@ -0,0 +1,12 @@
+-- the tokens belong to a user rather than a household, and only the hash of their secret is stored
+CREATE TABLE access_tokens (
+	id         TEXT PRIMARY KEY,
+	uid        TEXT      NOT NULL,
+	name       TEXT      NOT NULL,
+	hash       TEXT      NOT NULL UNIQUE,
+	scopes     TEXT      NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP
+);
+
+CREATE INDEX access_tokens_uid_idx ON access_tokens (uid);
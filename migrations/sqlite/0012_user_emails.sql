@@ -0,0 +1,5 @@
+-- the email addresses of the users are taken from their tokens on login, to notify them without asking the provider
+CREATE TABLE user_emails (
+	uid   TEXT PRIMARY KEY,
+	email TEXT NOT NULL
+);
@@ -1,10 +1,84 @@
 package main
 
-import "context"
+import (
+	"cmp"
+	"context"
+	"time"
+)
 
 type mockRepository struct {
+	GetHouseholdsCalls int
+	GetHouseholdsRes   []household
+
+	GetHouseholdCalls int
+	GetHouseholdID    string
+	GetHouseholdRes   household
+	GetHouseholdErr   error
+
+	GetUserHouseholdCalls int
+	GetUserHouseholdUID   string
+	GetUserHouseholdRes   userHousehold
+	GetUserHouseholdErr   error
+
+	CreateHouseholdCalls int
+	CreateHouseholdName  string
+	CreateHouseholdUID   string
+	CreateHouseholdErr   error
+
+	AddHouseholdMemberCalls int
+	AddHouseholdMemberID    string
+	AddHouseholdMemberUID   string
+	AddHouseholdMemberRole  householdRole
+	AddHouseholdMemberErr   error
+
+	GetHouseholdMembersCalls int
+	GetHouseholdMembersRes   []householdMember
+
+	UpdateHouseholdMemberRoleCalls int
+	UpdateHouseholdMemberRoleID    string
+	UpdateHouseholdMemberRoleUID   string
+	UpdateHouseholdMemberRoleRole  householdRole
+
+	RemoveHouseholdMemberCalls int
+	RemoveHouseholdMemberID    string
+	RemoveHouseholdMemberUID   string
+
+	CreateHouseholdInviteCalls  int
+	CreateHouseholdInviteInvite householdInvite
+
+	AcceptHouseholdInviteCalls int
+	AcceptHouseholdInviteCode  string
+	AcceptHouseholdInviteUID   string
+	AcceptHouseholdInviteRes   userHousehold
+	AcceptHouseholdInviteErr   error
+
+	GetAccessTokensCalls int
+	GetAccessTokensUID   string
+	GetAccessTokensRes   []accessToken
+
+	GetAccessTokenByHashCalls int
+	GetAccessTokenByHashHash  string
+	GetAccessTokenByHashRes   accessToken
+	GetAccessTokenByHashErr   error
+
+	CreateAccessTokenCalls int
+	CreateAccessTokenToken accessToken
+
+	DeleteAccessTokenCalls int
+	DeleteAccessTokenUID   string
+	DeleteAccessTokenID    string
+	DeleteAccessTokenErr   error
+
+	SetUserEmailCalls int
+	SetUserEmailUID   string
+	SetUserEmailEmail string
+
+	GetUserEmailsCalls int
+	GetUserEmailsUIDs  *[]string
+	GetUserEmailsRes   []string
+
 	GetLocationsCalls int
-	GetLocationsIDs   *[]string
+	GetLocationsQuery locationsQuery
 	GetLocationsRes   []location
 	GetLocationsErr   error
 
@@ -14,15 +88,21 @@ type mockRepository struct {
 	UpdateLocationCalls int
 	UpdateLocationID    string
 	UpdateLocationName  string
+	UpdateLocationVer   *int
 
 	DeleteLocationCalls int
 	DeleteLocationID    string
+	DeleteLocationVer   *int
 
-	GetItemsCalls       int
-	GetItemsTags        *[]string
-	GetItemsLocationIDs *[]string
-	GetItemsRes         []item
-	GetItemsErr         error
+	GetItemsCalls int
+	GetItemsQuery itemsQuery
+	GetItemsRes   []item
+	GetItemsErr   error
+
+	GetItemCalls int
+	GetItemID    string
+	GetItemRes   item
+	GetItemErr   error
 
 	CreateItemCalls  int
 	CreateItemParams writeItemParams
@@ -30,81 +110,357 @@ type mockRepository struct {
 	UpdateItemCalls  int
 	UpdateItemID     string
 	UpdateItemParams writeItemParams
+	UpdateItemVer    *int
+	UpdateItemErr    error
 
 	UpdateItemLocationCalls int
 	UpdateItemLocationID    string
 	UpdateItemLocationValue *string
+	UpdateItemLocationVer   *int
 
 	DeleteItemCalls int
 	DeleteItemID    string
+	DeleteItemVer   *int
+
+	ApplyItemWritesCalls  int
+	ApplyItemWritesWrites []itemWrite
+	ApplyItemWritesErr    error
+
+	GetShoppingListItemsCalls int
+	GetShoppingListItemsRes   []shoppingListItem
+
+	GetShoppingListItemCalls int
+	GetShoppingListItemID    string
+	GetShoppingListItemRes   shoppingListItem
+	GetShoppingListItemErr   error
+
+	CreateShoppingListItemCalls  int
+	CreateShoppingListItemParams writeShoppingListItemParams
+	CreateShoppingListItemRes    shoppingListItem
+
+	UpdateShoppingListItemCalls  int
+	UpdateShoppingListItemID     string
+	UpdateShoppingListItemParams writeShoppingListItemParams
+
+	DeleteShoppingListItemCalls int
+	DeleteShoppingListItemID    string
+
+	BuyShoppingListItemCalls  int
+	BuyShoppingListItemID     string
+	BuyShoppingListItemParams writeItemParams
+	BuyShoppingListItemRes    item
+	BuyShoppingListItemErr    error
+
+	GetExpiryRulesCalls int
+	GetExpiryRulesRes   []expiryRule
+
+	CreateExpiryRuleCalls  int
+	CreateExpiryRuleParams writeExpiryRuleParams
+
+	UpdateExpiryRuleCalls  int
+	UpdateExpiryRuleID     string
+	UpdateExpiryRuleParams writeExpiryRuleParams
+
+	DeleteExpiryRuleCalls int
+	DeleteExpiryRuleID    string
+}
+
+func (repo *mockRepository) GetHouseholds(_ context.Context) ([]household, error) {
+	repo.GetHouseholdsCalls++
+
+	return repo.GetHouseholdsRes, nil
+}
+
+func (repo *mockRepository) GetHousehold(_ context.Context, id string) (household, error) {
+	repo.GetHouseholdCalls++
+	repo.GetHouseholdID = id
+
+	return repo.GetHouseholdRes, repo.GetHouseholdErr
+}
+
+func (repo *mockRepository) GetUserHousehold(_ context.Context, uid string) (userHousehold, error) {
+	repo.GetUserHouseholdCalls++
+	repo.GetUserHouseholdUID = uid
+
+	return repo.GetUserHouseholdRes, repo.GetUserHouseholdErr
+}
+
+func (repo *mockRepository) CreateHousehold(_ context.Context, name string, uid string) (household, error) {
+	repo.CreateHouseholdCalls++
+	repo.CreateHouseholdName = name
+	repo.CreateHouseholdUID = uid
+
+	if repo.CreateHouseholdErr != nil {
+		return household{}, repo.CreateHouseholdErr
+	}
+
+	return household{ID: "created", Name: name}, nil
+}
+
+func (repo *mockRepository) AddHouseholdMember(
+	_ context.Context, householdID string, uid string, role householdRole,
+) error {
+	repo.AddHouseholdMemberCalls++
+	repo.AddHouseholdMemberID = householdID
+	repo.AddHouseholdMemberUID = uid
+	repo.AddHouseholdMemberRole = role
+
+	return repo.AddHouseholdMemberErr
+}
+
+func (repo *mockRepository) GetHouseholdMembers(_ context.Context, _ string) ([]householdMember, error) {
+	repo.GetHouseholdMembersCalls++
+
+	return repo.GetHouseholdMembersRes, nil
+}
+
+func (repo *mockRepository) UpdateHouseholdMemberRole(
+	_ context.Context, householdID string, uid string, role householdRole,
+) error {
+	repo.UpdateHouseholdMemberRoleCalls++
+	repo.UpdateHouseholdMemberRoleID = householdID
+	repo.UpdateHouseholdMemberRoleUID = uid
+	repo.UpdateHouseholdMemberRoleRole = role
+
+	return nil
+}
+
+func (repo *mockRepository) RemoveHouseholdMember(_ context.Context, householdID string, uid string) error {
+	repo.RemoveHouseholdMemberCalls++
+	repo.RemoveHouseholdMemberID = householdID
+	repo.RemoveHouseholdMemberUID = uid
+
+	return nil
+}
+
+func (repo *mockRepository) CreateHouseholdInvite(_ context.Context, invite householdInvite) error {
+	repo.CreateHouseholdInviteCalls++
+	repo.CreateHouseholdInviteInvite = invite
+
+	return nil
+}
+
+func (repo *mockRepository) AcceptHouseholdInvite(
+	_ context.Context, code string, uid string, _ time.Time,
+) (userHousehold, error) {
+	repo.AcceptHouseholdInviteCalls++
+	repo.AcceptHouseholdInviteCode = code
+	repo.AcceptHouseholdInviteUID = uid
+
+	return repo.AcceptHouseholdInviteRes, repo.AcceptHouseholdInviteErr
 }
 
-func (repo *mockRepository) GetLocations(_ context.Context, ids *[]string) ([]location, error) {
+func (repo *mockRepository) GetAccessTokens(_ context.Context, uid string) ([]accessToken, error) {
+	repo.GetAccessTokensCalls++
+	repo.GetAccessTokensUID = uid
+
+	return repo.GetAccessTokensRes, nil
+}
+
+func (repo *mockRepository) GetAccessTokenByHash(_ context.Context, hash string) (accessToken, error) {
+	repo.GetAccessTokenByHashCalls++
+	repo.GetAccessTokenByHashHash = hash
+
+	return repo.GetAccessTokenByHashRes, repo.GetAccessTokenByHashErr
+}
+
+func (repo *mockRepository) CreateAccessToken(_ context.Context, token accessToken) error {
+	repo.CreateAccessTokenCalls++
+	repo.CreateAccessTokenToken = token
+
+	return nil
+}
+
+func (repo *mockRepository) DeleteAccessToken(_ context.Context, uid string, id string) error {
+	repo.DeleteAccessTokenCalls++
+	repo.DeleteAccessTokenUID = uid
+	repo.DeleteAccessTokenID = id
+
+	return repo.DeleteAccessTokenErr
+}
+
+func (repo *mockRepository) SetUserEmail(_ context.Context, uid string, email string) error {
+	repo.SetUserEmailCalls++
+	repo.SetUserEmailUID = uid
+	repo.SetUserEmailEmail = email
+
+	return nil
+}
+
+func (repo *mockRepository) GetUserEmails(_ context.Context, uids *[]string) ([]string, error) {
+	repo.GetUserEmailsCalls++
+	repo.GetUserEmailsUIDs = uids
+
+	return repo.GetUserEmailsRes, nil
+}
+
+func (repo *mockRepository) GetLocations(_ context.Context, query locationsQuery) ([]location, error) {
 	repo.GetLocationsCalls++
-	repo.GetLocationsIDs = ids
+	repo.GetLocationsQuery = query
 
 	return repo.GetLocationsRes, repo.GetLocationsErr
 }
 
-func (repo *mockRepository) CreateLocation(_ context.Context, name string) error {
+func (repo *mockRepository) CreateLocation(_ context.Context, name string) (location, error) {
 	repo.CreateLocationCalls++
 	repo.CreateLocationName = name
 
-	return nil
+	return location{ID: "created", Name: name, Version: 1}, nil
 }
 
-func (repo *mockRepository) UpdateLocation(_ context.Context, id string, name string) error {
+func (repo *mockRepository) UpdateLocation(_ context.Context, id string, name string, version *int) error {
 	repo.UpdateLocationCalls++
 	repo.UpdateLocationID = id
 	repo.UpdateLocationName = name
+	repo.UpdateLocationVer = version
 
 	return nil
 }
 
-func (repo *mockRepository) DeleteLocation(_ context.Context, id string) error {
+func (repo *mockRepository) DeleteLocation(_ context.Context, id string, version *int) error {
 	repo.DeleteLocationCalls++
 	repo.DeleteLocationID = id
+	repo.DeleteLocationVer = version
 
 	return nil
 }
 
-func (repo *mockRepository) GetItems(_ context.Context,
-	tags *[]string,
-	locationIDs *[]string,
-) ([]item, error) {
+func (repo *mockRepository) GetItems(_ context.Context, query itemsQuery) ([]item, error) {
 	repo.GetItemsCalls++
-	repo.GetItemsTags = tags
-	repo.GetItemsLocationIDs = locationIDs
+	repo.GetItemsQuery = query
 
 	return repo.GetItemsRes, repo.GetItemsErr
 }
 
-func (repo *mockRepository) CreateItem(_ context.Context, params writeItemParams) error {
+func (repo *mockRepository) GetItem(_ context.Context, id string) (item, error) {
+	repo.GetItemCalls++
+	repo.GetItemID = id
+
+	return repo.GetItemRes, repo.GetItemErr
+}
+
+func (repo *mockRepository) CreateItem(_ context.Context, params writeItemParams) (item, error) {
 	repo.CreateItemCalls++
 	repo.CreateItemParams = params
 
-	return nil
+	return itemFromParams("created", params, 1), nil
 }
 
-func (repo *mockRepository) UpdateItem(_ context.Context, id string, params writeItemParams) error {
+func (repo *mockRepository) UpdateItem(_ context.Context, id string, params writeItemParams, version *int) error {
 	repo.UpdateItemCalls++
 	repo.UpdateItemID = id
 	repo.UpdateItemParams = params
+	repo.UpdateItemVer = version
 
-	return nil
+	return repo.UpdateItemErr
 }
 
-func (repo *mockRepository) UpdateItemLocation(_ context.Context, id string, locationID *string) error {
+func (repo *mockRepository) UpdateItemLocation(
+	_ context.Context, id string, locationID *string, version *int,
+) error {
 	repo.UpdateItemLocationCalls++
 	repo.UpdateItemLocationID = id
 	repo.UpdateItemLocationValue = locationID
+	repo.UpdateItemLocationVer = version
 
 	return nil
 }
 
-func (repo *mockRepository) DeleteItem(_ context.Context, id string) error {
+func (repo *mockRepository) DeleteItem(_ context.Context, id string, version *int) error {
 	repo.DeleteItemCalls++
 	repo.DeleteItemID = id
+	repo.DeleteItemVer = version
+
+	return nil
+}
+
+func (repo *mockRepository) ApplyItemWrites(_ context.Context, writes []itemWrite) ([]string, error) {
+	repo.ApplyItemWritesCalls++
+	repo.ApplyItemWritesWrites = writes
+
+	if repo.ApplyItemWritesErr != nil {
+		return nil, repo.ApplyItemWritesErr
+	}
+
+	ids := make([]string, len(writes))
+	for idx, w := range writes {
+		ids[idx] = cmp.Or(w.ID, "created")
+	}
+
+	return ids, nil
+}
+
+func (repo *mockRepository) GetShoppingListItems(_ context.Context) ([]shoppingListItem, error) {
+	repo.GetShoppingListItemsCalls++
+
+	return repo.GetShoppingListItemsRes, nil
+}
+
+func (repo *mockRepository) GetShoppingListItem(_ context.Context, id string) (shoppingListItem, error) {
+	repo.GetShoppingListItemCalls++
+	repo.GetShoppingListItemID = id
+
+	return repo.GetShoppingListItemRes, repo.GetShoppingListItemErr
+}
+
+func (repo *mockRepository) CreateShoppingListItem(
+	_ context.Context, params writeShoppingListItemParams,
+) (shoppingListItem, error) {
+	repo.CreateShoppingListItemCalls++
+	repo.CreateShoppingListItemParams = params
+
+	return repo.CreateShoppingListItemRes, nil
+}
+
+func (repo *mockRepository) UpdateShoppingListItem(
+	_ context.Context, id string, params writeShoppingListItemParams,
+) error {
+	repo.UpdateShoppingListItemCalls++
+	repo.UpdateShoppingListItemID = id
+	repo.UpdateShoppingListItemParams = params
+
+	return nil
+}
+
+func (repo *mockRepository) DeleteShoppingListItem(_ context.Context, id string) error {
+	repo.DeleteShoppingListItemCalls++
+	repo.DeleteShoppingListItemID = id
+
+	return nil
+}
+
+func (repo *mockRepository) BuyShoppingListItem(_ context.Context, id string, params writeItemParams) (item, error) {
+	repo.BuyShoppingListItemCalls++
+	repo.BuyShoppingListItemID = id
+	repo.BuyShoppingListItemParams = params
+
+	return repo.BuyShoppingListItemRes, repo.BuyShoppingListItemErr
+}
+
+func (repo *mockRepository) GetExpiryRules(_ context.Context) ([]expiryRule, error) {
+	repo.GetExpiryRulesCalls++
+
+	return repo.GetExpiryRulesRes, nil
+}
+
+func (repo *mockRepository) CreateExpiryRule(_ context.Context, params writeExpiryRuleParams) (expiryRule, error) {
+	repo.CreateExpiryRuleCalls++
+	repo.CreateExpiryRuleParams = params
+
+	return expiryRuleFromParams("rule", params), nil
+}
+
+func (repo *mockRepository) UpdateExpiryRule(_ context.Context, id string, params writeExpiryRuleParams) error {
+	repo.UpdateExpiryRuleCalls++
+	repo.UpdateExpiryRuleID = id
+	repo.UpdateExpiryRuleParams = params
+
+	return nil
+}
+
+func (repo *mockRepository) DeleteExpiryRule(_ context.Context, id string) error {
+	repo.DeleteExpiryRuleCalls++
+	repo.DeleteExpiryRuleID = id
 
 	return nil
 }
@@ -13,15 +13,22 @@ type notifier interface {
 		ctx context.Context,
 		expiries []itemExpiry,
 		comingExpiries []itemExpiry,
-		authRepo authenticationRepository,
+		frozenExpiries []itemExpiry,
+		emails []string,
 	) error
+	// sendsToRecipients reports whether the notifications are sent to the emails, rather than to a single
+	// destination of the operator like a Telegram chat.
+	sendsToRecipients() bool
 }
 
 func getNotificationTitle() string {
 	return "Pantry - Expiring items on " + time.Now().Format(time.DateOnly)
 }
 
-func notificationExpiriesToText(expiries []itemExpiry, comingExpiries []itemExpiry) string {
+// notificationExpiriesToText lists the expired and expiring items, the frozen ones are listed in their own section.
+func notificationExpiriesToText(
+	expiries []itemExpiry, comingExpiries []itemExpiry, frozenExpiries []itemExpiry,
+) string {
 	var textBuilder strings.Builder
 
 	if len(expiries) > 0 {
@@ -47,6 +54,22 @@ func notificationExpiriesToText(expiries []itemExpiry, comingExpiries []itemExpi
 		}
 	}
 
+	if len(frozenExpiries) > 0 {
+		if textBuilder.Len() > 0 {
+			textBuilder.WriteString("\n")
+		}
+
+		textBuilder.WriteString("FROZEN ITEMS\n------------\n")
+
+		for _, exp := range frozenExpiries {
+			if exp.daysLeft < 0 {
+				fmt.Fprintf(&textBuilder, "%s is %d day(s) overdue in the freezer\n", exp.item.Name, -exp.daysLeft)
+			} else {
+				fmt.Fprintf(&textBuilder, "%s has %d day(s) left in the freezer\n", exp.item.Name, exp.daysLeft)
+			}
+		}
+	}
+
 	if textBuilder.Len() == 0 {
 		textBuilder.WriteString("NO EXPIRING ITEMS")
 	}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultOIDCClockSkew is how far the clocks of the API and the issuer can drift apart when OIDC_CLOCK_SKEW is not
+// set.
+const defaultOIDCClockSkew = time.Minute
+
+// oidcJWKSRefreshInterval is how often the keys are fetched again from the JWKS URL, in case the issuer rotated them.
+const oidcJWKSRefreshInterval = time.Hour
+
+var (
+	errOIDCConfig        = errors.New("invalid OIDC configuration")
+	errJWTWithoutSubject = errors.New("JWT without subject")
+)
+
+// oidcSigningMethods are the asymmetric algorithms accepted for the JWTs, the keys of a JWKS are public so HMAC is
+// left out.
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+}
+
+type oidcConfig struct {
+	issuer string
+	// audience is checked against the aud claim when set
+	audience string
+	// jwksURL is where the keys of the issuer are fetched from, jwksPath is read instead when set
+	jwksURL   string
+	jwksPath  string
+	clockSkew time.Duration
+}
+
+func getOIDCAuthentication(ctx context.Context, repo repository) (oidcAuthentication, error) {
+	clockSkew := defaultOIDCClockSkew
+
+	if value := os.Getenv("OIDC_CLOCK_SKEW"); value != "" {
+		var err error
+		if clockSkew, err = time.ParseDuration(value); err != nil {
+			return oidcAuthentication{}, fmt.Errorf("%w: clock skew: %w", errOIDCConfig, err)
+		}
+	}
+
+	return newOIDCAuthentication(ctx, oidcConfig{
+		issuer:    os.Getenv("OIDC_ISSUER"),
+		audience:  os.Getenv("OIDC_AUDIENCE"),
+		jwksURL:   os.Getenv("OIDC_JWKS_URL"),
+		jwksPath:  os.Getenv("OIDC_JWKS_PATH"),
+		clockSkew: clockSkew,
+	}, repo)
+}
+
+// newOIDCAuthentication loads the keys of the issuer, the keys from a JWKS URL are refreshed in the background until
+// the context is done. The email addresses of the users are stored in the repository for the notifications.
+func newOIDCAuthentication(ctx context.Context, cfg oidcConfig, repo repository) (oidcAuthentication, error) {
+	if cfg.issuer == "" {
+		return oidcAuthentication{}, fmt.Errorf("%w: the issuer is required", errOIDCConfig)
+	}
+
+	if cfg.clockSkew < 0 {
+		return oidcAuthentication{}, fmt.Errorf("%w: the clock skew cannot be negative", errOIDCConfig)
+	}
+
+	var (
+		jwks keyfunc.Keyfunc
+		err  error
+	)
+
+	switch {
+	case cfg.jwksPath != "":
+		data, readErr := os.ReadFile(cfg.jwksPath)
+		if readErr != nil {
+			return oidcAuthentication{}, fmt.Errorf("failed to read JWKS file: %w", readErr)
+		}
+
+		jwks, err = keyfunc.NewJWKSetJSON(data)
+	case cfg.jwksURL != "":
+		// the keys are fetched again when a token names an unknown key, at most every few minutes
+		jwks, err = keyfunc.NewDefaultOverrideCtx(ctx, []string{cfg.jwksURL}, keyfunc.Override{
+			Client:          &http.Client{Timeout: httpTimeout},
+			RefreshInterval: oidcJWKSRefreshInterval,
+			RefreshErrorHandlerFunc: func(u string) func(context.Context, error) {
+				return func(ctx context.Context, err error) {
+					slog.ErrorContext(ctx, "Failed to refresh the JWKS.", "url", u, "err", err)
+				}
+			},
+		})
+	default:
+		return oidcAuthentication{}, fmt.Errorf("%w: either a JWKS URL or a JWKS file is required", errOIDCConfig)
+	}
+
+	if err != nil {
+		return oidcAuthentication{}, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(cfg.issuer),
+		jwt.WithLeeway(cfg.clockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+
+	if cfg.audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.audience))
+	}
+
+	return oidcAuthentication{
+		keyfunc:      jwks,
+		parser:       jwt.NewParser(opts...),
+		repo:         repo,
+		storedEmails: &sync.Map{},
+		tracer:       otel.Tracer("oidc-auth"),
+	}, nil
+}
+
+// oidcAuthentication verifies the JWTs of an OpenID Connect issuer, like Authentik or Keycloak, the user being the
+// subject of the token.
+type oidcAuthentication struct {
+	keyfunc keyfunc.Keyfunc
+	// parser checks the signature, the issuer, the audience when configured and the times allowing for the clock skew
+	parser *jwt.Parser
+	repo   repository
+	// storedEmails maps the users to the email addresses last stored in the repository, so that it is only written
+	// when they change
+	storedEmails *sync.Map
+	tracer       trace.Tracer
+}
+
+// oidcClaims are the claims of the JWT, the email ones being standard OpenID Connect claims.
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+}
+
+func (auth oidcAuthentication) Check(ctx context.Context, r *http.Request) (authenticatedUser, error) {
+	ctx, span := auth.tracer.Start(ctx, "oidcAuthentication.Check")
+	defer span.End()
+
+	rawToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	claims := &oidcClaims{}
+	if _, err := auth.parser.ParseWithClaims(rawToken, claims, auth.keyfunc.KeyfuncCtx(ctx)); err != nil {
+		return authenticatedUser{}, fmt.Errorf("failed to verify JWT: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return authenticatedUser{}, errJWTWithoutSubject
+	}
+
+	auth.storeEmail(ctx, claims)
+
+	return authenticatedUser{UID: claims.Subject}, nil
+}
+
+// storeEmail stores the email address of the user for the notifications, unless the issuer says it is not verified.
+// Failing to store it is only logged, as it does not affect the authentication.
+func (auth oidcAuthentication) storeEmail(ctx context.Context, claims *oidcClaims) {
+	if claims.Email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return
+	}
+
+	if stored, ok := auth.storedEmails.Load(claims.Subject); ok && stored == claims.Email {
+		return
+	}
+
+	if err := auth.repo.SetUserEmail(ctx, claims.Subject, claims.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to store the email address of the user.", "uid", claims.Subject, "err", err)
+
+		return
+	}
+
+	auth.storedEmails.Store(claims.Subject, claims.Email)
+}
+
+// oidcAuthenticationRepository looks up the email addresses the users had in their tokens when they last used the
+// API, as the issuer is not asked for them.
+type oidcAuthenticationRepository struct {
+	repo repository
+}
+
+func (repo oidcAuthenticationRepository) GetEmails(ctx context.Context, uids []string) ([]string, error) {
+	return repo.getEmails(ctx, &uids)
+}
+
+func (repo oidcAuthenticationRepository) GetAllEmails(ctx context.Context) ([]string, error) {
+	return repo.getEmails(ctx, nil)
+}
+
+func (repo oidcAuthenticationRepository) getEmails(ctx context.Context, uids *[]string) ([]string, error) {
+	emails, err := repo.repo.GetUserEmails(ctx, uids)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if len(emails) == 0 {
+		return nil, errNoEmailAddressesFound
+	}
+
+	return emails, nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testOIDCIssuer   = "https://auth.example.com/application/o/pantry/"
+	testOIDCAudience = "pantry"
+)
+
+// getTestJWKS generates a P-256 key, returning it along with the JWKS holding its public key.
+func getTestJWKS(t *testing.T, kid string) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	pub, err := key.PublicKey.ECDH()
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	// the uncompressed point is 0x04 followed by the coordinates
+	point := pub.Bytes()
+	coordinate := (len(point) - 1) / 2
+
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC",
+		"crv": "P-256",
+		"kid": kid,
+		"use": "sig",
+		"alg": "ES256",
+		"x":   base64.RawURLEncoding.EncodeToString(point[1 : 1+coordinate]),
+		"y":   base64.RawURLEncoding.EncodeToString(point[1+coordinate:]),
+	}}})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	return key, jwks
+}
+
+func signTestJWT(t *testing.T, key *ecdsa.PrivateKey, kid string, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	return signed
+}
+
+func getTestOIDCClaims(now time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    testOIDCIssuer,
+		Subject:   "alice",
+		Audience:  jwt.ClaimStrings{testOIDCAudience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+}
+
+func TestOIDCAuthentication(t *testing.T) {
+	t.Parallel()
+
+	key, jwks := getTestJWKS(t, "test")
+	otherKey, _ := getTestJWKS(t, "test")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	auth, err := newOIDCAuthentication(context.Background(), oidcConfig{
+		issuer: testOIDCIssuer, audience: testOIDCAudience, jwksPath: path, clockSkew: time.Minute,
+	}, newMemoryRepository())
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	now := time.Now()
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, getTestOIDCClaims(now)).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	scenarios := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "valid", token: signTestJWT(t, key, "test", getTestOIDCClaims(now))},
+		{
+			name: "expired within the clock skew",
+			token: signTestJWT(t, key, "test", func() jwt.RegisteredClaims {
+				c := getTestOIDCClaims(now)
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-30 * time.Second))
+
+				return c
+			}()),
+		},
+		{
+			name: "expired",
+			token: signTestJWT(t, key, "test", func() jwt.RegisteredClaims {
+				c := getTestOIDCClaims(now)
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * time.Minute))
+
+				return c
+			}()),
+			err: jwt.ErrTokenExpired,
+		},
+		{
+			name: "not valid yet",
+			token: signTestJWT(t, key, "test", func() jwt.RegisteredClaims {
+				c := getTestOIDCClaims(now)
+				c.NotBefore = jwt.NewNumericDate(now.Add(2 * time.Minute))
+
+				return c
+			}()),
+			err: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name: "other issuer",
+			token: signTestJWT(t, key, "test", func() jwt.RegisteredClaims {
+				c := getTestOIDCClaims(now)
+				c.Issuer = "https://evil.example.com/"
+
+				return c
+			}()),
+			err: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "other audience",
+			token: signTestJWT(t, key, "test", func() jwt.RegisteredClaims {
+				c := getTestOIDCClaims(now)
+				c.Audience = jwt.ClaimStrings{"other"}
+
+				return c
+			}()),
+			err: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name: "without subject",
+			token: signTestJWT(t, key, "test", func() jwt.RegisteredClaims {
+				c := getTestOIDCClaims(now)
+				c.Subject = ""
+
+				return c
+			}()),
+			err: errJWTWithoutSubject,
+		},
+		{
+			name:  "signed by another key",
+			token: signTestJWT(t, otherKey, "test", getTestOIDCClaims(now)),
+			err:   jwt.ErrTokenSignatureInvalid,
+		},
+		{name: "unknown key", token: signTestJWT(t, key, "unknown", getTestOIDCClaims(now)), err: jwt.ErrTokenUnverifiable},
+		{name: "HMAC", token: hmacToken, err: jwt.ErrTokenSignatureInvalid},
+		{name: "malformed", token: "not-a-jwt", err: jwt.ErrTokenMalformed},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			t.Parallel()
+
+			user, err := auth.Check(context.Background(), getBearerRequest(t, s.token))
+
+			switch {
+			case !errors.Is(err, s.err):
+				t.Errorf("Expected %v, got %v", s.err, err)
+			case s.err == nil && user.UID != "alice":
+				t.Errorf("Expected alice, got %+v", user)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticationJWKSURL(t *testing.T) {
+	t.Parallel()
+
+	key, jwks := getTestJWKS(t, "test")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwks) //nolint:errcheck
+	}))
+	t.Cleanup(server.Close)
+
+	// the audience is optional
+	cfg := oidcConfig{issuer: testOIDCIssuer, jwksURL: server.URL}
+
+	auth, err := newOIDCAuthentication(t.Context(), cfg, newMemoryRepository())
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	claims := getTestOIDCClaims(time.Now())
+	claims.Audience = nil
+
+	user, err := auth.Check(context.Background(), getBearerRequest(t, signTestJWT(t, key, "test", claims)))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if user.UID != "alice" {
+		t.Errorf("Expected alice, got %+v", user)
+	}
+}
+
+func TestOIDCAuthenticationStoresEmail(t *testing.T) {
+	t.Parallel()
+
+	key, jwks := getTestJWKS(t, "test")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	mockRepo := &mockRepository{}
+
+	auth, err := newOIDCAuthentication(context.Background(), oidcConfig{issuer: testOIDCIssuer, jwksPath: path}, mockRepo)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	withEmail := func(email string, verified *bool) oidcClaims {
+		return oidcClaims{RegisteredClaims: getTestOIDCClaims(time.Now()), Email: email, EmailVerified: verified}
+	}
+
+	scenarios := []struct {
+		claims oidcClaims
+		calls  int
+		email  string
+	}{
+		{claims: withEmail("", nil)},
+		{claims: withEmail("alice@example.com", nil), calls: 1, email: "alice@example.com"},
+		// the stored email address is not written again
+		{claims: withEmail("alice@example.com", getPtr(true)), calls: 1, email: "alice@example.com"},
+		{claims: withEmail("other@example.com", getPtr(false)), calls: 1, email: "alice@example.com"},
+		{claims: withEmail("new@example.com", nil), calls: 2, email: "new@example.com"},
+	}
+
+	for _, s := range scenarios {
+		r := getBearerRequest(t, signTestJWT(t, key, "test", s.claims))
+		if _, err := auth.Check(context.Background(), r); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if mockRepo.SetUserEmailCalls != s.calls || mockRepo.SetUserEmailEmail != s.email {
+			t.Errorf("Expected %d calls storing %q for %+v, got %d storing %q",
+				s.calls, s.email, s.claims, mockRepo.SetUserEmailCalls, mockRepo.SetUserEmailEmail)
+		}
+
+		if s.calls > 0 && mockRepo.SetUserEmailUID != "alice" {
+			t.Errorf("Expected the email address of alice, got %q", mockRepo.SetUserEmailUID)
+		}
+	}
+}
+
+func TestOIDCAuthenticationRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	authRepo := oidcAuthenticationRepository{repo: repo}
+
+	if _, err := authRepo.GetAllEmails(ctx); !errors.Is(err, errNoEmailAddressesFound) {
+		t.Errorf("Expected errNoEmailAddressesFound, got %v", err)
+	}
+
+	if err := repo.SetUserEmail(ctx, "alice", "alice@example.com"); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	emails, err := authRepo.GetEmails(ctx, []string{"alice", "bob"})
+	if err != nil || !slices.Equal(emails, []string{"alice@example.com"}) {
+		t.Errorf("Expected the email address of alice, got %v with error %v", emails, err)
+	}
+
+	if _, err := authRepo.GetEmails(ctx, []string{"bob"}); !errors.Is(err, errNoEmailAddressesFound) {
+		t.Errorf("Expected errNoEmailAddressesFound, got %v", err)
+	}
+}
+
+func TestNewOIDCAuthenticationErr(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []oidcConfig{
+		{jwksPath: "jwks.json"},
+		{issuer: testOIDCIssuer},
+		{issuer: testOIDCIssuer, jwksPath: "jwks.json", clockSkew: -time.Second},
+	}
+
+	for _, cfg := range scenarios {
+		if _, err := newOIDCAuthentication(context.Background(), cfg, newMemoryRepository()); !errors.Is(err, errOIDCConfig) {
+			t.Errorf("Expected errOIDCConfig for %+v, got %v", cfg, err)
+		}
+	}
+}
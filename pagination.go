@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// pageParams are the pagination params of the listing endpoints, zero Limit returns everything after the cursor.
+type pageParams struct {
+	Limit  int `validate:"gte=0"`
+	Cursor string
+}
+
+// itemsCursor holds the sorted fields of the last item of a page, the next page starts after it.
+type itemsCursor struct {
+	Sort               itemsSort  `json:"sort,omitempty"`
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	BoughtAt           time.Time  `json:"boughtAt"`
+	Price              *int       `json:"price,omitempty"`
+	EffectiveExpiresAt *time.Time `json:"effectiveExpiresAt,omitempty"`
+}
+
+func newItemsCursor(i item, sort itemsSort) itemsCursor {
+	return itemsCursor{
+		Sort:               sort,
+		ID:                 i.ID,
+		Name:               i.Name,
+		BoughtAt:           i.BoughtAt,
+		Price:              i.Price,
+		EffectiveExpiresAt: i.EffectiveExpiresAt,
+	}
+}
+
+// item returns the fields of the cursor as an item so that it can be compared with compareItems.
+func (c itemsCursor) item() item {
+	return item{ID: c.ID, Name: c.Name, BoughtAt: c.BoughtAt, Price: c.Price, EffectiveExpiresAt: c.EffectiveExpiresAt}
+}
+
+// locationsCursor holds the sorted fields of the last location of a page.
+type locationsCursor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// limitEntries returns at most limit of the sorted entries, zero limit returns all of them.
+func limitEntries[T any](entries []T, limit int) []T {
+	if limit > 0 && len(entries) > limit {
+		return entries[:limit]
+	}
+
+	return entries
+}
+
+// encodeCursor returns the cursor as the opaque string the clients send back for the next page.
+func encodeCursor(cursor any) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns nil for an empty cursor and errValidation for a malformed one.
+func decodeCursor[T any](cursor string) (*T, error) {
+	if cursor == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor: %w", errValidation, err)
+	}
+
+	var c T
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor: %w", errValidation, err)
+	}
+
+	return &c, nil
+}
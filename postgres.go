@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel"
+)
+
+// postgresMigrationLockID is an arbitrary key for the advisory lock held while migrating.
+const postgresMigrationLockID = 7_312_004
+
+func getPostgresRepository(ctx context.Context) (postgresRepository, error) {
+	return openPostgresRepository(ctx, os.Getenv("POSTGRES_URL"))
+}
+
+func openPostgresRepository(ctx context.Context, url string) (postgresRepository, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return postgresRepository{}, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+
+	repo := postgresRepository{sqlRepository{
+		db: db,
+		dialect: sqlDialect{
+			name:                 "postgres",
+			numberedPlaceholders: true,
+			migrationLock:        fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", postgresMigrationLockID),
+		},
+		tracer: otel.Tracer("postgres"),
+	}}
+
+	if err := repo.migrate(ctx); err != nil {
+		db.Close() //nolint:errcheck,gosec
+
+		return postgresRepository{}, fmt.Errorf("failed to migrate postgres database: %w", err)
+	}
+
+	return repo, nil
+}
+
+type postgresRepository struct {
+	sqlRepository
+}
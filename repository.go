@@ -1,15 +1,90 @@
 package main
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
+// errVersionMismatch is returned by the writes given a version that differs from the stored one.
+var errVersionMismatch = errors.New("version does not match")
+
+// repository stores the data of the API. The writes of locations and items taking a version only apply when it
+// matches the stored version, which is checked atomically with the write, nil skips the check. Deleting with a
+// version returns errVersionMismatch if there is nothing to delete. Every write of a location or an item increments
+// its version.
+//
+// The data is scoped to the household returned by getHouseholdID for the context, the households, their members,
+// the access tokens and the email addresses of the users are not.
 type repository interface {
-	GetLocations(ctx context.Context, ids *[]string) ([]location, error)
-	CreateLocation(ctx context.Context, name string) error
-	UpdateLocation(ctx context.Context, id string, name string) error
-	DeleteLocation(ctx context.Context, id string) error
-	GetItems(ctx context.Context, tags *[]string, locationIDs *[]string) ([]item, error)
-	CreateItem(ctx context.Context, params writeItemParams) error
-	UpdateItem(ctx context.Context, id string, params writeItemParams) error
-	UpdateItemLocation(ctx context.Context, id string, locationID *string) error
-	DeleteItem(ctx context.Context, id string) error
+	GetHouseholds(ctx context.Context) ([]household, error)
+	// GetHousehold returns errHouseholdNotFound if there is no household with the id.
+	GetHousehold(ctx context.Context, id string) (household, error)
+	// GetUserHousehold returns errHouseholdNotFound if the user does not belong to any household.
+	GetUserHousehold(ctx context.Context, uid string) (userHousehold, error)
+	// CreateHousehold creates the household along with its first member as the owner, it returns
+	// errHouseholdMemberExists if the user already belongs to a household.
+	CreateHousehold(ctx context.Context, name string, uid string) (household, error)
+	// AddHouseholdMember returns errHouseholdNotFound if there is no household with the id and
+	// errHouseholdMemberExists if the user already belongs to a household.
+	AddHouseholdMember(ctx context.Context, householdID string, uid string, role householdRole) error
+	GetHouseholdMembers(ctx context.Context, householdID string) ([]householdMember, error)
+	// UpdateHouseholdMemberRole returns errHouseholdMemberNotFound if the user does not belong to the household and
+	// errHouseholdLastOwner if the role would take away the last owner of the household with other members.
+	UpdateHouseholdMemberRole(ctx context.Context, householdID string, uid string, role householdRole) error
+	// RemoveHouseholdMember returns errHouseholdMemberNotFound if the user does not belong to the household and
+	// errHouseholdLastOwner if the user is the last owner of the household with other members.
+	RemoveHouseholdMember(ctx context.Context, householdID string, uid string) error
+	CreateHouseholdInvite(ctx context.Context, invite householdInvite) error
+	// AcceptHouseholdInvite moves the user to the household of the invite with its role and deletes the invite,
+	// returning the household. It returns errHouseholdInviteNotFound if there is no invite with the code and
+	// errHouseholdInviteExpired if the invite expired before now. It returns errHouseholdLastOwner if the user is the
+	// last owner of their current household with other members.
+	AcceptHouseholdInvite(ctx context.Context, code string, uid string, now time.Time) (userHousehold, error)
+	// GetAccessTokens returns the access tokens of the user, the oldest first.
+	GetAccessTokens(ctx context.Context, uid string) ([]accessToken, error)
+	// GetAccessTokenByHash returns errAccessTokenNotFound if there is no access token with the hash.
+	GetAccessTokenByHash(ctx context.Context, hash string) (accessToken, error)
+	CreateAccessToken(ctx context.Context, token accessToken) error
+	// DeleteAccessToken returns errAccessTokenNotFound if the user has no access token with the id.
+	DeleteAccessToken(ctx context.Context, uid string, id string) error
+	// SetUserEmail stores the email address of the user, replacing the previous one.
+	SetUserEmail(ctx context.Context, uid string, email string) error
+	// GetUserEmails returns the stored email addresses of the users, sorted by user, nil returns the ones of all the
+	// users. The users without a stored email address are skipped.
+	GetUserEmails(ctx context.Context, uids *[]string) ([]string, error)
+	GetLocations(ctx context.Context, query locationsQuery) ([]location, error)
+	// CreateLocation returns the stored location.
+	CreateLocation(ctx context.Context, name string) (location, error)
+	UpdateLocation(ctx context.Context, id string, name string, version *int) error
+	DeleteLocation(ctx context.Context, id string, version *int) error
+	GetItems(ctx context.Context, query itemsQuery) ([]item, error)
+	// GetItem returns errItemNotFound if there is no item with the id.
+	GetItem(ctx context.Context, id string) (item, error)
+	// CreateItem returns the stored item.
+	CreateItem(ctx context.Context, params writeItemParams) (item, error)
+	UpdateItem(ctx context.Context, id string, params writeItemParams, version *int) error
+	UpdateItemLocation(ctx context.Context, id string, locationID *string, version *int) error
+	DeleteItem(ctx context.Context, id string, version *int) error
+	// ApplyItemWrites applies all the writes in order or none of them, returning the IDs of the written items. The
+	// failing write is returned as an itemWriteError.
+	ApplyItemWrites(ctx context.Context, writes []itemWrite) ([]string, error)
+	GetShoppingListItems(ctx context.Context) ([]shoppingListItem, error)
+	// GetShoppingListItem returns errShoppingListItemNotFound if there is no list entry with the id.
+	GetShoppingListItem(ctx context.Context, id string) (shoppingListItem, error)
+	// CreateShoppingListItem returns the stored list entry.
+	CreateShoppingListItem(ctx context.Context, params writeShoppingListItemParams) (shoppingListItem, error)
+	UpdateShoppingListItem(ctx context.Context, id string, params writeShoppingListItemParams) error
+	// DeleteShoppingListItem returns errShoppingListItemNotFound if there is no list entry with the id.
+	DeleteShoppingListItem(ctx context.Context, id string) error
+	// BuyShoppingListItem creates the item and deletes the list entry in a single write, returning the stored item.
+	// It returns errShoppingListItemNotFound if there is no list entry with the id, creating nothing.
+	BuyShoppingListItem(ctx context.Context, id string, params writeItemParams) (item, error)
+	GetExpiryRules(ctx context.Context) ([]expiryRule, error)
+	// CreateExpiryRule returns the stored rule.
+	CreateExpiryRule(ctx context.Context, params writeExpiryRuleParams) (expiryRule, error)
+	// UpdateExpiryRule returns errExpiryRuleNotFound if there is no rule with the id.
+	UpdateExpiryRule(ctx context.Context, id string, params writeExpiryRuleParams) error
+	// DeleteExpiryRule returns errExpiryRuleNotFound if there is no rule with the id.
+	DeleteExpiryRule(ctx context.Context, id string) error
 }
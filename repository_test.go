@@ -0,0 +1,1421 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runRepositoryContract checks that a repository implementation behaves the same way as the other backends.
+// newRepo must return an empty repository every time it is called.
+//
+//nolint:maintidx
+func runRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository) {
+	t.Helper()
+
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("CreateLocation and GetLocations", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		fridge := contractCreateLocation(t, repo, "Fridge")
+		pantry := contractCreateLocation(t, repo, "Pantry")
+
+		locs, err := repo.GetLocations(ctx, locationsQuery{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if names := contractLocationNames(locs); !reflect.DeepEqual(names, []string{"Fridge", "Pantry"}) {
+			t.Errorf("Got locations %v instead of Fridge and Pantry", names)
+		}
+
+		locs, err = repo.GetLocations(ctx, locationsQuery{IDs: &[]string{pantry.ID, "missing"}})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !reflect.DeepEqual(locs, []location{pantry}) {
+			t.Errorf("Got %+v instead of only %+v", locs, pantry)
+		}
+
+		if fridge.ID == pantry.ID || fridge.ID == "" {
+			t.Errorf("Locations got invalid IDs %q and %q", fridge.ID, pantry.ID)
+		}
+	})
+
+	t.Run("GetLocations pagination", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		for _, name := range []string{"Pantry", "Fridge", "Freezer", "Cellar", "Fridge"} {
+			contractCreateLocation(t, repo, name)
+		}
+
+		all, err := repo.GetLocations(ctx, locationsQuery{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		paged := []location{}
+		query := locationsQuery{Limit: 2}
+
+		for range all {
+			locs, err := repo.GetLocations(ctx, query)
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			if len(locs) > query.Limit {
+				t.Fatalf("Got %d locations with limit %d", len(locs), query.Limit)
+			}
+
+			paged = append(paged, locs...)
+
+			if len(locs) < query.Limit {
+				break
+			}
+
+			last := locs[len(locs)-1]
+			query.After = &locationsCursor{ID: last.ID, Name: last.Name}
+		}
+
+		if !reflect.DeepEqual(paged, all) {
+			t.Errorf("Got pages %+v instead of %+v", paged, all)
+		}
+
+		if names := contractLocationNames(all); !slices.IsSorted(names) || len(names) != 5 {
+			t.Errorf("Expected 5 locations sorted by name, got %v", names)
+		}
+	})
+
+	t.Run("UpdateLocation", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		l := contractCreateLocation(t, repo, "Fridge")
+
+		if err := repo.UpdateLocation(ctx, l.ID, "Freezer", nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		locs, err := repo.GetLocations(ctx, locationsQuery{IDs: &[]string{l.ID}})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(locs) != 1 || locs[0].Name != "Freezer" {
+			t.Errorf("Expected location to be renamed to Freezer, got %+v", locs)
+		}
+
+		if err := repo.UpdateLocation(ctx, "missing", "Shelf", nil); !errors.Is(err, errLocationNotFound) {
+			t.Errorf("Expected errLocationNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DeleteLocation", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		fridge := contractCreateLocation(t, repo, "Fridge")
+		pantry := contractCreateLocation(t, repo, "Pantry")
+
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Cheese", Tags: []string{}, BoughtAt: boughtAt, LocationID: &fridge.ID,
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Rice", Tags: []string{}, BoughtAt: boughtAt, LocationID: &pantry.ID,
+		})
+
+		if err := repo.DeleteLocation(ctx, fridge.ID, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		locs, err := repo.GetLocations(ctx, locationsQuery{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !reflect.DeepEqual(locs, []location{pantry}) {
+			t.Errorf("Got %+v instead of only %+v", locs, pantry)
+		}
+
+		items := contractGetItemsByName(t, repo, itemsQuery{})
+
+		if items["Cheese"].LocationID != nil {
+			t.Errorf("Cheese still has location %s", *items["Cheese"].LocationID)
+		}
+
+		if items["Rice"].LocationID == nil || *items["Rice"].LocationID != pantry.ID {
+			t.Errorf("Rice lost its location, got %v", items["Rice"].LocationID)
+		}
+
+		if err := repo.DeleteLocation(ctx, "missing", nil); err != nil {
+			t.Errorf("Deleting a missing location returned %s", err)
+		}
+	})
+
+	t.Run("CreateItem and GetItems", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t)
+		params := writeItemParams{
+			Name:       "Cheese",
+			Type:       getPtr("250g"),
+			Tags:       []string{"dairy", "smelly"},
+			Price:      getPtr(1499),
+			BoughtAt:   boughtAt,
+			OpenedAt:   getPtr(boughtAt.Add(24 * time.Hour)),
+			ExpiresAt:  getPtr(boughtAt.Add(240 * time.Hour)),
+			Lifespan:   getPtr(5),
+			LocationID: getPtr("fridge"),
+		}
+
+		contractCreateItem(t, repo, params)
+		contractCreateItem(t, repo, writeItemParams{Name: "Potato", Tags: []string{}, BoughtAt: boughtAt})
+
+		items := contractGetItemsByName(t, repo, itemsQuery{})
+		if len(items) != 2 {
+			t.Fatalf("Got %d items instead of 2", len(items))
+		}
+
+		contractCompareItem(t, items["Cheese"], params)
+		contractCompareItem(t, items["Potato"], writeItemParams{Name: "Potato", Tags: []string{}, BoughtAt: boughtAt})
+
+		if items["Cheese"].ID == "" || items["Cheese"].ID == items["Potato"].ID {
+			t.Errorf("Items got invalid IDs %q and %q", items["Cheese"].ID, items["Potato"].ID)
+		}
+	})
+
+	t.Run("GetItems filters", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t)
+
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Cheese", Tags: []string{"dairy", "smelly"}, BoughtAt: boughtAt, LocationID: getPtr("fridge"),
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Milk", Tags: []string{"dairy"}, BoughtAt: boughtAt, LocationID: getPtr("door"),
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Garlic", Tags: []string{"smelly", "vegetable"}, BoughtAt: boughtAt, OpenedAt: getPtr(boughtAt),
+			LocationID: getPtr("pantry"),
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Salt", Tags: []string{"spice"}, BoughtAt: boughtAt,
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Yogurt", Tags: []string{"dairy"}, BoughtAt: boughtAt, ArchivedAt: getPtr(boughtAt),
+			Outcome: getPtr(itemOutcomeWasted), LocationID: getPtr("fridge"),
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Bread", Tags: []string{}, BoughtAt: boughtAt, ArchivedAt: getPtr(boughtAt),
+			Outcome: getPtr(itemOutcomeConsumed),
+		})
+
+		scenarios := []struct {
+			query itemsQuery
+			names []string
+		}{
+			{query: itemsQuery{}, names: []string{"Cheese", "Garlic", "Milk", "Salt"}},
+			{query: itemsQuery{Tags: &[]string{"dairy"}}, names: []string{"Cheese", "Milk"}},
+			{query: itemsQuery{Tags: &[]string{"smelly", "spice"}}, names: []string{"Cheese", "Garlic", "Salt"}},
+			{query: itemsQuery{LocationIDs: &[]string{"fridge", "pantry"}}, names: []string{"Cheese", "Garlic"}},
+			{
+				query: itemsQuery{Tags: &[]string{"smelly"}, LocationIDs: &[]string{"fridge", "door"}},
+				names: []string{"Cheese"},
+			},
+			{query: itemsQuery{Tags: &[]string{"fruit"}}, names: []string{}},
+			{query: itemsQuery{OpenedOnly: true}, names: []string{"Garlic"}},
+			{query: itemsQuery{Unassigned: true}, names: []string{"Salt"}},
+			{query: itemsQuery{Archived: true}, names: []string{"Bread", "Yogurt"}},
+			{query: itemsQuery{Archived: true, Outcome: getPtr(itemOutcomeWasted)}, names: []string{"Yogurt"}},
+			{
+				query: itemsQuery{BoughtFrom: &boughtAt, BoughtBefore: getPtr(boughtAt.Add(time.Hour))},
+				names: []string{"Cheese", "Garlic", "Milk", "Salt"},
+			},
+			{query: itemsQuery{BoughtBefore: &boughtAt}, names: []string{}},
+			{query: itemsQuery{BoughtFrom: getPtr(boughtAt.Add(time.Second))}, names: []string{}},
+			{query: itemsQuery{Tags: &[]string{"dairy"}, Archived: true}, names: []string{"Yogurt"}},
+			{query: itemsQuery{Tags: &[]string{"dairy"}, OpenedOnly: true}, names: []string{}},
+		}
+
+		for _, s := range scenarios {
+			items := contractGetItemsByName(t, repo, s.query)
+
+			names := []string{}
+			for name := range items {
+				names = append(names, name)
+			}
+
+			slices.Sort(names)
+
+			if !reflect.DeepEqual(names, s.names) {
+				t.Errorf("Filtering by %+v returned %v instead of %v", s.query, names, s.names)
+			}
+		}
+	})
+
+	t.Run("GetItems sorting", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t)
+
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Milk", Tags: []string{"dairy"}, Price: getPtr(349), BoughtAt: boughtAt.Add(48 * time.Hour),
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Cheese", Tags: []string{"dairy"}, Price: getPtr(1499), BoughtAt: boughtAt,
+		})
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Salt", Tags: []string{"spice"}, BoughtAt: boughtAt.Add(24 * time.Hour),
+		})
+
+		scenarios := []struct {
+			sort  itemsSort
+			names []string
+		}{
+			{sort: itemsSortName, names: []string{"Cheese", "Milk", "Salt"}},
+			{sort: itemsSortBoughtAt, names: []string{"Cheese", "Salt", "Milk"}},
+			{sort: itemsSortPrice, names: []string{"Salt", "Milk", "Cheese"}},
+		}
+
+		for _, s := range scenarios {
+			items, err := repo.GetItems(context.Background(), itemsQuery{Sort: s.sort})
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			names := []string{}
+			for _, i := range items {
+				names = append(names, i.Name)
+			}
+
+			if !reflect.DeepEqual(names, s.names) {
+				t.Errorf("Sorting by %q returned %v instead of %v", s.sort, names, s.names)
+			}
+		}
+
+		items, err := repo.GetItems(context.Background(), itemsQuery{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !slices.IsSortedFunc(items, func(a, b item) int { return strings.Compare(a.ID, b.ID) }) {
+			t.Errorf("Expected the items to be sorted by ID by default, got %+v", items)
+		}
+	})
+
+	t.Run("GetItems pagination", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		for idx, name := range []string{"Milk", "Cheese", "Salt", "Bread", "Cheese", "Rice", "Eggs"} {
+			params := writeItemParams{
+				Name: name, Tags: []string{"food"}, BoughtAt: boughtAt.Add(time.Duration(idx%3) * time.Hour),
+			}
+			if idx%2 == 0 {
+				params.Price = getPtr(100 * (idx % 3))
+			}
+
+			contractCreateItem(t, repo, params)
+		}
+
+		for _, sort := range []itemsSort{itemsSortID, itemsSortName, itemsSortBoughtAt, itemsSortPrice} {
+			all, err := repo.GetItems(ctx, itemsQuery{Sort: sort})
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			paged := []item{}
+			query := itemsQuery{Tags: &[]string{"food"}, Sort: sort, Limit: 3}
+
+			for range all {
+				items, err := repo.GetItems(ctx, query)
+				if err != nil {
+					t.Fatalf("Got error: %s", err)
+				}
+
+				paged = append(paged, items...)
+
+				if len(items) < query.Limit {
+					break
+				}
+
+				query.After = getPtr(newItemsCursor(items[len(items)-1], sort))
+			}
+
+			allIDs, pagedIDs := []string{}, []string{}
+			for _, i := range all {
+				allIDs = append(allIDs, i.ID)
+			}
+
+			for _, i := range paged {
+				pagedIDs = append(pagedIDs, i.ID)
+			}
+
+			if !reflect.DeepEqual(pagedIDs, allIDs) {
+				t.Errorf("Paging sorted by %q returned %v instead of %v", sort, pagedIDs, allIDs)
+			}
+		}
+	})
+
+	t.Run("GetItem", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		params := writeItemParams{
+			Name: "Cheese", Type: getPtr("250g"), Tags: []string{"dairy", "smelly"}, Price: getPtr(1499),
+			BoughtAt: boughtAt, ExpiresAt: getPtr(boughtAt.Add(240 * time.Hour)), LocationID: getPtr("fridge"),
+			Quantity: getPtr(187.5), InitialQuantity: getPtr(250.0), Unit: getPtr(itemUnitGram),
+			ArchivedAt: getPtr(boughtAt.Add(48 * time.Hour)), Outcome: getPtr(itemOutcomeGivenAway),
+			FrozenAt: getPtr(boughtAt.Add(2 * time.Hour)), ThawedAt: getPtr(boughtAt.Add(24 * time.Hour)),
+		}
+
+		contractCreateItem(t, repo, params)
+
+		id := contractGetItemsByName(t, repo, itemsQuery{Archived: true})["Cheese"].ID
+
+		i, err := repo.GetItem(ctx, id)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		contractCompareItem(t, i, params)
+
+		if i.ID != id {
+			t.Errorf("Got item %s instead of %s", i.ID, id)
+		}
+
+		if _, err := repo.GetItem(ctx, "missing"); !errors.Is(err, errItemNotFound) {
+			t.Errorf("Expected errItemNotFound, got %v", err)
+		}
+	})
+
+	t.Run("UpdateItem", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Cheese", Type: getPtr("250g"), Tags: []string{"dairy"}, Price: getPtr(1499),
+			BoughtAt: boughtAt, Lifespan: getPtr(5), LocationID: getPtr("fridge"),
+		})
+
+		id := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"].ID
+		params := writeItemParams{
+			Name: "Blue cheese", Tags: []string{"smelly", "dairy"}, BoughtAt: boughtAt.Add(time.Hour),
+			OpenedAt: getPtr(boughtAt.Add(48 * time.Hour)), ExpiresAt: getPtr(boughtAt.Add(96 * time.Hour)),
+		}
+
+		if err := repo.UpdateItem(ctx, id, params, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		items := contractGetItemsByName(t, repo, itemsQuery{})
+		if len(items) != 1 {
+			t.Fatalf("Got %d items instead of 1", len(items))
+		}
+
+		contractCompareItem(t, items["Blue cheese"], params)
+
+		if items["Blue cheese"].ID != id {
+			t.Errorf("Item ID changed from %s to %s", id, items["Blue cheese"].ID)
+		}
+
+		err := repo.UpdateItem(ctx, "missing", writeItemParams{Name: "Tea", Tags: []string{}, BoughtAt: boughtAt}, nil)
+		if !errors.Is(err, errItemNotFound) {
+			t.Errorf("Expected errItemNotFound, got %v", err)
+		}
+	})
+
+	t.Run("UpdateItemLocation", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		contractCreateItem(t, repo, writeItemParams{
+			Name: "Cheese", Tags: []string{"dairy"}, BoughtAt: boughtAt, LocationID: getPtr("fridge"),
+		})
+
+		id := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"].ID
+
+		if err := repo.UpdateItemLocation(ctx, id, getPtr("freezer"), nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if i := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"]; i.LocationID == nil || *i.LocationID != "freezer" {
+			t.Errorf("Expected location freezer, got %v", i.LocationID)
+		}
+
+		if err := repo.UpdateItemLocation(ctx, id, nil, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if i := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"]; i.LocationID != nil {
+			t.Errorf("Expected no location, got %s", *i.LocationID)
+		}
+
+		if items := contractGetItemsByName(t, repo, itemsQuery{Tags: &[]string{"dairy"}}); len(items) != 1 {
+			t.Errorf("Moving the item changed its tags, got %+v", items)
+		}
+
+		if err := repo.UpdateItemLocation(ctx, "missing", nil, nil); !errors.Is(err, errItemNotFound) {
+			t.Errorf("Expected errItemNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DeleteItem", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		contractCreateItem(t, repo, writeItemParams{Name: "Cheese", Tags: []string{"dairy"}, BoughtAt: boughtAt})
+		contractCreateItem(t, repo, writeItemParams{Name: "Milk", Tags: []string{"dairy"}, BoughtAt: boughtAt})
+
+		id := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"].ID
+
+		if err := repo.DeleteItem(ctx, id, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		items := contractGetItemsByName(t, repo, itemsQuery{Tags: &[]string{"dairy"}})
+		if _, ok := items["Milk"]; !ok || len(items) != 1 {
+			t.Errorf("Expected only Milk to remain, got %+v", items)
+		}
+
+		if err := repo.DeleteItem(ctx, "missing", nil); err != nil {
+			t.Errorf("Deleting a missing item returned %s", err)
+		}
+	})
+
+	t.Run("Location versions", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		l := contractCreateLocation(t, repo, "Fridge")
+
+		if l.Version != 1 {
+			t.Fatalf("Created location has version %d instead of 1", l.Version)
+		}
+
+		if err := repo.UpdateLocation(ctx, l.ID, "Freezer", getPtr(1)); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.UpdateLocation(ctx, l.ID, "Shelf", getPtr(1)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+		}
+
+		if err := repo.UpdateLocation(ctx, "missing", "Shelf", getPtr(1)); !errors.Is(err, errLocationNotFound) {
+			t.Errorf("Expected errLocationNotFound, got %v", err)
+		}
+
+		locs, err := repo.GetLocations(ctx, locationsQuery{IDs: &[]string{l.ID}})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(locs) != 1 || locs[0].Name != "Freezer" || locs[0].Version != 2 {
+			t.Errorf("Expected Freezer at version 2, got %+v", locs)
+		}
+
+		if err := repo.DeleteLocation(ctx, l.ID, getPtr(1)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+		}
+
+		if err := repo.DeleteLocation(ctx, l.ID, getPtr(2)); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.DeleteLocation(ctx, l.ID, getPtr(2)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a deleted location, got %v", err)
+		}
+	})
+
+	t.Run("Item versions", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		fridge := contractCreateLocation(t, repo, "Fridge")
+		params := writeItemParams{Name: "Cheese", Tags: []string{}, BoughtAt: boughtAt, LocationID: &fridge.ID}
+
+		contractCreateItem(t, repo, params)
+
+		i := contractGetItemsByName(t, repo, itemsQuery{})["Cheese"]
+		if i.Version != 1 {
+			t.Fatalf("Created item has version %d instead of 1", i.Version)
+		}
+
+		if err := repo.UpdateItem(ctx, i.ID, params, getPtr(1)); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.UpdateItem(ctx, i.ID, params, getPtr(1)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+		}
+
+		if err := repo.UpdateItemLocation(ctx, i.ID, nil, getPtr(1)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+		}
+
+		if err := repo.UpdateItemLocation(ctx, i.ID, &fridge.ID, getPtr(2)); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		// writes without a version always apply
+		if err := repo.UpdateItem(ctx, i.ID, params, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		// removing the location of the item is a write of the item too
+		if err := repo.DeleteLocation(ctx, fridge.ID, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		i, err := repo.GetItem(ctx, i.ID)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if i.Version != 5 {
+			t.Errorf("Expected version 5 after four writes, got %d", i.Version)
+		}
+
+		if err := repo.DeleteItem(ctx, i.ID, getPtr(4)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+		}
+
+		if err := repo.DeleteItem(ctx, i.ID, getPtr(5)); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.DeleteItem(ctx, i.ID, getPtr(5)); !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a deleted item, got %v", err)
+		}
+	})
+
+	t.Run("ApplyItemWrites", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		contractCreateItem(t, repo, writeItemParams{Name: "Cheese", Tags: []string{}, BoughtAt: boughtAt})
+		contractCreateItem(t, repo, writeItemParams{Name: "Milk", Tags: []string{}, BoughtAt: boughtAt})
+
+		items := contractGetItemsByName(t, repo, itemsQuery{})
+		cheese, milk := items["Cheese"], items["Milk"]
+		rice := writeItemParams{Name: "Rice", Tags: []string{"grains"}, BoughtAt: boughtAt}
+
+		// a failing write leaves out the writes before it too
+		_, err := repo.ApplyItemWrites(ctx, []itemWrite{
+			{Op: itemBatchCreate, Params: rice},
+			{Op: itemBatchMove, ID: "missing", LocationID: getPtr("fridge")},
+		})
+
+		var writeErr itemWriteError
+		if !errors.As(err, &writeErr) || writeErr.Index != 1 || !errors.Is(err, errItemNotFound) {
+			t.Errorf("Expected errItemNotFound of the second write, got %v", err)
+		}
+
+		if items := contractGetItemsByName(t, repo, itemsQuery{}); len(items) != 2 {
+			t.Errorf("Expected the failed writes to be left out, got %+v", items)
+		}
+
+		ids, err := repo.ApplyItemWrites(ctx, []itemWrite{
+			{Op: itemBatchCreate, Params: rice},
+			{Op: itemBatchUpdate, ID: cheese.ID, Params: writeItemParams{
+				Name: "Blue cheese", Tags: []string{}, BoughtAt: boughtAt,
+			}, Version: getPtr(1)},
+			{Op: itemBatchMove, ID: cheese.ID, LocationID: getPtr("fridge"), Version: getPtr(2)},
+			{Op: itemBatchDelete, ID: milk.ID},
+		})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		items = contractGetItemsByName(t, repo, itemsQuery{})
+		if len(items) != 2 || len(ids) != 4 || ids[0] != items["Rice"].ID || ids[1] != cheese.ID || ids[3] != milk.ID {
+			t.Fatalf("Got IDs %v for items %+v", ids, items)
+		}
+
+		contractCompareItem(t, items["Rice"], rice)
+
+		if i := items["Blue cheese"]; i.LocationID == nil || *i.LocationID != "fridge" || i.Version != 3 {
+			t.Errorf("Expected Blue cheese in the fridge at version 3, got %+v", i)
+		}
+
+		_, err = repo.ApplyItemWrites(ctx, []itemWrite{{Op: itemBatchDelete, ID: cheese.ID, Version: getPtr(2)}})
+		if !errors.Is(err, errVersionMismatch) {
+			t.Errorf("Expected errVersionMismatch for a stale version, got %v", err)
+		}
+	})
+
+	t.Run("Shopping list", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		milk := writeShoppingListItemParams{
+			Name: "Milk", Type: getPtr("1l"), Tags: []string{"dairy", "drink"},
+			Quantity: getPtr(2.0), Unit: getPtr(itemUnitCount), LocationID: getPtr("fridge"),
+		}
+		bread := writeShoppingListItemParams{Name: "Bread", Tags: []string{}}
+
+		for _, params := range []writeShoppingListItemParams{milk, bread} {
+			created, err := repo.CreateShoppingListItem(ctx, params)
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			if created.ID == "" {
+				t.Errorf("Expected the created entry to have an id, got %+v", created)
+			}
+
+			contractCompareShoppingListItem(t, created, params)
+		}
+
+		entries, err := repo.GetShoppingListItems(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(entries) != 2 || entries[0].Name != "Bread" || entries[1].Name != "Milk" {
+			t.Fatalf("Expected Bread and Milk sorted by name, got %+v", entries)
+		}
+
+		contractCompareShoppingListItem(t, entries[0], bread)
+		contractCompareShoppingListItem(t, entries[1], milk)
+
+		id := entries[1].ID
+		milk.Tags = []string{"drink"}
+		milk.Quantity, milk.Unit = nil, nil
+
+		if err := repo.UpdateShoppingListItem(ctx, id, milk); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		entry, err := repo.GetShoppingListItem(ctx, id)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		contractCompareShoppingListItem(t, entry, milk)
+
+		if err := repo.UpdateShoppingListItem(ctx, "missing", bread); !errors.Is(err, errShoppingListItemNotFound) {
+			t.Errorf("Expected errShoppingListItemNotFound, got %v", err)
+		}
+
+		if err := repo.DeleteShoppingListItem(ctx, id); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.DeleteShoppingListItem(ctx, id); !errors.Is(err, errShoppingListItemNotFound) {
+			t.Errorf("Expected errShoppingListItemNotFound deleting again, got %v", err)
+		}
+
+		if _, err := repo.GetShoppingListItem(ctx, id); !errors.Is(err, errShoppingListItemNotFound) {
+			t.Errorf("Expected errShoppingListItemNotFound, got %v", err)
+		}
+
+		if entries, err := repo.GetShoppingListItems(ctx); err != nil || len(entries) != 1 {
+			t.Errorf("Expected only Bread to remain, got %+v, %v", entries, err)
+		}
+	})
+
+	t.Run("BuyShoppingListItem", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		entry, err := repo.CreateShoppingListItem(ctx, writeShoppingListItemParams{Name: "Milk", Tags: []string{}})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		params := writeItemParams{Name: "Milk", Tags: []string{"dairy"}, BoughtAt: time.Now().UTC().Truncate(time.Second)}
+
+		bought, err := repo.BuyShoppingListItem(ctx, entry.ID, params)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		contractCompareItem(t, bought, params)
+
+		if _, err := repo.GetShoppingListItem(ctx, entry.ID); !errors.Is(err, errShoppingListItemNotFound) {
+			t.Errorf("Expected the bought entry to be deleted, got %v", err)
+		}
+
+		// buying the entry again creates no other item
+		if _, err := repo.BuyShoppingListItem(ctx, entry.ID, params); !errors.Is(err, errShoppingListItemNotFound) {
+			t.Errorf("Expected errShoppingListItemNotFound, got %v", err)
+		}
+
+		if items, err := repo.GetItems(ctx, itemsQuery{}); err != nil || len(items) != 1 {
+			t.Errorf("Expected only the bought item, got %+v, %v", items, err)
+		}
+	})
+
+	t.Run("Expiry rules", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		milk := writeExpiryRuleParams{Type: getPtr("milk"), ShelfLife: getPtr(7), OpenedLifespan: getPtr(4)}
+		dairy := writeExpiryRuleParams{
+			Tag: getPtr("dairy"), ShelfLife: getPtr(10), FrozenShelfLife: getPtr(60), ThawedLifespan: getPtr(1),
+		}
+
+		for _, params := range []writeExpiryRuleParams{milk, dairy} {
+			created, err := repo.CreateExpiryRule(ctx, params)
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			if created.ID == "" || !reflect.DeepEqual(created, expiryRuleFromParams(created.ID, params)) {
+				t.Errorf("Expected the created rule to be %+v, got %+v", params, created)
+			}
+		}
+
+		rules, err := repo.GetExpiryRules(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		byKey := map[string]expiryRule{}
+		for _, r := range rules {
+			byKey[*cmp.Or(r.Type, r.Tag)] = r
+		}
+
+		if len(rules) != 2 ||
+			!reflect.DeepEqual(byKey["milk"].ShelfLife, milk.ShelfLife) ||
+			!reflect.DeepEqual(byKey["milk"].OpenedLifespan, milk.OpenedLifespan) ||
+			byKey["milk"].Tag != nil ||
+			!reflect.DeepEqual(byKey["dairy"].Tag, dairy.Tag) ||
+			!reflect.DeepEqual(byKey["dairy"].FrozenShelfLife, dairy.FrozenShelfLife) ||
+			!reflect.DeepEqual(byKey["dairy"].ThawedLifespan, dairy.ThawedLifespan) ||
+			byKey["milk"].FrozenShelfLife != nil ||
+			byKey["dairy"].Type != nil || byKey["dairy"].OpenedLifespan != nil {
+			t.Fatalf("Got rules %+v instead of %+v and %+v", rules, milk, dairy)
+		}
+
+		dairy.OpenedLifespan = getPtr(5)
+
+		if err := repo.UpdateExpiryRule(ctx, byKey["dairy"].ID, dairy); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.UpdateExpiryRule(ctx, "missing", dairy); !errors.Is(err, errExpiryRuleNotFound) {
+			t.Errorf("Expected errExpiryRuleNotFound, got %v", err)
+		}
+
+		if err := repo.DeleteExpiryRule(ctx, byKey["milk"].ID); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.DeleteExpiryRule(ctx, byKey["milk"].ID); !errors.Is(err, errExpiryRuleNotFound) {
+			t.Errorf("Expected errExpiryRuleNotFound deleting again, got %v", err)
+		}
+
+		rules, err = repo.GetExpiryRules(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(rules) != 1 || !reflect.DeepEqual(rules[0].OpenedLifespan, getPtr(5)) {
+			t.Errorf("Expected only the updated dairy rule to remain, got %+v", rules)
+		}
+	})
+
+	t.Run("Households", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		if _, err := repo.GetUserHousehold(ctx, "alice"); !errors.Is(err, errHouseholdNotFound) {
+			t.Errorf("Expected errHouseholdNotFound, got %v", err)
+		}
+
+		created, err := repo.CreateHousehold(ctx, "Smiths", "alice")
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if created.ID == "" || created.ID == defaultHouseholdID || created.Name != "Smiths" {
+			t.Errorf("Got invalid household %+v", created)
+		}
+
+		if _, err := repo.CreateHousehold(ctx, "Other", "alice"); !errors.Is(err, errHouseholdMemberExists) {
+			t.Errorf("Expected errHouseholdMemberExists, got %v", err)
+		}
+
+		if err := repo.AddHouseholdMember(ctx, created.ID, "bob", householdRoleViewer); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.AddHouseholdMember(ctx, defaultHouseholdID, "carol", householdRoleEditor); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		err = repo.AddHouseholdMember(ctx, defaultHouseholdID, "bob", householdRoleOwner)
+		if !errors.Is(err, errHouseholdMemberExists) {
+			t.Errorf("Expected errHouseholdMemberExists, got %v", err)
+		}
+
+		if err := repo.AddHouseholdMember(ctx, "missing", "dave", householdRoleOwner); !errors.Is(err, errHouseholdNotFound) {
+			t.Errorf("Expected errHouseholdNotFound, got %v", err)
+		}
+
+		defaultHousehold := household{ID: defaultHouseholdID, Name: defaultHouseholdName}
+
+		for uid, expected := range map[string]userHousehold{
+			"alice": {household: created, Role: householdRoleOwner},
+			"bob":   {household: created, Role: householdRoleViewer},
+			"carol": {household: defaultHousehold, Role: householdRoleEditor},
+		} {
+			if uh, err := repo.GetUserHousehold(ctx, uid); err != nil || uh != expected {
+				t.Errorf("Expected %s to belong to %+v, got %+v with error %v", uid, expected, uh, err)
+			}
+		}
+
+		if h, err := repo.GetHousehold(ctx, created.ID); err != nil || h != created {
+			t.Errorf("Expected %+v, got %+v with error %v", created, h, err)
+		}
+
+		if _, err := repo.GetHousehold(ctx, "missing"); !errors.Is(err, errHouseholdNotFound) {
+			t.Errorf("Expected errHouseholdNotFound, got %v", err)
+		}
+
+		households, err := repo.GetHouseholds(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(households) != 2 || !slices.Contains(households, created) ||
+			!slices.Contains(households, defaultHousehold) {
+			t.Errorf("Expected the default household and %+v, got %+v", created, households)
+		}
+	})
+
+	t.Run("Household members and invites", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		smiths, err := repo.CreateHousehold(ctx, "Smiths", "alice")
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		for householdID, uid := range map[string]string{smiths.ID: "bob", defaultHouseholdID: "carol"} {
+			if err := repo.AddHouseholdMember(ctx, householdID, uid, householdRoleEditor); err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+		}
+
+		members, err := repo.GetHouseholdMembers(ctx, smiths.ID)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		expectedMembers := []householdMember{
+			{UID: "alice", Role: householdRoleOwner}, {UID: "bob", Role: householdRoleEditor},
+		}
+		if !reflect.DeepEqual(members, expectedMembers) {
+			t.Errorf("Expected %+v, got %+v", expectedMembers, members)
+		}
+
+		if err := repo.UpdateHouseholdMemberRole(ctx, smiths.ID, "bob", householdRoleViewer); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if uh, err := repo.GetUserHousehold(ctx, "bob"); err != nil || uh.Role != householdRoleViewer {
+			t.Errorf("Expected bob to become a viewer, got %+v with error %v", uh, err)
+		}
+
+		err = repo.UpdateHouseholdMemberRole(ctx, defaultHouseholdID, "bob", householdRoleOwner)
+		if !errors.Is(err, errHouseholdMemberNotFound) {
+			t.Errorf("Expected errHouseholdMemberNotFound, got %v", err)
+		}
+
+		if err := repo.RemoveHouseholdMember(ctx, defaultHouseholdID, "bob"); !errors.Is(err, errHouseholdMemberNotFound) {
+			t.Errorf("Expected errHouseholdMemberNotFound, got %v", err)
+		}
+
+		if err := repo.RemoveHouseholdMember(ctx, smiths.ID, "bob"); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if _, err := repo.GetUserHousehold(ctx, "bob"); !errors.Is(err, errHouseholdNotFound) {
+			t.Errorf("Expected errHouseholdNotFound for the removed member, got %v", err)
+		}
+
+		invites := []householdInvite{
+			{
+				Code: "valid", HouseholdID: smiths.ID, Role: householdRoleViewer, CreatedBy: "alice",
+				ExpiresAt: now.Add(time.Hour),
+			},
+			{
+				Code: "expired", HouseholdID: smiths.ID, Role: householdRoleEditor, CreatedBy: "alice",
+				ExpiresAt: now.Add(-time.Hour),
+			},
+			// the role of an invite defaults to editor
+			{Code: "default", HouseholdID: defaultHouseholdID, CreatedBy: "carol", ExpiresAt: now.Add(time.Hour)},
+		}
+
+		for _, invite := range invites {
+			if err := repo.CreateHouseholdInvite(ctx, invite); err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+		}
+
+		// carol moves from the default household to the one of the invite, with the role of the invite
+		expected := userHousehold{household: smiths, Role: householdRoleViewer}
+
+		if uh, err := repo.AcceptHouseholdInvite(ctx, "valid", "carol", now); err != nil || uh != expected {
+			t.Errorf("Expected to join %+v, got %+v with error %v", expected, uh, err)
+		}
+
+		if uh, err := repo.GetUserHousehold(ctx, "carol"); err != nil || uh != expected {
+			t.Errorf("Expected carol to belong to %+v, got %+v with error %v", expected, uh, err)
+		}
+
+		if members, err := repo.GetHouseholdMembers(ctx, defaultHouseholdID); err != nil || len(members) != 0 {
+			t.Errorf("Expected no members left in the default household, got %+v with error %v", members, err)
+		}
+
+		// the invites can be accepted once
+		if _, err := repo.AcceptHouseholdInvite(ctx, "valid", "bob", now); !errors.Is(err, errHouseholdInviteNotFound) {
+			t.Errorf("Expected errHouseholdInviteNotFound, got %v", err)
+		}
+
+		if _, err := repo.AcceptHouseholdInvite(ctx, "expired", "bob", now); !errors.Is(err, errHouseholdInviteExpired) {
+			t.Errorf("Expected errHouseholdInviteExpired, got %v", err)
+		}
+
+		// a user without a household can join the default one
+		if h, err := repo.AcceptHouseholdInvite(ctx, "default", "bob", now); err != nil ||
+			h.ID != defaultHouseholdID || h.Role != householdRoleEditor {
+			t.Errorf("Expected to join the default household as an editor, got %+v with error %v", h, err)
+		}
+
+		if members, err := repo.GetHouseholdMembers(ctx, defaultHouseholdID); err != nil ||
+			!reflect.DeepEqual(members, []householdMember{{UID: "bob", Role: householdRoleEditor}}) {
+			t.Errorf("Expected bob in the default household, got %+v with error %v", members, err)
+		}
+	})
+
+	t.Run("Last household owner", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		smiths, err := repo.CreateHousehold(ctx, "Smiths", "alice")
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		// alone in the household, alice can give up being its owner
+		if err := repo.UpdateHouseholdMemberRole(ctx, smiths.ID, "alice", householdRoleEditor); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.UpdateHouseholdMemberRole(ctx, smiths.ID, "alice", householdRoleOwner); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.AddHouseholdMember(ctx, smiths.ID, "bob", householdRoleEditor); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		invite := householdInvite{
+			Code: "default", HouseholdID: defaultHouseholdID, CreatedBy: "carol", ExpiresAt: now.Add(time.Hour),
+		}
+		if err := repo.CreateHouseholdInvite(ctx, invite); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		err = repo.UpdateHouseholdMemberRole(ctx, smiths.ID, "alice", householdRoleViewer)
+		if !errors.Is(err, errHouseholdLastOwner) {
+			t.Errorf("Expected errHouseholdLastOwner demoting the last owner, got %v", err)
+		}
+
+		if err := repo.RemoveHouseholdMember(ctx, smiths.ID, "alice"); !errors.Is(err, errHouseholdLastOwner) {
+			t.Errorf("Expected errHouseholdLastOwner removing the last owner, got %v", err)
+		}
+
+		if _, err := repo.AcceptHouseholdInvite(ctx, "default", "alice", now); !errors.Is(err, errHouseholdLastOwner) {
+			t.Errorf("Expected errHouseholdLastOwner accepting an invite as the last owner, got %v", err)
+		}
+
+		members, err := repo.GetHouseholdMembers(ctx, smiths.ID)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		expectedMembers := []householdMember{
+			{UID: "alice", Role: householdRoleOwner}, {UID: "bob", Role: householdRoleEditor},
+		}
+		if !reflect.DeepEqual(members, expectedMembers) {
+			t.Errorf("Expected the members to stay %+v, got %+v", expectedMembers, members)
+		}
+
+		// with bob as another owner, alice can leave
+		if err := repo.UpdateHouseholdMemberRole(ctx, smiths.ID, "bob", householdRoleOwner); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if _, err := repo.AcceptHouseholdInvite(ctx, "default", "alice", now); err != nil {
+			t.Errorf("Got error: %s", err)
+		}
+	})
+
+	t.Run("Access tokens", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		tokens := []accessToken{
+			{
+				ID: "1", UID: "alice", Name: "Scripts", Scopes: []permission{permissionUse, permissionView},
+				CreatedAt: now.Add(-time.Hour), ExpiresAt: getPtr(now.Add(time.Hour)), Hash: "hash1",
+			},
+			{ID: "2", UID: "alice", Name: "Home", Scopes: []permission{}, CreatedAt: now, Hash: "hash2"},
+			{ID: "3", UID: "bob", Name: "Bob", Scopes: []permission{}, CreatedAt: now, Hash: "hash3"},
+		}
+
+		for _, token := range tokens {
+			if err := repo.CreateAccessToken(ctx, token); err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+		}
+
+		aliceTokens, err := repo.GetAccessTokens(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !reflect.DeepEqual(aliceTokens, tokens[:2]) {
+			t.Errorf("Expected %+v, got %+v", tokens[:2], aliceTokens)
+		}
+
+		if token, err := repo.GetAccessTokenByHash(ctx, "hash1"); err != nil || !reflect.DeepEqual(token, tokens[0]) {
+			t.Errorf("Expected %+v, got %+v with error %v", tokens[0], token, err)
+		}
+
+		if _, err := repo.GetAccessTokenByHash(ctx, "missing"); !errors.Is(err, errAccessTokenNotFound) {
+			t.Errorf("Expected errAccessTokenNotFound, got %v", err)
+		}
+
+		// the users can only delete their own tokens
+		if err := repo.DeleteAccessToken(ctx, "bob", "1"); !errors.Is(err, errAccessTokenNotFound) {
+			t.Errorf("Expected errAccessTokenNotFound, got %v", err)
+		}
+
+		if err := repo.DeleteAccessToken(ctx, "alice", "1"); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if _, err := repo.GetAccessTokenByHash(ctx, "hash1"); !errors.Is(err, errAccessTokenNotFound) {
+			t.Errorf("Expected errAccessTokenNotFound for the deleted token, got %v", err)
+		}
+
+		if tokens, err := repo.GetAccessTokens(ctx, "alice"); err != nil || len(tokens) != 1 {
+			t.Errorf("Expected a single token left, got %+v with error %v", tokens, err)
+		}
+	})
+
+	t.Run("User emails", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		for uid, email := range map[string]string{"bob": "bob@example.com", "alice": "old@example.com"} {
+			if err := repo.SetUserEmail(ctx, uid, email); err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+		}
+
+		// the email address of a user is replaced
+		if err := repo.SetUserEmail(ctx, "alice", "alice@example.com"); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		scenarios := []struct {
+			uids     *[]string
+			expected []string
+		}{
+			{expected: []string{"alice@example.com", "bob@example.com"}},
+			{uids: &[]string{"bob", "carol"}, expected: []string{"bob@example.com"}},
+			{uids: &[]string{}, expected: []string{}},
+		}
+
+		for _, s := range scenarios {
+			emails, err := repo.GetUserEmails(ctx, s.uids)
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+
+			if !reflect.DeepEqual(emails, s.expected) {
+				t.Errorf("Expected %v for %v, got %v", s.expected, s.uids, emails)
+			}
+		}
+	})
+
+	t.Run("Household isolation", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t)
+
+		smiths, err := repo.CreateHousehold(context.Background(), "Smiths", "alice")
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		ctx := withHouseholdID(context.Background(), smiths.ID)
+		// requests without a household use the default one
+		otherCtx := context.Background()
+
+		fridge, err := repo.CreateLocation(ctx, "Fridge")
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		milk := writeItemParams{
+			Name: "Milk", Tags: []string{"dairy"}, BoughtAt: boughtAt, LocationID: &fridge.ID, Quantity: getPtr(1.0),
+		}
+
+		created, err := repo.CreateItem(ctx, milk)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		_, err = repo.CreateShoppingListItem(ctx, writeShoppingListItemParams{Name: "Bread", Tags: []string{}})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		rule := writeExpiryRuleParams{Type: getPtr("milk"), ShelfLife: getPtr(7)}
+		if _, err := repo.CreateExpiryRule(ctx, rule); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if locs, err := repo.GetLocations(otherCtx, locationsQuery{}); err != nil || len(locs) != 0 {
+			t.Errorf("Expected no locations in the other household, got %+v with error %v", locs, err)
+		}
+
+		if locs, err := repo.GetLocations(otherCtx, locationsQuery{IDs: &[]string{fridge.ID}}); err != nil ||
+			len(locs) != 0 {
+			t.Errorf("Expected no locations by ID in the other household, got %+v with error %v", locs, err)
+		}
+
+		if items, err := repo.GetItems(otherCtx, itemsQuery{}); err != nil || len(items) != 0 {
+			t.Errorf("Expected no items in the other household, got %+v with error %v", items, err)
+		}
+
+		if _, err := repo.GetItem(otherCtx, created.ID); !errors.Is(err, errItemNotFound) {
+			t.Errorf("Expected errItemNotFound, got %v", err)
+		}
+
+		if err := repo.UpdateItem(otherCtx, created.ID, milk, &created.Version); !errors.Is(err, errItemNotFound) {
+			t.Errorf("Expected errItemNotFound, got %v", err)
+		}
+
+		if err := repo.UpdateLocation(otherCtx, fridge.ID, "Mine", nil); !errors.Is(err, errLocationNotFound) {
+			t.Errorf("Expected errLocationNotFound, got %v", err)
+		}
+
+		if err := repo.DeleteItem(otherCtx, created.ID, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if err := repo.DeleteLocation(otherCtx, fridge.ID, nil); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if entries, err := repo.GetShoppingListItems(otherCtx); err != nil || len(entries) != 0 {
+			t.Errorf("Expected an empty shopping list in the other household, got %+v with error %v", entries, err)
+		}
+
+		if rules, err := repo.GetExpiryRules(otherCtx); err != nil || len(rules) != 0 {
+			t.Errorf("Expected no expiry rules in the other household, got %+v with error %v", rules, err)
+		}
+
+		// the same rule can exist once in every household
+		if _, err := repo.CreateExpiryRule(otherCtx, rule); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		// the writes of the other household did not change anything
+		stored, err := repo.GetItem(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if !reflect.DeepEqual(stored, created) {
+			t.Errorf("Expected %+v to be unchanged, got %+v", created, stored)
+		}
+
+		if locs, err := repo.GetLocations(ctx, locationsQuery{}); err != nil || !reflect.DeepEqual(locs, []location{fridge}) {
+			t.Errorf("Expected only %+v, got %+v with error %v", fridge, locs, err)
+		}
+
+		if rules, err := repo.GetExpiryRules(ctx); err != nil || len(rules) != 1 {
+			t.Errorf("Expected a single expiry rule, got %+v with error %v", rules, err)
+		}
+	})
+}
+
+// contractCreateLocation creates a location and returns it, looking it up by its name.
+func contractCreateLocation(t *testing.T, repo repository, name string) location {
+	t.Helper()
+
+	ctx := context.Background()
+
+	created, err := repo.CreateLocation(ctx, name)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	locs, err := repo.GetLocations(ctx, locationsQuery{IDs: &[]string{created.ID}})
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if !reflect.DeepEqual(locs, []location{created}) || created.Name != name {
+		t.Fatalf("Created location %s returned as %+v is stored as %+v", name, created, locs)
+	}
+
+	return created
+}
+
+// contractCreateItem creates the item and checks that it is returned as stored.
+func contractCreateItem(t *testing.T, repo repository, params writeItemParams) item {
+	t.Helper()
+
+	ctx := context.Background()
+
+	created, err := repo.CreateItem(ctx, params)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	contractCompareItem(t, created, params)
+
+	stored, err := repo.GetItem(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if !reflect.DeepEqual(created, stored) {
+		t.Fatalf("Created item %+v is stored as %+v", created, stored)
+	}
+
+	return created
+}
+
+// contractGetItemsByName returns the filtered items keyed by their names, which are unique within a test.
+func contractGetItemsByName(t *testing.T, repo repository, query itemsQuery) map[string]item {
+	t.Helper()
+
+	items, err := repo.GetItems(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	res := map[string]item{}
+	for _, i := range items {
+		res[i.Name] = i
+	}
+
+	return res
+}
+
+func contractLocationNames(locs []location) []string {
+	names := []string{}
+	for _, l := range locs {
+		names = append(names, l.Name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+func contractEqualTimes(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+//nolint:cyclop
+func contractCompareItem(t *testing.T, i item, params writeItemParams) {
+	t.Helper()
+
+	if i.Name != params.Name ||
+		!reflect.DeepEqual(i.Type, params.Type) ||
+		!reflect.DeepEqual(i.Tags, params.Tags) ||
+		!reflect.DeepEqual(i.Price, params.Price) ||
+		!i.BoughtAt.Equal(params.BoughtAt) ||
+		!contractEqualTimes(i.OpenedAt, params.OpenedAt) ||
+		!contractEqualTimes(i.ExpiresAt, params.ExpiresAt) ||
+		!reflect.DeepEqual(i.Lifespan, params.Lifespan) ||
+		!reflect.DeepEqual(i.LocationID, params.LocationID) ||
+		!reflect.DeepEqual(i.Quantity, params.Quantity) ||
+		!reflect.DeepEqual(i.InitialQuantity, params.InitialQuantity) ||
+		!reflect.DeepEqual(i.Unit, params.Unit) ||
+		!contractEqualTimes(i.ArchivedAt, params.ArchivedAt) ||
+		!reflect.DeepEqual(i.Outcome, params.Outcome) ||
+		!contractEqualTimes(i.FrozenAt, params.FrozenAt) ||
+		!contractEqualTimes(i.ThawedAt, params.ThawedAt) {
+		t.Errorf("Got item %+v instead of %+v", i, params)
+	}
+}
+
+func contractCompareShoppingListItem(t *testing.T, e shoppingListItem, params writeShoppingListItemParams) {
+	t.Helper()
+
+	if e.Name != params.Name ||
+		!reflect.DeepEqual(e.Type, params.Type) ||
+		!reflect.DeepEqual(e.Tags, params.Tags) ||
+		!reflect.DeepEqual(e.Quantity, params.Quantity) ||
+		!reflect.DeepEqual(e.Unit, params.Unit) ||
+		!reflect.DeepEqual(e.LocationID, params.LocationID) {
+		t.Errorf("Got shopping list item %+v instead of %+v", e, params)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type shoppingListItem struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	Type *string  `json:"type"`
+	Tags []string `json:"tags"`
+	// Quantity and Unit are how much to buy
+	Quantity *float64  `json:"quantity"`
+	Unit     *itemUnit `json:"unit"`
+	// LocationID is where the item goes once bought
+	LocationID *string `json:"locationId"`
+}
+
+type writeShoppingListItemParams struct {
+	Name       string    `json:"name"       validate:"required,min=2"`
+	Type       *string   `json:"type"`
+	Tags       []string  `json:"tags"       validate:"required"`
+	Quantity   *float64  `json:"quantity"   validate:"required_with=Unit,omitempty,gt=0"`
+	Unit       *itemUnit `json:"unit"       validate:"required_with=Quantity,omitempty,oneof=count g kg ml l"`
+	LocationID *string   `json:"locationId"`
+}
+
+type buyShoppingListItemParams struct {
+	Price *int `json:"price"`
+	// BoughtAt defaults to the current time
+	BoughtAt *time.Time `json:"boughtAt"`
+	// LocationID overrides the location of the list entry
+	LocationID *string    `json:"locationId"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+var errShoppingListItemNotFound = errors.New("shopping list item not found")
+
+// shoppingListParamsFromItem returns the params of a list entry buying the item again.
+func shoppingListParamsFromItem(i item) writeShoppingListItemParams {
+	return writeShoppingListItemParams{
+		Name:       i.Name,
+		Type:       i.Type,
+		Tags:       i.Tags,
+		Quantity:   i.InitialQuantity,
+		Unit:       i.Unit,
+		LocationID: i.LocationID,
+	}
+}
+
+// addItemToShoppingList adds a list entry buying the item again.
+func addItemToShoppingList(ctx context.Context, repo repository, i item) error {
+	if _, err := repo.CreateShoppingListItem(ctx, shoppingListParamsFromItem(i)); err != nil {
+		return fmt.Errorf("create shopping list item: %w", err)
+	}
+
+	return nil
+}
+
+func getShoppingList(ctx context.Context, repo repository) ([]shoppingListItem, error) {
+	entries, err := repo.GetShoppingListItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get shopping list items: %w", err)
+	}
+
+	return entries, nil
+}
+
+func getShoppingListItem(ctx context.Context, repo repository, id string) (shoppingListItem, error) {
+	entry, err := repo.GetShoppingListItem(ctx, id)
+	if err != nil {
+		return shoppingListItem{}, fmt.Errorf("get shopping list item: %w", err)
+	}
+
+	return entry, nil
+}
+
+// createShoppingListItem adds the entry to the shopping list, returning it as stored.
+func createShoppingListItem(
+	ctx context.Context, repo repository, validate *validator.Validate, params writeShoppingListItemParams,
+) (shoppingListItem, error) {
+	if err := validate.Struct(params); err != nil {
+		return shoppingListItem{}, fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	entry, err := repo.CreateShoppingListItem(ctx, params)
+	if err != nil {
+		return shoppingListItem{}, fmt.Errorf("create shopping list item: %w", err)
+	}
+
+	return entry, nil
+}
+
+func updateShoppingListItem(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, params writeShoppingListItemParams,
+) error {
+	if err := validate.Struct(params); err != nil {
+		return fmt.Errorf("%w: %w", errValidation, err)
+	}
+
+	if err := repo.UpdateShoppingListItem(ctx, id, params); err != nil {
+		return fmt.Errorf("update shopping list item: %w", err)
+	}
+
+	return nil
+}
+
+func deleteShoppingListItem(ctx context.Context, repo repository, id string) error {
+	if err := repo.DeleteShoppingListItem(ctx, id); err != nil {
+		return fmt.Errorf("delete shopping list item: %w", err)
+	}
+
+	return nil
+}
+
+// buyShoppingListItem creates an item from the list entry and removes the entry from the list at once, returning the
+// item. Buying an entry again returns errShoppingListItemNotFound, so that a repeated request creates no duplicate.
+func buyShoppingListItem(
+	ctx context.Context, repo repository, validate *validator.Validate, id string, params buyShoppingListItemParams,
+) (item, error) {
+	entry, err := repo.GetShoppingListItem(ctx, id)
+	if err != nil {
+		return item{}, fmt.Errorf("get shopping list item: %w", err)
+	}
+
+	itemParams := writeItemParams{
+		Name:       entry.Name,
+		Type:       entry.Type,
+		Tags:       entry.Tags,
+		Price:      params.Price,
+		BoughtAt:   *cmp.Or(params.BoughtAt, getPtr(time.Now())),
+		ExpiresAt:  params.ExpiresAt,
+		LocationID: cmp.Or(params.LocationID, entry.LocationID),
+		Quantity:   entry.Quantity,
+		Unit:       entry.Unit,
+	}
+
+	return storeNewItem(ctx, repo, validate, itemParams, func(ctx context.Context, params writeItemParams) (item, error) {
+		i, err := repo.BuyShoppingListItem(ctx, id, params)
+		if err != nil {
+			return item{}, fmt.Errorf("buy shopping list item: %w", err)
+		}
+
+		return i, nil
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestCreateShoppingListItem(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	scenarios := []struct {
+		params writeShoppingListItemParams
+		valid  bool
+	}{
+		{params: writeShoppingListItemParams{Name: "Milk", Tags: []string{"dairy"}}, valid: true},
+		{
+			params: writeShoppingListItemParams{
+				Name: "Milk", Tags: []string{}, Quantity: getPtr(2.0), Unit: getPtr(itemUnitCount),
+			},
+			valid: true,
+		},
+		{params: writeShoppingListItemParams{Name: "M", Tags: []string{}}},
+		{params: writeShoppingListItemParams{Name: "Milk"}},
+		{params: writeShoppingListItemParams{Name: "Milk", Tags: []string{}, Quantity: getPtr(2.0)}},
+		{
+			params: writeShoppingListItemParams{
+				Name: "Milk", Tags: []string{}, Quantity: getPtr(0.0), Unit: getPtr(itemUnitCount),
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		mockRepo := &mockRepository{}
+
+		_, err := createShoppingListItem(context.Background(), mockRepo, validate, s.params)
+		if s.valid && err != nil {
+			t.Errorf("Got error for %+v: %s", s.params, err)
+		} else if !s.valid && !errors.Is(err, errValidation) {
+			t.Errorf("Expected a validation error for %+v, got %v", s.params, err)
+		}
+
+		if (mockRepo.CreateShoppingListItemCalls == 1) != s.valid {
+			t.Errorf("Creating %+v called CreateShoppingListItem %d times", s.params, mockRepo.CreateShoppingListItemCalls)
+		}
+	}
+}
+
+func TestFinishItemAddToShoppingList(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	mockRepo := &mockRepository{GetItemRes: item{
+		ID: "milk", Name: "Milk", Type: getPtr("1l"), Tags: []string{"dairy"}, LocationID: getPtr("fridge"),
+		Quantity: getPtr(0.5), InitialQuantity: getPtr(2.0), Unit: getPtr(itemUnitCount),
+	}}
+
+	params := finishItemParams{Outcome: itemOutcomeConsumed, AddToShoppingList: true}
+	if _, err := finishItem(context.Background(), mockRepo, validate, "milk", params); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	expected := writeShoppingListItemParams{
+		Name: "Milk", Type: getPtr("1l"), Tags: []string{"dairy"},
+		Quantity: getPtr(2.0), Unit: getPtr(itemUnitCount), LocationID: getPtr("fridge"),
+	}
+
+	if mockRepo.CreateShoppingListItemCalls != 1 || !reflect.DeepEqual(mockRepo.CreateShoppingListItemParams, expected) {
+		t.Errorf("Expected the item to be listed as %+v, got %+v", expected, mockRepo.CreateShoppingListItemParams)
+	}
+}
+
+func TestBuyShoppingListItem(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		params     buyShoppingListItemParams
+		locationID *string
+	}{
+		{params: buyShoppingListItemParams{Price: getPtr(299), BoughtAt: &boughtAt}, locationID: getPtr("fridge")},
+		{params: buyShoppingListItemParams{LocationID: getPtr("cellar")}, locationID: getPtr("cellar")},
+	}
+
+	for _, s := range scenarios {
+		repo := newMemoryRepository()
+
+		_, err := repo.CreateShoppingListItem(ctx, writeShoppingListItemParams{
+			Name: "Milk", Type: getPtr("1l"), Tags: []string{"dairy"},
+			Quantity: getPtr(2.0), Unit: getPtr(itemUnitCount), LocationID: getPtr("fridge"),
+		})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		entries, err := repo.GetShoppingListItems(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		bought, err := buyShoppingListItem(ctx, repo, validate, entries[0].ID, s.params)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		items, err := repo.GetItems(ctx, itemsQuery{})
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(items) != 1 {
+			t.Fatalf("Expected one bought item, got %+v", items)
+		}
+
+		i := items[0]
+		if bought.ID != i.ID || bought.Version != i.Version {
+			t.Errorf("Returned %+v instead of the bought item %+v", bought, i)
+		}
+
+		if i.Name != "Milk" || !reflect.DeepEqual(i.Type, getPtr("1l")) || !reflect.DeepEqual(i.Tags, []string{"dairy"}) ||
+			!reflect.DeepEqual(i.Price, s.params.Price) || !reflect.DeepEqual(i.LocationID, s.locationID) ||
+			!reflect.DeepEqual(i.Quantity, getPtr(2.0)) || !reflect.DeepEqual(i.InitialQuantity, getPtr(2.0)) {
+			t.Errorf("Buying with %+v created %+v", s.params, i)
+		}
+
+		if s.params.BoughtAt != nil && !i.BoughtAt.Equal(*s.params.BoughtAt) {
+			t.Errorf("Bought at %v instead of %v", i.BoughtAt, s.params.BoughtAt)
+		} else if s.params.BoughtAt == nil && time.Since(i.BoughtAt) > time.Minute {
+			t.Errorf("Bought at %v instead of now", i.BoughtAt)
+		}
+
+		if entries, _ := repo.GetShoppingListItems(ctx); len(entries) != 0 {
+			t.Errorf("Expected the bought entry to be removed, got %+v", entries)
+		}
+
+		// a repeated request buys nothing
+		_, err = buyShoppingListItem(ctx, repo, validate, entries[0].ID, s.params)
+		if !errors.Is(err, errShoppingListItemNotFound) {
+			t.Errorf("Expected errShoppingListItemNotFound buying again, got %v", err)
+		}
+
+		if items, _ := repo.GetItems(ctx, itemsQuery{}); len(items) != 1 {
+			t.Errorf("Expected no other item buying again, got %+v", items)
+		}
+	}
+}
+
+func TestBuyShoppingListItemNotFound(t *testing.T) {
+	t.Parallel()
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	repo := newMemoryRepository()
+
+	_, err := buyShoppingListItem(context.Background(), repo, validate, "missing", buyShoppingListItemParams{})
+	if !errors.Is(err, errShoppingListItemNotFound) {
+		t.Errorf("Expected errShoppingListItemNotFound, got %v", err)
+	}
+}
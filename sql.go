@@ -0,0 +1,1527 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+const sqlItemColumns = "id, name, type, price, bought_at, opened_at, expires_at, lifespan, location_id, " +
+	"quantity, initial_quantity, unit, archived_at, outcome, frozen_at, thawed_at, version"
+
+// sqlDialect describes the differences between the SQL databases sharing sqlRepository.
+type sqlDialect struct {
+	name string
+	// numberedPlaceholders makes queries use $1, $2... instead of ? placeholders
+	numberedPlaceholders bool
+	// migrationLock is executed at the start of the migration transaction to keep concurrent instances out
+	migrationLock string
+}
+
+// sqlRepository implements the repository on top of database/sql, it is embedded by the SQL backends.
+type sqlRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+	tracer  trace.Tracer
+}
+
+// rebind converts the ? placeholders of a query into the placeholders of the dialect.
+func (repo sqlRepository) rebind(query string) string {
+	if !repo.dialect.numberedPlaceholders {
+		return query
+	}
+
+	var b strings.Builder
+
+	n := 0
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (repo sqlRepository) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return repo.db.QueryContext(ctx, repo.rebind(query), args...) //nolint:wrapcheck
+}
+
+func (repo sqlRepository) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return repo.db.ExecContext(ctx, repo.rebind(query), args...) //nolint:wrapcheck
+}
+
+func (repo sqlRepository) execTx(ctx context.Context, tx *sql.Tx, query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(ctx, repo.rebind(query), args...) //nolint:wrapcheck
+}
+
+// inTx runs fn in a transaction, committing it when fn succeeds and rolling it back otherwise.
+func (repo sqlRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sql begin transaction: %w", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sql commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// migrate applies the embedded migrations of the dialect that were not applied yet, in order of their version.
+// Migration files are named <version>_<description>.sql.
+func (repo sqlRepository) migrate(ctx context.Context) error {
+	dir := path.Join("migrations", repo.dialect.name)
+
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return fmt.Errorf("read migrations: %w", err)
+	}
+
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		if repo.dialect.migrationLock != "" {
+			if _, err := tx.ExecContext(ctx, repo.dialect.migrationLock); err != nil {
+				return fmt.Errorf("lock migrations: %w", err)
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL
+		)`)
+		if err != nil {
+			return fmt.Errorf("create schema migrations table: %w", err)
+		}
+
+		applied, err := repo.getAppliedMigrations(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			versionStr, _, _ := strings.Cut(entry.Name(), "_")
+
+			version, err := strconv.Atoi(versionStr)
+			if err != nil {
+				return fmt.Errorf("parse migration version of %s: %w", entry.Name(), err)
+			}
+
+			if slices.Contains(applied, version) {
+				continue
+			}
+
+			migration, err := fs.ReadFile(migrationsFS, path.Join(dir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("read migration %s: %w", entry.Name(), err)
+			}
+
+			if _, err := tx.ExecContext(ctx, string(migration)); err != nil {
+				return fmt.Errorf("apply migration %s: %w", entry.Name(), err)
+			}
+
+			_, err = repo.execTx(ctx, tx,
+				"INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)",
+				version, time.Now().UTC(),
+			)
+			if err != nil {
+				return fmt.Errorf("record migration %s: %w", entry.Name(), err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repo sqlRepository) getAppliedMigrations(ctx context.Context, tx *sql.Tx) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("get applied migrations: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	versions := []int{}
+
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan applied migration: %w", err)
+		}
+
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate applied migrations: %w", err)
+	}
+
+	return versions, nil
+}
+
+// sqlPlaceholders returns a comma separated query placeholder for each value, along with the values as args.
+func sqlPlaceholders(values []string) (string, []any) {
+	args := make([]any, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+
+	return strings.TrimSuffix(strings.Repeat("?,", len(values)), ","), args
+}
+
+func sqlTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	return getPtr(t.UTC())
+}
+
+func sqlToItems(rows *sql.Rows) ([]item, error) {
+	items := []item{}
+
+	for rows.Next() {
+		i := item{Tags: []string{}}
+
+		err := rows.Scan(
+			&i.ID, &i.Name, &i.Type, &i.Price, &i.BoughtAt, &i.OpenedAt, &i.ExpiresAt, &i.Lifespan, &i.LocationID,
+			&i.Quantity, &i.InitialQuantity, &i.Unit, &i.ArchivedAt, &i.Outcome, &i.FrozenAt, &i.ThawedAt, &i.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("sql scan item: %w", err)
+		}
+
+		items = append(items, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate items: %w", err)
+	}
+
+	return items, nil
+}
+
+func (repo sqlRepository) fillItemTags(ctx context.Context, items []item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(items))
+	indexes := make(map[string]int, len(items))
+
+	for idx, i := range items {
+		ids = append(ids, i.ID)
+		indexes[i.ID] = idx
+	}
+
+	placeholders, args := sqlPlaceholders(ids)
+
+	rows, err := repo.query(ctx,
+		"SELECT item_id, tag FROM item_tags WHERE item_id IN ("+placeholders+") ORDER BY item_id, position",
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("sql get item tags: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var itemID, tag string
+		if err := rows.Scan(&itemID, &tag); err != nil {
+			return fmt.Errorf("sql scan item tag: %w", err)
+		}
+
+		items[indexes[itemID]].Tags = append(items[indexes[itemID]].Tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sql iterate item tags: %w", err)
+	}
+
+	return nil
+}
+
+// sqlVersion returns the condition matching the rows with the version, nil version matches every row.
+func sqlVersion(version *int, args []any) (string, []any) {
+	if version == nil {
+		return "", args
+	}
+
+	return " AND version = ?", append(args, *version)
+}
+
+// versionMismatchOr tells apart why a write checking the version did not change any row, returning
+// errVersionMismatch if the row with the id exists in the table and notFound otherwise.
+func (repo sqlRepository) versionMismatchOr(
+	ctx context.Context, tx *sql.Tx, table string, id string, version *int, notFound error,
+) error {
+	if version == nil {
+		return notFound
+	}
+
+	var exists bool
+
+	err := tx.QueryRowContext(ctx,
+		repo.rebind("SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = ? AND household_id = ?)"),
+		id, getHouseholdID(ctx),
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("sql check %s exists: %w", table, err)
+	}
+
+	if exists {
+		return errVersionMismatch
+	}
+
+	return notFound
+}
+
+// sqlLimit returns the LIMIT clause for the limit, zero limit returns all the rows.
+func sqlLimit(limit int, args []any) (string, []any) {
+	if limit == 0 {
+		return "", args
+	}
+
+	return " LIMIT ?", append(args, limit)
+}
+
+func (repo sqlRepository) GetLocations(ctx context.Context, q locationsQuery) ([]location, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetLocations")
+	defer span.End()
+
+	conditions, args := []string{"household_id = ?"}, []any{getHouseholdID(ctx)}
+
+	if q.IDs != nil {
+		if len(*q.IDs) == 0 {
+			return []location{}, nil
+		}
+
+		placeholders, idArgs := sqlPlaceholders(*q.IDs)
+		conditions = append(conditions, "id IN ("+placeholders+")")
+		args = append(args, idArgs...)
+	}
+
+	if q.After != nil {
+		conditions = append(conditions, "(name, id) > (?, ?)")
+		args = append(args, q.After.Name, q.After.ID)
+	}
+
+	query := "SELECT id, name, version FROM locations WHERE " + strings.Join(conditions, " AND ")
+
+	limit, args := sqlLimit(q.Limit, args)
+
+	rows, err := repo.query(ctx, query+" ORDER BY name, id"+limit, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql get locations: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	locations := []location{}
+
+	for rows.Next() {
+		var l location
+		if err := rows.Scan(&l.ID, &l.Name, &l.Version); err != nil {
+			return nil, fmt.Errorf("sql scan location: %w", err)
+		}
+
+		locations = append(locations, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+func (repo sqlRepository) CreateLocation(ctx context.Context, name string) (location, error) {
+	l := location{ID: uuid.NewString(), Name: name, Version: 1}
+
+	_, err := repo.exec(ctx,
+		"INSERT INTO locations (id, household_id, name, version) VALUES (?, ?, ?, ?)",
+		l.ID, getHouseholdID(ctx), l.Name, l.Version,
+	)
+	if err != nil {
+		return location{}, fmt.Errorf("sql create location: %w", err)
+	}
+
+	return l, nil
+}
+
+func (repo sqlRepository) UpdateLocation(ctx context.Context, id string, name string, version *int) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		versionCond, args := sqlVersion(version, []any{name, id, getHouseholdID(ctx)})
+
+		res, err := repo.execTx(ctx, tx,
+			"UPDATE locations SET name = ?, version = version + 1 WHERE id = ? AND household_id = ?"+versionCond, args...,
+		)
+		if err != nil {
+			return fmt.Errorf("sql update location: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("sql update location rows affected: %w", err)
+		} else if n == 0 {
+			return repo.versionMismatchOr(ctx, tx, "locations", id, version, errLocationNotFound)
+		}
+
+		return nil
+	})
+}
+
+func (repo sqlRepository) DeleteLocation(ctx context.Context, id string, version *int) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		versionCond, args := sqlVersion(version, []any{id, getHouseholdID(ctx)})
+
+		res, err := repo.execTx(ctx, tx, "DELETE FROM locations WHERE id = ? AND household_id = ?"+versionCond, args...)
+		if err != nil {
+			return fmt.Errorf("sql delete location: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("sql delete location rows affected: %w", err)
+		} else if n == 0 && version != nil {
+			return errVersionMismatch
+		}
+
+		_, err = repo.execTx(ctx, tx,
+			"UPDATE items SET location_id = NULL, version = version + 1 WHERE location_id = ? AND household_id = ?",
+			id, getHouseholdID(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("sql nullify item location: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// sqlItemsSortKeys maps the sorts to the expressions ordered by before the ID,
+// items without a price come first when sorting by it.
+var sqlItemsSortKeys = map[itemsSort]string{
+	itemsSortName:     "name",
+	itemsSortBoughtAt: "bought_at",
+	itemsSortPrice:    "COALESCE(price, -1)",
+}
+
+// sqlItemsSortValue returns the value of the sort key expression for the cursor.
+func sqlItemsSortValue(sort itemsSort, after itemsCursor) any {
+	switch sort {
+	case itemsSortName:
+		return after.Name
+	case itemsSortBoughtAt:
+		return sqlTime(&after.BoughtAt)
+	case itemsSortPrice:
+		return *cmp.Or(after.Price, getPtr(-1))
+	case itemsSortID, itemsSortDaysLeft:
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) GetItems(ctx context.Context, q itemsQuery) ([]item, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetItems")
+	defer span.End()
+
+	tags, locationIDs := q.Tags, q.LocationIDs
+
+	if (tags != nil && len(*tags) == 0) || (locationIDs != nil && len(*locationIDs) == 0) {
+		return []item{}, nil
+	}
+
+	conditions, args := []string{"household_id = ?"}, []any{getHouseholdID(ctx)}
+
+	if tags != nil {
+		placeholders, tagArgs := sqlPlaceholders(*tags)
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM item_tags WHERE item_tags.item_id = items.id AND item_tags.tag IN ("+placeholders+"))",
+		)
+		args = append(args, tagArgs...)
+	}
+
+	if locationIDs != nil {
+		placeholders, locationArgs := sqlPlaceholders(*locationIDs)
+		conditions = append(conditions, "location_id IN ("+placeholders+")")
+		args = append(args, locationArgs...)
+	}
+
+	if q.Unassigned {
+		conditions = append(conditions, "location_id IS NULL")
+	}
+
+	if q.OpenedOnly {
+		conditions = append(conditions, "opened_at IS NOT NULL")
+	}
+
+	if q.Archived {
+		conditions = append(conditions, "archived_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "archived_at IS NULL")
+	}
+
+	if q.Outcome != nil {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, *q.Outcome)
+	}
+
+	if q.BoughtFrom != nil {
+		conditions = append(conditions, "bought_at >= ?")
+		args = append(args, sqlTime(q.BoughtFrom))
+	}
+
+	if q.BoughtBefore != nil {
+		conditions = append(conditions, "bought_at < ?")
+		args = append(args, sqlTime(q.BoughtBefore))
+	}
+
+	sortKey, hasSortKey := sqlItemsSortKeys[q.Sort]
+
+	if q.After != nil && hasSortKey {
+		conditions = append(conditions, "("+sortKey+", id) > (?, ?)")
+		args = append(args, sqlItemsSortValue(q.Sort, *q.After), q.After.ID)
+	} else if q.After != nil {
+		conditions = append(conditions, "id > ?")
+		args = append(args, q.After.ID)
+	}
+
+	query := "SELECT " + sqlItemColumns + " FROM items WHERE " + strings.Join(conditions, " AND ")
+
+	orderBy := "id"
+	if hasSortKey {
+		orderBy = sortKey + ", id"
+	}
+
+	limit, args := sqlLimit(q.Limit, args)
+
+	rows, err := repo.query(ctx, query+" ORDER BY "+orderBy+limit, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql get items: %w", err)
+	}
+
+	items, err := sqlToItems(rows)
+	rows.Close() //nolint:errcheck,gosec
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.fillItemTags(ctx, items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (repo sqlRepository) GetItem(ctx context.Context, id string) (item, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetItem")
+	defer span.End()
+
+	rows, err := repo.query(ctx,
+		"SELECT "+sqlItemColumns+" FROM items WHERE id = ? AND household_id = ?", id, getHouseholdID(ctx),
+	)
+	if err != nil {
+		return item{}, fmt.Errorf("sql get item: %w", err)
+	}
+
+	items, err := sqlToItems(rows)
+	rows.Close() //nolint:errcheck,gosec
+
+	if err != nil {
+		return item{}, err
+	}
+
+	if len(items) == 0 {
+		return item{}, errItemNotFound
+	}
+
+	if err := repo.fillItemTags(ctx, items); err != nil {
+		return item{}, err
+	}
+
+	return items[0], nil
+}
+
+func (repo sqlRepository) writeItemTags(ctx context.Context, tx *sql.Tx, id string, tags []string) error {
+	if _, err := repo.execTx(ctx, tx, "DELETE FROM item_tags WHERE item_id = ?", id); err != nil {
+		return fmt.Errorf("sql delete item tags: %w", err)
+	}
+
+	for pos, tag := range tags {
+		_, err := repo.execTx(ctx, tx,
+			"INSERT INTO item_tags (item_id, position, tag) VALUES (?, ?, ?)",
+			id, pos, tag,
+		)
+		if err != nil {
+			return fmt.Errorf("sql insert item tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeItemTx applies the write in the transaction, returning the ID of the written item.
+func (repo sqlRepository) writeItemTx(ctx context.Context, tx *sql.Tx, w itemWrite) (string, error) {
+	switch w.Op {
+	case itemBatchCreate:
+		return repo.createItemTx(ctx, tx, w.Params)
+	case itemBatchUpdate:
+		return w.ID, repo.updateItemTx(ctx, tx, w.ID, w.Params, w.Version)
+	case itemBatchMove:
+		return w.ID, repo.updateItemLocationTx(ctx, tx, w.ID, w.LocationID, w.Version)
+	case itemBatchDelete:
+		return w.ID, repo.deleteItemTx(ctx, tx, w.ID, w.Version)
+	}
+
+	return "", fmt.Errorf("sql unknown item write %q", w.Op)
+}
+
+func (repo sqlRepository) createItemTx(ctx context.Context, tx *sql.Tx, params writeItemParams) (string, error) {
+	id := uuid.NewString()
+
+	_, err := repo.execTx(ctx, tx,
+		"INSERT INTO items ("+sqlItemColumns+", household_id) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, params.Name, params.Type, params.Price, params.BoughtAt.UTC(),
+		sqlTime(params.OpenedAt), sqlTime(params.ExpiresAt), params.Lifespan, params.LocationID,
+		params.Quantity, params.InitialQuantity, params.Unit, sqlTime(params.ArchivedAt), params.Outcome,
+		sqlTime(params.FrozenAt), sqlTime(params.ThawedAt), 1, getHouseholdID(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("sql create item: %w", err)
+	}
+
+	return id, repo.writeItemTags(ctx, tx, id, params.Tags)
+}
+
+func (repo sqlRepository) updateItemTx(
+	ctx context.Context, tx *sql.Tx, id string, params writeItemParams, version *int,
+) error {
+	versionCond, args := sqlVersion(version, []any{
+		params.Name, params.Type, params.Price, params.BoughtAt.UTC(),
+		sqlTime(params.OpenedAt), sqlTime(params.ExpiresAt), params.Lifespan, params.LocationID,
+		params.Quantity, params.InitialQuantity, params.Unit, sqlTime(params.ArchivedAt), params.Outcome,
+		sqlTime(params.FrozenAt), sqlTime(params.ThawedAt), id, getHouseholdID(ctx),
+	})
+
+	res, err := repo.execTx(ctx, tx,
+		`UPDATE items
+		SET name = ?, type = ?, price = ?, bought_at = ?, opened_at = ?, expires_at = ?, lifespan = ?, location_id = ?,
+			quantity = ?, initial_quantity = ?, unit = ?, archived_at = ?, outcome = ?, frozen_at = ?, thawed_at = ?,
+			version = version + 1
+		WHERE id = ? AND household_id = ?`+versionCond,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("sql update item: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql update item rows affected: %w", err)
+	} else if n == 0 {
+		return repo.versionMismatchOr(ctx, tx, "items", id, version, errItemNotFound)
+	}
+
+	return repo.writeItemTags(ctx, tx, id, params.Tags)
+}
+
+func (repo sqlRepository) updateItemLocationTx(
+	ctx context.Context, tx *sql.Tx, id string, locationID *string, version *int,
+) error {
+	versionCond, args := sqlVersion(version, []any{locationID, id, getHouseholdID(ctx)})
+
+	res, err := repo.execTx(ctx, tx,
+		"UPDATE items SET location_id = ?, version = version + 1 WHERE id = ? AND household_id = ?"+versionCond,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("sql update item location: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql update item location rows affected: %w", err)
+	} else if n == 0 {
+		return repo.versionMismatchOr(ctx, tx, "items", id, version, errItemNotFound)
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) deleteItemTx(ctx context.Context, tx *sql.Tx, id string, version *int) error {
+	versionCond, args := sqlVersion(version, []any{id, getHouseholdID(ctx)})
+
+	res, err := repo.execTx(ctx, tx, "DELETE FROM items WHERE id = ? AND household_id = ?"+versionCond, args...)
+	if err != nil {
+		return fmt.Errorf("sql delete item: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql delete item rows affected: %w", err)
+	} else if n == 0 && version != nil {
+		return errVersionMismatch
+	}
+
+	return nil
+}
+
+// writeItem applies the write in its own transaction.
+func (repo sqlRepository) writeItem(ctx context.Context, w itemWrite) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := repo.writeItemTx(ctx, tx, w)
+
+		return err
+	})
+}
+
+func (repo sqlRepository) CreateItem(ctx context.Context, params writeItemParams) (item, error) {
+	var id string
+
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+
+		id, err = repo.createItemTx(ctx, tx, params)
+
+		return err
+	})
+	if err != nil {
+		return item{}, err
+	}
+
+	// the item is read back to return it as stored, like with its times in UTC
+	return repo.GetItem(ctx, id)
+}
+
+func (repo sqlRepository) UpdateItem(ctx context.Context, id string, params writeItemParams, version *int) error {
+	return repo.writeItem(ctx, itemWrite{Op: itemBatchUpdate, ID: id, Params: params, Version: version})
+}
+
+func (repo sqlRepository) UpdateItemLocation(
+	ctx context.Context, id string, locationID *string, version *int,
+) error {
+	return repo.writeItem(ctx, itemWrite{Op: itemBatchMove, ID: id, LocationID: locationID, Version: version})
+}
+
+func (repo sqlRepository) DeleteItem(ctx context.Context, id string, version *int) error {
+	return repo.writeItem(ctx, itemWrite{Op: itemBatchDelete, ID: id, Version: version})
+}
+
+func (repo sqlRepository) ApplyItemWrites(ctx context.Context, writes []itemWrite) ([]string, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.ApplyItemWrites")
+	defer span.End()
+
+	ids := make([]string, len(writes))
+
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		for idx, w := range writes {
+			id, err := repo.writeItemTx(ctx, tx, w)
+			if err != nil {
+				return itemWriteError{Index: idx, Err: err}
+			}
+
+			ids[idx] = id
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+const sqlShoppingListItemColumns = "id, name, type, quantity, unit, location_id"
+
+// getShoppingListItems returns the list entries of the household matching the condition, which is joined to the
+// household one with AND.
+func (repo sqlRepository) getShoppingListItems(
+	ctx context.Context, condition string, args ...any,
+) ([]shoppingListItem, error) {
+	rows, err := repo.query(ctx,
+		"SELECT "+sqlShoppingListItemColumns+" FROM shopping_list_items WHERE household_id = ?"+condition+
+			" ORDER BY name, id",
+		append([]any{getHouseholdID(ctx)}, args...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sql get shopping list items: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	entries := []shoppingListItem{}
+	indexes := map[string]int{}
+
+	for rows.Next() {
+		e := shoppingListItem{Tags: []string{}}
+		if err := rows.Scan(&e.ID, &e.Name, &e.Type, &e.Quantity, &e.Unit, &e.LocationID); err != nil {
+			return nil, fmt.Errorf("sql scan shopping list item: %w", err)
+		}
+
+		indexes[e.ID] = len(entries)
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate shopping list items: %w", err)
+	}
+
+	rows.Close() //nolint:errcheck,gosec
+
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+
+	placeholders, tagArgs := sqlPlaceholders(ids)
+
+	tagRows, err := repo.query(ctx,
+		"SELECT shopping_list_item_id, tag FROM shopping_list_item_tags WHERE shopping_list_item_id IN ("+
+			placeholders+") ORDER BY shopping_list_item_id, position",
+		tagArgs...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sql get shopping list item tags: %w", err)
+	}
+
+	defer tagRows.Close() //nolint:errcheck
+
+	for tagRows.Next() {
+		var id, tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			return nil, fmt.Errorf("sql scan shopping list item tag: %w", err)
+		}
+
+		entries[indexes[id]].Tags = append(entries[indexes[id]].Tags, tag)
+	}
+
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate shopping list item tags: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (repo sqlRepository) GetShoppingListItems(ctx context.Context) ([]shoppingListItem, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetShoppingListItems")
+	defer span.End()
+
+	return repo.getShoppingListItems(ctx, "")
+}
+
+func (repo sqlRepository) GetShoppingListItem(ctx context.Context, id string) (shoppingListItem, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetShoppingListItem")
+	defer span.End()
+
+	entries, err := repo.getShoppingListItems(ctx, " AND id = ?", id)
+	if err != nil {
+		return shoppingListItem{}, err
+	}
+
+	if len(entries) == 0 {
+		return shoppingListItem{}, errShoppingListItemNotFound
+	}
+
+	return entries[0], nil
+}
+
+func (repo sqlRepository) writeShoppingListItemTags(ctx context.Context, tx *sql.Tx, id string, tags []string) error {
+	_, err := repo.execTx(ctx, tx, "DELETE FROM shopping_list_item_tags WHERE shopping_list_item_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("sql delete shopping list item tags: %w", err)
+	}
+
+	for pos, tag := range tags {
+		_, err := repo.execTx(ctx, tx,
+			"INSERT INTO shopping_list_item_tags (shopping_list_item_id, position, tag) VALUES (?, ?, ?)",
+			id, pos, tag,
+		)
+		if err != nil {
+			return fmt.Errorf("sql insert shopping list item tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) CreateShoppingListItem(
+	ctx context.Context, params writeShoppingListItemParams,
+) (shoppingListItem, error) {
+	id := uuid.NewString()
+
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := repo.execTx(ctx, tx,
+			"INSERT INTO shopping_list_items ("+sqlShoppingListItemColumns+", household_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			id, params.Name, params.Type, params.Quantity, params.Unit, params.LocationID, getHouseholdID(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("sql create shopping list item: %w", err)
+		}
+
+		return repo.writeShoppingListItemTags(ctx, tx, id, params.Tags)
+	})
+	if err != nil {
+		return shoppingListItem{}, err
+	}
+
+	return repo.GetShoppingListItem(ctx, id)
+}
+
+func (repo sqlRepository) UpdateShoppingListItem(
+	ctx context.Context, id string, params writeShoppingListItemParams,
+) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := repo.execTx(ctx, tx,
+			`UPDATE shopping_list_items SET name = ?, type = ?, quantity = ?, unit = ?, location_id = ?
+			WHERE id = ? AND household_id = ?`,
+			params.Name, params.Type, params.Quantity, params.Unit, params.LocationID, id, getHouseholdID(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("sql update shopping list item: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("sql update shopping list item rows affected: %w", err)
+		} else if n == 0 {
+			return errShoppingListItemNotFound
+		}
+
+		return repo.writeShoppingListItemTags(ctx, tx, id, params.Tags)
+	})
+}
+
+func (repo sqlRepository) DeleteShoppingListItem(ctx context.Context, id string) error {
+	res, err := repo.exec(ctx,
+		"DELETE FROM shopping_list_items WHERE id = ? AND household_id = ?", id, getHouseholdID(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("sql delete shopping list item: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql delete shopping list item rows affected: %w", err)
+	} else if n == 0 {
+		return errShoppingListItemNotFound
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) BuyShoppingListItem(ctx context.Context, id string, params writeItemParams) (item, error) {
+	var itemID string
+
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		// deleting the entry first makes a concurrent buy of the same entry find nothing to delete
+		res, err := repo.execTx(ctx, tx,
+			"DELETE FROM shopping_list_items WHERE id = ? AND household_id = ?", id, getHouseholdID(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("sql delete shopping list item: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("sql delete shopping list item rows affected: %w", err)
+		} else if n == 0 {
+			return errShoppingListItemNotFound
+		}
+
+		itemID, err = repo.createItemTx(ctx, tx, params)
+
+		return err
+	})
+	if err != nil {
+		return item{}, err
+	}
+
+	// the item is read back to return it as stored, like with its times in UTC
+	return repo.GetItem(ctx, itemID)
+}
+
+func (repo sqlRepository) GetExpiryRules(ctx context.Context) ([]expiryRule, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetExpiryRules")
+	defer span.End()
+
+	rows, err := repo.query(ctx,
+		`SELECT id, type, tag, shelf_life, opened_lifespan, frozen_shelf_life, thawed_lifespan
+		FROM expiry_rules WHERE household_id = ? ORDER BY id`,
+		getHouseholdID(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sql get expiry rules: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	rules := []expiryRule{}
+
+	for rows.Next() {
+		var r expiryRule
+		err := rows.Scan(&r.ID, &r.Type, &r.Tag, &r.ShelfLife, &r.OpenedLifespan, &r.FrozenShelfLife, &r.ThawedLifespan)
+		if err != nil {
+			return nil, fmt.Errorf("sql scan expiry rule: %w", err)
+		}
+
+		rules = append(rules, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate expiry rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (repo sqlRepository) CreateExpiryRule(ctx context.Context, params writeExpiryRuleParams) (expiryRule, error) {
+	id := uuid.NewString()
+
+	_, err := repo.exec(ctx,
+		`INSERT INTO expiry_rules
+			(id, household_id, type, tag, shelf_life, opened_lifespan, frozen_shelf_life, thawed_lifespan)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, getHouseholdID(ctx), params.Type, params.Tag, params.ShelfLife, params.OpenedLifespan,
+		params.FrozenShelfLife, params.ThawedLifespan,
+	)
+	if err != nil {
+		return expiryRule{}, fmt.Errorf("sql create expiry rule: %w", err)
+	}
+
+	return expiryRuleFromParams(id, params), nil
+}
+
+func (repo sqlRepository) UpdateExpiryRule(ctx context.Context, id string, params writeExpiryRuleParams) error {
+	res, err := repo.exec(ctx,
+		`UPDATE expiry_rules
+		SET type = ?, tag = ?, shelf_life = ?, opened_lifespan = ?, frozen_shelf_life = ?, thawed_lifespan = ?
+		WHERE id = ? AND household_id = ?`,
+		params.Type, params.Tag, params.ShelfLife, params.OpenedLifespan, params.FrozenShelfLife, params.ThawedLifespan,
+		id, getHouseholdID(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("sql update expiry rule: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql update expiry rule rows affected: %w", err)
+	} else if n == 0 {
+		return errExpiryRuleNotFound
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) DeleteExpiryRule(ctx context.Context, id string) error {
+	res, err := repo.exec(ctx, "DELETE FROM expiry_rules WHERE id = ? AND household_id = ?", id, getHouseholdID(ctx))
+	if err != nil {
+		return fmt.Errorf("sql delete expiry rule: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql delete expiry rule rows affected: %w", err)
+	} else if n == 0 {
+		return errExpiryRuleNotFound
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) GetHouseholds(ctx context.Context) ([]household, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetHouseholds")
+	defer span.End()
+
+	rows, err := repo.query(ctx, "SELECT id, name FROM households ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("sql get households: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	households := []household{}
+
+	for rows.Next() {
+		var h household
+		if err := rows.Scan(&h.ID, &h.Name); err != nil {
+			return nil, fmt.Errorf("sql scan household: %w", err)
+		}
+
+		households = append(households, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate households: %w", err)
+	}
+
+	return households, nil
+}
+
+func (repo sqlRepository) GetHousehold(ctx context.Context, id string) (household, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetHousehold")
+	defer span.End()
+
+	h := household{}
+
+	err := repo.db.QueryRowContext(ctx, repo.rebind("SELECT id, name FROM households WHERE id = ?"), id).
+		Scan(&h.ID, &h.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return household{}, errHouseholdNotFound
+	} else if err != nil {
+		return household{}, fmt.Errorf("sql get household: %w", err)
+	}
+
+	return h, nil
+}
+
+func (repo sqlRepository) GetUserHousehold(ctx context.Context, uid string) (userHousehold, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetUserHousehold")
+	defer span.End()
+
+	uh := userHousehold{}
+
+	err := repo.db.QueryRowContext(ctx,
+		repo.rebind(`SELECT households.id, households.name, household_members.role FROM households
+		JOIN household_members ON household_members.household_id = households.id
+		WHERE household_members.uid = ?`),
+		uid,
+	).Scan(&uh.ID, &uh.Name, &uh.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userHousehold{}, errHouseholdNotFound
+	} else if err != nil {
+		return userHousehold{}, fmt.Errorf("sql get user household: %w", err)
+	}
+
+	return uh, nil
+}
+
+// addHouseholdMemberTx returns errHouseholdMemberExists if the user already belongs to a household.
+func (repo sqlRepository) addHouseholdMemberTx(
+	ctx context.Context, tx *sql.Tx, householdID string, uid string, role householdRole,
+) error {
+	var exists bool
+
+	err := tx.QueryRowContext(ctx, repo.rebind("SELECT EXISTS (SELECT 1 FROM household_members WHERE uid = ?)"), uid).
+		Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("sql check household member exists: %w", err)
+	}
+
+	if exists {
+		return errHouseholdMemberExists
+	}
+
+	_, err = repo.execTx(ctx, tx,
+		"INSERT INTO household_members (uid, household_id, role) VALUES (?, ?, ?)", uid, householdID, role,
+	)
+	if err != nil {
+		return fmt.Errorf("sql add household member: %w", err)
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) CreateHousehold(ctx context.Context, name string, uid string) (household, error) {
+	h := household{ID: uuid.NewString(), Name: name}
+
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := repo.execTx(ctx, tx, "INSERT INTO households (id, name) VALUES (?, ?)", h.ID, h.Name)
+		if err != nil {
+			return fmt.Errorf("sql create household: %w", err)
+		}
+
+		return repo.addHouseholdMemberTx(ctx, tx, h.ID, uid, householdRoleOwner)
+	})
+	if err != nil {
+		return household{}, err
+	}
+
+	return h, nil
+}
+
+func (repo sqlRepository) AddHouseholdMember(
+	ctx context.Context, householdID string, uid string, role householdRole,
+) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+
+		err := tx.QueryRowContext(ctx, repo.rebind("SELECT EXISTS (SELECT 1 FROM households WHERE id = ?)"), householdID).
+			Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("sql check household exists: %w", err)
+		}
+
+		if !exists {
+			return errHouseholdNotFound
+		}
+
+		return repo.addHouseholdMemberTx(ctx, tx, householdID, uid, role)
+	})
+}
+
+func (repo sqlRepository) GetHouseholdMembers(ctx context.Context, householdID string) ([]householdMember, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetHouseholdMembers")
+	defer span.End()
+
+	rows, err := repo.query(ctx, "SELECT uid, role FROM household_members WHERE household_id = ? ORDER BY uid",
+		householdID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sql get household members: %w", err)
+	}
+
+	return sqlToHouseholdMembers(rows)
+}
+
+func sqlToHouseholdMembers(rows *sql.Rows) ([]householdMember, error) {
+	defer rows.Close() //nolint:errcheck
+
+	members := []householdMember{}
+
+	for rows.Next() {
+		var m householdMember
+		if err := rows.Scan(&m.UID, &m.Role); err != nil {
+			return nil, fmt.Errorf("sql scan household member: %w", err)
+		}
+
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate household members: %w", err)
+	}
+
+	return members, nil
+}
+
+// checkOtherOwnerTx returns errHouseholdLastOwner if the user is the last owner of the household with other
+// members. It locks the household first, so that the concurrent changes of its members wait for the transaction.
+func (repo sqlRepository) checkOtherOwnerTx(ctx context.Context, tx *sql.Tx, householdID string, uid string) error {
+	// the no-op update takes the lock of the row in every dialect
+	if _, err := repo.execTx(ctx, tx, "UPDATE households SET name = name WHERE id = ?", householdID); err != nil {
+		return fmt.Errorf("sql lock household: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, repo.rebind("SELECT uid, role FROM household_members WHERE household_id = ?"),
+		householdID,
+	)
+	if err != nil {
+		return fmt.Errorf("sql get household members: %w", err)
+	}
+
+	members, err := sqlToHouseholdMembers(rows)
+	if err != nil {
+		return err
+	}
+
+	return checkOtherOwner(members, uid)
+}
+
+func (repo sqlRepository) UpdateHouseholdMemberRole(
+	ctx context.Context, householdID string, uid string, role householdRole,
+) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		if role != householdRoleOwner {
+			if err := repo.checkOtherOwnerTx(ctx, tx, householdID, uid); err != nil {
+				return err
+			}
+		}
+
+		res, err := repo.execTx(ctx, tx,
+			"UPDATE household_members SET role = ? WHERE uid = ? AND household_id = ?", role, uid, householdID,
+		)
+		if err != nil {
+			return fmt.Errorf("sql update household member role: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("sql update household member role rows affected: %w", err)
+		} else if n == 0 {
+			return errHouseholdMemberNotFound
+		}
+
+		return nil
+	})
+}
+
+func (repo sqlRepository) RemoveHouseholdMember(ctx context.Context, householdID string, uid string) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		if err := repo.checkOtherOwnerTx(ctx, tx, householdID, uid); err != nil {
+			return err
+		}
+
+		res, err := repo.execTx(ctx, tx,
+			"DELETE FROM household_members WHERE uid = ? AND household_id = ?", uid, householdID,
+		)
+		if err != nil {
+			return fmt.Errorf("sql remove household member: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("sql remove household member rows affected: %w", err)
+		} else if n == 0 {
+			return errHouseholdMemberNotFound
+		}
+
+		return nil
+	})
+}
+
+func (repo sqlRepository) CreateHouseholdInvite(ctx context.Context, invite householdInvite) error {
+	_, err := repo.exec(ctx,
+		"INSERT INTO household_invites (code, household_id, role, created_by, expires_at) VALUES (?, ?, ?, ?, ?)",
+		invite.Code, invite.HouseholdID, cmp.Or(invite.Role, householdRoleEditor), invite.CreatedBy, invite.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sql create household invite: %w", err)
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) AcceptHouseholdInvite(
+	ctx context.Context, code string, uid string, now time.Time,
+) (userHousehold, error) {
+	uh := userHousehold{}
+
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		var expiresAt time.Time
+
+		err := tx.QueryRowContext(ctx,
+			repo.rebind(`SELECT households.id, households.name, household_invites.role, household_invites.expires_at
+			FROM household_invites
+			JOIN households ON households.id = household_invites.household_id
+			WHERE household_invites.code = ?`),
+			code,
+		).Scan(&uh.ID, &uh.Name, &uh.Role, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errHouseholdInviteNotFound
+		} else if err != nil {
+			return fmt.Errorf("sql get household invite: %w", err)
+		}
+
+		if !now.Before(expiresAt) {
+			return errHouseholdInviteExpired
+		}
+
+		var currentHouseholdID string
+
+		err = tx.QueryRowContext(ctx, repo.rebind("SELECT household_id FROM household_members WHERE uid = ?"), uid).
+			Scan(&currentHouseholdID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return fmt.Errorf("sql get household member: %w", err)
+		default:
+			if err := repo.checkOtherOwnerTx(ctx, tx, currentHouseholdID, uid); err != nil {
+				return err
+			}
+		}
+
+		if _, err := repo.execTx(ctx, tx, "DELETE FROM household_members WHERE uid = ?", uid); err != nil {
+			return fmt.Errorf("sql remove household member: %w", err)
+		}
+
+		if err := repo.addHouseholdMemberTx(ctx, tx, uh.ID, uid, uh.Role); err != nil {
+			return err
+		}
+
+		if _, err := repo.execTx(ctx, tx, "DELETE FROM household_invites WHERE code = ?", code); err != nil {
+			return fmt.Errorf("sql delete household invite: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return userHousehold{}, err
+	}
+
+	return uh, nil
+}
+
+const sqlAccessTokenColumns = "id, uid, name, hash, scopes, created_at, expires_at"
+
+// sqlScopes stores the scopes separated by spaces, like the scopes of OAuth.
+func sqlScopes(scopes []permission) string {
+	values := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		values = append(values, string(scope))
+	}
+
+	return strings.Join(values, " ")
+}
+
+func sqlToAccessToken(scan func(dest ...any) error) (accessToken, error) {
+	var (
+		t      accessToken
+		scopes string
+	)
+
+	if err := scan(&t.ID, &t.UID, &t.Name, &t.Hash, &scopes, &t.CreatedAt, &t.ExpiresAt); err != nil {
+		return accessToken{}, err //nolint:wrapcheck
+	}
+
+	t.Scopes = []permission{}
+	for scope := range strings.FieldsSeq(scopes) {
+		t.Scopes = append(t.Scopes, permission(scope))
+	}
+
+	return t, nil
+}
+
+func (repo sqlRepository) GetAccessTokens(ctx context.Context, uid string) ([]accessToken, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetAccessTokens")
+	defer span.End()
+
+	rows, err := repo.query(ctx,
+		"SELECT "+sqlAccessTokenColumns+" FROM access_tokens WHERE uid = ? ORDER BY created_at, id", uid,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sql get access tokens: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	tokens := []accessToken{}
+
+	for rows.Next() {
+		t, err := sqlToAccessToken(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("sql scan access token: %w", err)
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (repo sqlRepository) GetAccessTokenByHash(ctx context.Context, hash string) (accessToken, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetAccessTokenByHash")
+	defer span.End()
+
+	row := repo.db.QueryRowContext(ctx,
+		repo.rebind("SELECT "+sqlAccessTokenColumns+" FROM access_tokens WHERE hash = ?"), hash,
+	)
+
+	t, err := sqlToAccessToken(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return accessToken{}, errAccessTokenNotFound
+	} else if err != nil {
+		return accessToken{}, fmt.Errorf("sql get access token: %w", err)
+	}
+
+	return t, nil
+}
+
+func (repo sqlRepository) CreateAccessToken(ctx context.Context, token accessToken) error {
+	_, err := repo.exec(ctx,
+		"INSERT INTO access_tokens ("+sqlAccessTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		token.ID, token.UID, token.Name, token.Hash, sqlScopes(token.Scopes), token.CreatedAt.UTC(),
+		sqlTime(token.ExpiresAt),
+	)
+	if err != nil {
+		return fmt.Errorf("sql create access token: %w", err)
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) DeleteAccessToken(ctx context.Context, uid string, id string) error {
+	res, err := repo.exec(ctx, "DELETE FROM access_tokens WHERE id = ? AND uid = ?", id, uid)
+	if err != nil {
+		return fmt.Errorf("sql delete access token: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql delete access token rows affected: %w", err)
+	} else if n == 0 {
+		return errAccessTokenNotFound
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) SetUserEmail(ctx context.Context, uid string, email string) error {
+	_, err := repo.exec(ctx,
+		"INSERT INTO user_emails (uid, email) VALUES (?, ?) ON CONFLICT (uid) DO UPDATE SET email = excluded.email",
+		uid, email,
+	)
+	if err != nil {
+		return fmt.Errorf("sql set user email: %w", err)
+	}
+
+	return nil
+}
+
+func (repo sqlRepository) GetUserEmails(ctx context.Context, uids *[]string) ([]string, error) {
+	ctx, span := repo.tracer.Start(ctx, "sqlRepository.GetUserEmails")
+	defer span.End()
+
+	query, args := "SELECT email FROM user_emails", []any{}
+
+	if uids != nil {
+		if len(*uids) == 0 {
+			return []string{}, nil
+		}
+
+		var placeholders string
+		placeholders, args = sqlPlaceholders(*uids)
+		query += " WHERE uid IN (" + placeholders + ")"
+	}
+
+	rows, err := repo.query(ctx, query+" ORDER BY uid", args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql get user emails: %w", err)
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	emails := []string{}
+
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, fmt.Errorf("sql scan user email: %w", err)
+		}
+
+		emails = append(emails, email)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql iterate user emails: %w", err)
+	}
+
+	return emails, nil
+}
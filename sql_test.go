@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newTestSQLiteRepository(t *testing.T) sqliteRepository {
+	t.Helper()
+
+	repo, err := openSQLiteRepository(context.Background(), filepath.Join(t.TempDir(), "pantry.db"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	t.Cleanup(func() { repo.db.Close() }) //nolint:errcheck,gosec
+
+	return repo
+}
+
+// newTestPostgresRepository returns a repository migrated into a fresh schema of the POSTGRES_TEST_URL database.
+func newTestPostgresRepository(t *testing.T) postgresRepository {
+	t.Helper()
+
+	ctx := context.Background()
+
+	adminDB, err := sql.Open("pgx", os.Getenv("POSTGRES_TEST_URL"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	t.Cleanup(func() { adminDB.Close() }) //nolint:errcheck,gosec
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	if _, err := adminDB.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	t.Cleanup(func() {
+		adminDB.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE") //nolint:errcheck,gosec
+	})
+
+	u, err := url.Parse(os.Getenv("POSTGRES_TEST_URL"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	repo, err := openPostgresRepository(ctx, u.String())
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	t.Cleanup(func() { repo.db.Close() }) //nolint:errcheck,gosec
+
+	return repo
+}
+
+func TestSQLRepositoryContract(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sqlite", func(t *testing.T) {
+		t.Parallel()
+
+		runRepositoryContract(t, func(t *testing.T) repository { return newTestSQLiteRepository(t) })
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		t.Parallel()
+
+		if os.Getenv("POSTGRES_TEST_URL") == "" {
+			t.Skip("POSTGRES_TEST_URL is not set")
+		}
+
+		runRepositoryContract(t, func(t *testing.T) repository { return newTestPostgresRepository(t) })
+	})
+}
+
+func TestSQLiteMigrateTwice(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pantry.db")
+
+	migrations, err := fs.ReadDir(migrationsFS, "migrations/sqlite")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	for range 2 {
+		repo, err := openSQLiteRepository(ctx, path)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		var count int
+		if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if count != len(migrations) {
+			t.Errorf("Got %d applied migrations instead of %d", count, len(migrations))
+		}
+
+		repo.db.Close() //nolint:errcheck,gosec
+	}
+}
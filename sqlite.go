@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	_ "modernc.org/sqlite"
+)
+
+func getSQLiteRepository(ctx context.Context) (sqliteRepository, error) {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = "pantry.db"
+	}
+
+	return openSQLiteRepository(ctx, path)
+}
+
+func openSQLiteRepository(ctx context.Context, path string) (sqliteRepository, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return sqliteRepository{}, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite allows a single writer at a time, sharing one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	repo := sqliteRepository{sqlRepository{
+		db:      db,
+		dialect: sqlDialect{name: "sqlite"},
+		tracer:  otel.Tracer("sqlite"),
+	}}
+
+	if err := repo.migrate(ctx); err != nil {
+		db.Close() //nolint:errcheck,gosec
+
+		return sqliteRepository{}, fmt.Errorf("failed to migrate sqlite database: %w", err)
+	}
+
+	return repo, nil
+}
+
+type sqliteRepository struct {
+	sqlRepository
+}
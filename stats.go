@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const statsMonthFormat = "2006-01"
+
+type monthSpending struct {
+	Month string `json:"month"`
+	Spent int    `json:"spent"`
+}
+
+type wasteRate struct {
+	Key string `json:"key"`
+	// Total counts the items that were finished or expired, Wasted the ones that were wasted or expired
+	Total  int     `json:"total"`
+	Wasted int     `json:"wasted"`
+	Rate   float64 `json:"rate"`
+}
+
+type stats struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// SpentPerMonth lists every month of the range, prices are summed in the units they are stored in
+	SpentPerMonth       []monthSpending `json:"spentPerMonth"`
+	WastedValue         int             `json:"wastedValue"`
+	WasteRateByTag      []wasteRate     `json:"wasteRateByTag"`
+	WasteRateByType     []wasteRate     `json:"wasteRateByType"`
+	AverageDaysConsumed *float64        `json:"averageDaysConsumed"`
+}
+
+// isItemWasted returns whether the item was thrown away or is still around after expiring,
+// the item needs to have its expiry filled.
+func isItemWasted(i item) bool {
+	if i.Outcome != nil {
+		return *i.Outcome == itemOutcomeWasted
+	}
+
+	return i.ArchivedAt == nil && i.Status == itemStatusExpired
+}
+
+// hasItemEnded returns whether it is known if the item was wasted.
+func hasItemEnded(i item) bool {
+	return i.ArchivedAt != nil || i.Status == itemStatusExpired
+}
+
+func addWasteRate(rates map[string]*wasteRate, key string, wasted bool) {
+	rate, ok := rates[key]
+	if !ok {
+		rate = &wasteRate{Key: key}
+		rates[key] = rate
+	}
+
+	rate.Total++
+
+	if wasted {
+		rate.Wasted++
+	}
+}
+
+func sortedWasteRates(rates map[string]*wasteRate) []wasteRate {
+	res := []wasteRate{}
+
+	for _, rate := range rates {
+		rate.Rate = float64(rate.Wasted) / float64(rate.Total)
+		res = append(res, *rate)
+	}
+
+	slices.SortFunc(res, func(a, b wasteRate) int {
+		return cmp.Or(cmp.Compare(b.Rate, a.Rate), cmp.Compare(a.Key, b.Key))
+	})
+
+	return res
+}
+
+// calculateStats computes the stats of the items, which need to have their expiry filled.
+func calculateStats(items []item, from time.Time, to time.Time) stats {
+	spent := map[string]int{}
+	tagRates, typeRates := map[string]*wasteRate{}, map[string]*wasteRate{}
+	wastedValue, consumedCount := 0, 0
+	consumedDays := 0.0
+
+	for _, i := range items {
+		price := *cmp.Or(i.Price, getPtr(0))
+		spent[i.BoughtAt.UTC().Format(statsMonthFormat)] += price
+
+		if i.Outcome != nil && *i.Outcome == itemOutcomeConsumed {
+			consumedCount++
+			consumedDays += i.ArchivedAt.Sub(i.BoughtAt).Hours() / 24 //nolint:mnd
+		}
+
+		if !hasItemEnded(i) {
+			continue
+		}
+
+		wasted := isItemWasted(i)
+		if wasted {
+			wastedValue += price
+		}
+
+		for _, tag := range i.Tags {
+			addWasteRate(tagRates, tag, wasted)
+		}
+
+		if i.Type != nil {
+			addWasteRate(typeRates, *i.Type, wasted)
+		}
+	}
+
+	res := stats{
+		From:            from,
+		To:              to,
+		SpentPerMonth:   []monthSpending{},
+		WastedValue:     wastedValue,
+		WasteRateByTag:  sortedWasteRates(tagRates),
+		WasteRateByType: sortedWasteRates(typeRates),
+	}
+
+	month := time.Date(from.UTC().Year(), from.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	for month.Before(to) {
+		key := month.Format(statsMonthFormat)
+		res.SpentPerMonth = append(res.SpentPerMonth, monthSpending{Month: key, Spent: spent[key]})
+		month = month.AddDate(0, 1, 0)
+	}
+
+	if consumedCount > 0 {
+		res.AverageDaysConsumed = getPtr(consumedDays / float64(consumedCount))
+	}
+
+	return res
+}
+
+// getStats returns the stats of the items bought from the start of the range until before its end.
+func getStats(ctx context.Context, repo repository, from time.Time, to time.Time) (stats, error) {
+	if !from.Before(to) {
+		return stats{}, fmt.Errorf("%w: the range has to start before it ends", errValidation)
+	}
+
+	query := itemsQuery{Sort: itemsSortBoughtAt, BoughtFrom: &from, BoughtBefore: &to}
+
+	items, err := repo.GetItems(ctx, query)
+	if err != nil {
+		return stats{}, fmt.Errorf("get items: %w", err)
+	}
+
+	query.Archived = true
+
+	archivedItems, err := repo.GetItems(ctx, query)
+	if err != nil {
+		return stats{}, fmt.Errorf("get archived items: %w", err)
+	}
+
+	rules, err := getExpiryRules(ctx, repo)
+	if err != nil {
+		return stats{}, err
+	}
+
+	items = append(items, archivedItems...)
+	fillItemsExpiry(items, rules)
+
+	return calculateStats(items, from, to), nil
+}
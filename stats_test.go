@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCalculateStats(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	boughtAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	items := []item{
+		{
+			Name: "Milk", Type: getPtr("milk"), Tags: []string{"dairy"}, Price: getPtr(300), BoughtAt: boughtAt,
+			ArchivedAt: getPtr(boughtAt.Add(48 * time.Hour)), Outcome: getPtr(itemOutcomeConsumed),
+		},
+		{
+			Name: "Cheese", Tags: []string{"dairy", "smelly"}, Price: getPtr(1500), BoughtAt: boughtAt,
+			ArchivedAt: getPtr(boughtAt.Add(96 * time.Hour)), Outcome: getPtr(itemOutcomeWasted),
+		},
+		{
+			Name: "Yogurt", Type: getPtr("milk"), Tags: []string{"dairy"}, Price: getPtr(200),
+			BoughtAt: boughtAt.AddDate(0, 1, 0), ExpiresAt: getPtr(time.Now().Add(-48 * time.Hour)),
+		},
+		{
+			Name: "Cake", Tags: []string{"sweet"}, BoughtAt: boughtAt.AddDate(0, 1, 0),
+			ArchivedAt: getPtr(boughtAt.AddDate(0, 1, 1)), Outcome: getPtr(itemOutcomeGivenAway),
+		},
+		{Name: "Salt", Tags: []string{"spice"}, Price: getPtr(100), BoughtAt: boughtAt},
+	}
+
+	fillItemsExpiry(items, expiryRules{})
+
+	s := calculateStats(items, from, to)
+
+	expectedSpent := []monthSpending{
+		{Month: "2024-04", Spent: 0}, {Month: "2024-05", Spent: 1900}, {Month: "2024-06", Spent: 200},
+	}
+	if !reflect.DeepEqual(s.SpentPerMonth, expectedSpent) {
+		t.Errorf("Got spent per month %+v instead of %+v", s.SpentPerMonth, expectedSpent)
+	}
+
+	if s.WastedValue != 1700 {
+		t.Errorf("Got wasted value %d instead of 1700", s.WastedValue)
+	}
+
+	expectedTags := []wasteRate{
+		{Key: "smelly", Total: 1, Wasted: 1, Rate: 1},
+		{Key: "dairy", Total: 3, Wasted: 2, Rate: 2.0 / 3},
+		{Key: "sweet", Total: 1, Wasted: 0, Rate: 0},
+	}
+	if !reflect.DeepEqual(s.WasteRateByTag, expectedTags) {
+		t.Errorf("Got waste rates by tag %+v instead of %+v", s.WasteRateByTag, expectedTags)
+	}
+
+	expectedTypes := []wasteRate{{Key: "milk", Total: 2, Wasted: 1, Rate: 0.5}}
+	if !reflect.DeepEqual(s.WasteRateByType, expectedTypes) {
+		t.Errorf("Got waste rates by type %+v instead of %+v", s.WasteRateByType, expectedTypes)
+	}
+
+	if s.AverageDaysConsumed == nil || *s.AverageDaysConsumed != 2 {
+		t.Errorf("Got average days to consumption %v instead of 2", s.AverageDaysConsumed)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, params := range []writeItemParams{
+		{Name: "Milk", Tags: []string{}, Price: getPtr(300), BoughtAt: from},
+		{
+			Name: "Cheese", Tags: []string{}, Price: getPtr(1500), BoughtAt: from.Add(time.Hour),
+			ArchivedAt: getPtr(from.Add(48 * time.Hour)), Outcome: getPtr(itemOutcomeConsumed),
+		},
+		{Name: "Bread", Tags: []string{}, Price: getPtr(400), BoughtAt: to},
+		{Name: "Eggs", Tags: []string{}, Price: getPtr(500), BoughtAt: from.Add(-time.Hour)},
+	} {
+		if _, err := repo.CreateItem(ctx, params); err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+	}
+
+	s, err := getStats(ctx, repo, from, to)
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+
+	if !reflect.DeepEqual(s.SpentPerMonth, []monthSpending{{Month: "2024-05", Spent: 1800}}) {
+		t.Errorf("Expected 1800 to be spent in May 2024, got %+v", s.SpentPerMonth)
+	}
+
+	if s.AverageDaysConsumed == nil || *s.AverageDaysConsumed != 47.0/24 {
+		t.Errorf("Got average days to consumption %v instead of %v", s.AverageDaysConsumed, 47.0/24)
+	}
+
+	if _, err := getStats(ctx, repo, to, from); !errors.Is(err, errValidation) {
+		t.Errorf("Expected a validation error for a reversed range, got %v", err)
+	}
+}
@@ -22,9 +22,10 @@ func (n telegramNotifier) NotifyAboutItems(
 	ctx context.Context,
 	expiries []itemExpiry,
 	comingExpiries []itemExpiry,
-	_ authenticationRepository,
+	frozenExpiries []itemExpiry,
+	_ []string,
 ) error {
-	msg := getNotificationTitle() + "\n\n" + notificationExpiriesToText(expiries, comingExpiries)
+	msg := getNotificationTitle() + "\n\n" + notificationExpiriesToText(expiries, comingExpiries, frozenExpiries)
 	targetURL := n.getURL("/sendMessage")
 
 	body, err := json.Marshal(map[string]any{
@@ -56,6 +57,10 @@ func (n telegramNotifier) NotifyAboutItems(
 	return nil
 }
 
+func (n telegramNotifier) sendsToRecipients() bool {
+	return false
+}
+
 func (n telegramNotifier) getURL(subpath string) url.URL {
 	return url.URL{
 		Scheme: "https",
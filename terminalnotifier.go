@@ -8,9 +8,17 @@ import (
 type terminalNotifier struct{}
 
 func (n terminalNotifier) NotifyAboutItems(
-	_ context.Context, expiries []itemExpiry, comingExpiries []itemExpiry, _ authenticationRepository,
+	_ context.Context,
+	expiries []itemExpiry,
+	comingExpiries []itemExpiry,
+	frozenExpiries []itemExpiry,
+	_ []string,
 ) error {
-	fmt.Print(notificationExpiriesToText(expiries, comingExpiries)) //nolint: forbidigo
+	fmt.Print(notificationExpiriesToText(expiries, comingExpiries, frozenExpiries)) //nolint: forbidigo
 
 	return nil
 }
+
+func (n terminalNotifier) sendsToRecipients() bool {
+	return false
+}